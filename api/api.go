@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"spyrosoft-recruitment-task/base"
+	"spyrosoft-recruitment-task/logger"
+	"sync"
+	"time"
+)
+
+// State holds the most recently fetched summary, it is updated by workers and read by HTTP handlers
+type State struct {
+	mu         sync.RWMutex
+	summary    *base.ExchangeRatesSummary
+	outOfScope []string
+
+	// summaries of the last pools, served at /history
+	History *History
+	// out-of-scope dates since startup, served at /out-of-scope/all
+	AllOutOfScope *OutOfScopeSet
+}
+
+func (s *State) Update(summary base.ExchangeRatesSummary, outOfScope []base.OutOfScopeRate) {
+	dates := make([]string, 0, len(outOfScope))
+	for _, rate := range outOfScope {
+		dates = append(dates, rate.EffectiveDate.Format("2006-01-02"))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.summary = &summary
+	s.outOfScope = dates
+}
+
+func (s *State) Latest() (*base.ExchangeRatesSummary, []string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.summary, s.outOfScope
+}
+
+func NewHandler(state *State) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/rates", func(w http.ResponseWriter, r *http.Request) {
+		summary, _ := state.Latest()
+		if summary == nil {
+			writeNoData(w)
+			return
+		}
+		writeJson(w, summary)
+	})
+
+	mux.HandleFunc("/rates/out-of-scope", func(w http.ResponseWriter, r *http.Request) {
+		summary, outOfScope := state.Latest()
+		if summary == nil {
+			writeNoData(w)
+			return
+		}
+		writeJson(w, outOfScope)
+	})
+
+	mux.HandleFunc("/out-of-scope/all", func(w http.ResponseWriter, r *http.Request) {
+		writeJson(w, state.AllOutOfScope.All())
+	})
+
+	// oldest pool first, empty list until the first pool finishes
+	mux.HandleFunc("/history", func(w http.ResponseWriter, r *http.Request) {
+		writeJson(w, state.History.Entries())
+	})
+
+	return onlyGet(mux)
+}
+
+// StartServer serves the rates API on given address in background, returned server should be shut down with Shutdown
+func StartServer(addr string, state *State) *http.Server {
+	server := &http.Server{Addr: addr, Handler: NewHandler(state)}
+
+	go func() {
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Rates API server failed: %s", err)
+		}
+	}()
+
+	return server
+}
+
+func Shutdown(server *http.Server, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return server.Shutdown(ctx)
+}
+
+func onlyGet(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeNoData(w http.ResponseWriter) {
+	// nothing fetched successfully yet
+	http.Error(w, "no rates fetched yet", http.StatusServiceUnavailable)
+}
+
+func writeJson(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		logger.Error("Failed to encode rates API response: %s", err)
+	}
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"spyrosoft-recruitment-task/base"
+	"testing"
+	"time"
+)
+
+// get performs GET of path on handler, returning status and body of the response
+func get(t *testing.T, handler http.Handler, path string) (int, string) {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	return recorder.Code, recorder.Body.String()
+}
+
+func newTestState() *State {
+	return &State{History: NewHistory(DefaultHistorySize), AllOutOfScope: NewOutOfScopeSet()}
+}
+
+func TestHandlerReturnsUnavailableBeforeFirstFetch(t *testing.T) {
+	handler := NewHandler(&State{})
+
+	for _, path := range []string{"/rates", "/rates/out-of-scope"} {
+		if status, _ := get(t, handler, path); status != http.StatusServiceUnavailable {
+			t.Errorf("GET %s returned %d before the first fetch, want 503", path, status)
+		}
+	}
+}
+
+func TestHandlerReturnsLatestSummary(t *testing.T) {
+	var summary base.ExchangeRatesSummary
+	err := json.Unmarshal([]byte(`{"table":"A","currency":"euro","code":"EUR","rates":[`+
+		`{"no":"001/A/NBP/2024","effectiveDate":"2024-01-02","mid":4.35},`+
+		`{"no":"002/A/NBP/2024","effectiveDate":"2024-01-03","mid":4.6}]}`), &summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := &State{}
+	below := base.OutOfScopeRate{No: "001/A/NBP/2024", EffectiveDate: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Mid: 4.35, Direction: base.DirectionBelow}
+	state.Update(summary, []base.OutOfScopeRate{below})
+	handler := NewHandler(state)
+
+	status, body := get(t, handler, "/rates")
+	want := `{"table":"A","currency":"euro","code":"EUR","rates":[` +
+		`{"no":"001/A/NBP/2024","effectiveDate":"2024-01-02T00:00:00Z","mid":4.35},` +
+		`{"no":"002/A/NBP/2024","effectiveDate":"2024-01-03T00:00:00Z","mid":4.6}]}` + "\n"
+	if status != http.StatusOK || body != want {
+		t.Errorf("GET /rates = %d %s, want 200 %s", status, body, want)
+	}
+
+	status, body = get(t, handler, "/rates/out-of-scope")
+	if status != http.StatusOK || body != `["2024-01-02"]`+"\n" {
+		t.Errorf("GET /rates/out-of-scope = %d %s, want 200 [\"2024-01-02\"]", status, body)
+	}
+}
+
+func TestHandlerAllowsOnlyGet(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	NewHandler(&State{}).ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/rates", nil))
+
+	if recorder.Code != http.StatusMethodNotAllowed || recorder.Header().Get("Allow") != http.MethodGet {
+		t.Errorf("POST /rates = %d, Allow %q, want 405 allowing GET", recorder.Code, recorder.Header().Get("Allow"))
+	}
+}
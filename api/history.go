@@ -0,0 +1,87 @@
+package api
+
+import (
+	"spyrosoft-recruitment-task/base"
+	"sync"
+	"time"
+)
+
+// DefaultHistorySize is the number of pools kept for /history
+const DefaultHistorySize = 100
+
+// CurrencySummary is what a pool fetched of a single currency
+type CurrencySummary struct {
+	Currency   string  `json:"currency"`
+	Rates      int     `json:"rates"`
+	Min        float64 `json:"min"`
+	Max        float64 `json:"max"`
+	Average    float64 `json:"average"`
+	OutOfScope int     `json:"out_of_scope"`
+	// latest effective date, empty when no rate was fetched
+	Newest string `json:"newest,omitempty"`
+}
+
+// HistoryEntry is a summary of a finished requests pool
+type HistoryEntry struct {
+	Time       time.Time         `json:"time"`
+	Currencies []CurrencySummary `json:"currencies"`
+}
+
+// NewHistoryEntry summarizes stats of every currency of a pool finished at given time,
+// stats of a single currency pool carry no currency, so currencies are passed along in the same order
+func NewHistoryEntry(at time.Time, currencies []string, allStats []base.PoolStats) HistoryEntry {
+	entry := HistoryEntry{Time: at}
+	for i, stats := range allStats {
+		summary := CurrencySummary{
+			Currency:   currencies[i],
+			Rates:      stats.Rates,
+			Min:        stats.Min,
+			Max:        stats.Max,
+			Average:    stats.Average,
+			OutOfScope: stats.OutOfScope,
+		}
+		if !stats.Newest.IsZero() {
+			summary.Newest = stats.Newest.Format("2006-01-02")
+		}
+		entry.Currencies = append(entry.Currencies, summary)
+	}
+	return entry
+}
+
+// History is a ring buffer of the last pools, once full every added entry replaces the oldest one
+type History struct {
+	mu      sync.RWMutex
+	entries []HistoryEntry
+	// index the next entry is written at
+	next int
+	full bool
+}
+
+func NewHistory(size int) *History {
+	return &History{entries: make([]HistoryEntry, size)}
+}
+
+func (h *History) Add(entry HistoryEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.entries[h.next] = entry
+	h.next = (h.next + 1) % len(h.entries)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// Entries returns a copy of kept entries, oldest first
+func (h *History) Entries() []HistoryEntry {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if !h.full {
+		return append([]HistoryEntry{}, h.entries[:h.next]...)
+	}
+
+	entries := make([]HistoryEntry, 0, len(h.entries))
+	entries = append(entries, h.entries[h.next:]...)
+	return append(entries, h.entries[:h.next]...)
+}
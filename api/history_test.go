@@ -0,0 +1,120 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"spyrosoft-recruitment-task/base"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testPoolTime = time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+
+// entryOfPool returns entry of the pool finished given number of minutes after testPoolTime
+func entryOfPool(minutes int) HistoryEntry {
+	return HistoryEntry{Time: testPoolTime.Add(time.Duration(minutes) * time.Minute)}
+}
+
+// poolsOf returns minutes after testPoolTime of entries
+func poolsOf(entries []HistoryEntry) []int {
+	pools := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		pools = append(pools, int(entry.Time.Sub(testPoolTime)/time.Minute))
+	}
+	return pools
+}
+
+func TestHistoryKeepsMostRecentEntriesInOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		added int
+		want  []int
+	}{
+		{"empty", 0, []int{}},
+		{"partially filled", 2, []int{0, 1}},
+		{"exactly full", 3, []int{0, 1, 2}},
+		{"overflown", 5, []int{2, 3, 4}},
+		{"overflown more than twice", 7, []int{4, 5, 6}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := NewHistory(3)
+			for pool := 0; pool < tt.added; pool++ {
+				history.Add(entryOfPool(pool))
+			}
+
+			if got := poolsOf(history.Entries()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Entries() of pools = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHistoryEntriesAreCopied(t *testing.T) {
+	history := NewHistory(2)
+	history.Add(entryOfPool(0))
+
+	entries := history.Entries()
+	history.Add(entryOfPool(1))
+	history.Add(entryOfPool(2))
+
+	if got := poolsOf(entries); !reflect.DeepEqual(got, []int{0}) {
+		t.Errorf("returned entries changed to pools %v by following Add()", got)
+	}
+}
+
+func TestHistoryIsSafeForConcurrentUse(t *testing.T) {
+	history := NewHistory(10)
+
+	var wg sync.WaitGroup
+	for writer := 0; writer < 8; writer++ {
+		wg.Add(1)
+		go func(writer int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				history.Add(entryOfPool(writer*50 + i))
+				history.Entries()
+			}
+		}(writer)
+	}
+	wg.Wait()
+
+	if entries := history.Entries(); len(entries) != 10 {
+		t.Errorf("Entries() returned %d entries, want 10", len(entries))
+	}
+}
+
+func TestNewHistoryEntry(t *testing.T) {
+	allStats := []base.PoolStats{
+		{Rates: 2, Min: 4.3, Max: 4.6, Average: 4.45, OutOfScope: 1, Newest: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{},
+	}
+
+	got := NewHistoryEntry(testPoolTime, []string{"EUR", "USD"}, allStats)
+
+	want := HistoryEntry{Time: testPoolTime, Currencies: []CurrencySummary{
+		{Currency: "EUR", Rates: 2, Min: 4.3, Max: 4.6, Average: 4.45, OutOfScope: 1, Newest: "2024-01-03"},
+		{Currency: "USD"},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewHistoryEntry() = %+v, want %+v", got, want)
+	}
+}
+
+func TestHandlerReturnsHistory(t *testing.T) {
+	state := &State{History: NewHistory(2)}
+	for pool := 0; pool < 3; pool++ {
+		entry := entryOfPool(pool)
+		entry.Currencies = []CurrencySummary{{Currency: "EUR", Rates: pool}}
+		state.History.Add(entry)
+	}
+
+	status, body := get(t, NewHandler(state), "/history")
+	want := `[{"time":"2024-01-02T12:01:00Z","currencies":[{"currency":"EUR","rates":1,"min":0,"max":0,"average":0,"out_of_scope":0}]},` +
+		`{"time":"2024-01-02T12:02:00Z","currencies":[{"currency":"EUR","rates":2,"min":0,"max":0,"average":0,"out_of_scope":0}]}]` + "\n"
+	if status != http.StatusOK || body != want {
+		t.Errorf("GET /history = %d %s, want 200 %s", status, body, want)
+	}
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"sort"
+	"spyrosoft-recruitment-task/base"
+	"sync"
+)
+
+// OutOfScopeDate is an effective date a currency was out of scope on
+type OutOfScopeDate struct {
+	Currency string `json:"currency"`
+	Date     string `json:"date"`
+}
+
+// OutOfScopeSet collects distinct out-of-scope dates of all pools since startup, workers add to it concurrently
+type OutOfScopeSet struct {
+	mu    sync.RWMutex
+	dates map[OutOfScopeDate]struct{}
+}
+
+func NewOutOfScopeSet() *OutOfScopeSet {
+	return &OutOfScopeSet{dates: map[OutOfScopeDate]struct{}{}}
+}
+
+// Add adds dates of rates of currency, dates already in the set are not added twice
+func (s *OutOfScopeSet) Add(currency string, rates []base.OutOfScopeRate) {
+	if len(rates) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, rate := range rates {
+		s.dates[OutOfScopeDate{Currency: currency, Date: rate.EffectiveDate.Format("2006-01-02")}] = struct{}{}
+	}
+}
+
+// All returns dates of the set ordered by date, then by currency
+func (s *OutOfScopeSet) All() []OutOfScopeDate {
+	s.mu.RLock()
+	all := make([]OutOfScopeDate, 0, len(s.dates))
+	for date := range s.dates {
+		all = append(all, date)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Date != all[j].Date {
+			return all[i].Date < all[j].Date
+		}
+		return all[i].Currency < all[j].Currency
+	})
+	return all
+}
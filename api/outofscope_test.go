@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"spyrosoft-recruitment-task/base"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ratesOn returns out-of-scope rates of given days of January 2024
+func ratesOn(days ...int) []base.OutOfScopeRate {
+	rates := make([]base.OutOfScopeRate, 0, len(days))
+	for _, day := range days {
+		rates = append(rates, base.OutOfScopeRate{EffectiveDate: time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC)})
+	}
+	return rates
+}
+
+func TestOutOfScopeSetDeduplicatesConcurrentlyAddedDates(t *testing.T) {
+	set := NewOutOfScopeSet()
+
+	// every worker reports days overlapping with days of the previous and the next one
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for pool := 0; pool < 20; pool++ {
+				set.Add("EUR", ratesOn(worker+1, worker+2))
+				set.Add("USD", ratesOn(1))
+				set.All()
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	want := []OutOfScopeDate{{Currency: "EUR", Date: "2024-01-01"}, {Currency: "USD", Date: "2024-01-01"}}
+	for day := 2; day <= 9; day++ {
+		want = append(want, OutOfScopeDate{Currency: "EUR", Date: time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC).Format("2006-01-02")})
+	}
+	if got := set.All(); !reflect.DeepEqual(got, want) {
+		t.Errorf("All() = %v, want %v", got, want)
+	}
+}
+
+func TestHandlerReturnsAllOutOfScopeDates(t *testing.T) {
+	state := newTestState()
+	state.AllOutOfScope.Add("USD", ratesOn(3, 1))
+	state.AllOutOfScope.Add("EUR", ratesOn(3))
+	state.AllOutOfScope.Add("USD", ratesOn(3))
+
+	status, body := get(t, NewHandler(state), "/out-of-scope/all")
+	want := `[{"currency":"USD","date":"2024-01-01"},{"currency":"EUR","date":"2024-01-03"},{"currency":"USD","date":"2024-01-03"}]` + "\n"
+	if status != http.StatusOK || body != want {
+		t.Errorf("GET /out-of-scope/all = %d %s, want 200 %s", status, body, want)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"spyrosoft-recruitment-task/base"
+	"spyrosoft-recruitment-task/logger"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+)
+
+// bandsFile holds rate bounds per currency read from -bands-file, reloaded whenever the file changes
+type bandsFile struct {
+	path string
+	// currencies the file may configure
+	table string
+
+	mu    sync.Mutex
+	bands map[string]base.RateBounds
+}
+
+type bandEntry struct {
+	Min *float64 `json:"min" yaml:"min"`
+	Max *float64 `json:"max" yaml:"max"`
+}
+
+func loadBandsFile(path string, table string) (*bandsFile, error) {
+	f := &bandsFile{path: path, table: table}
+
+	bands, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	f.bands = bands
+
+	return f, nil
+}
+
+// read parses the file, YAML or JSON mapping currency codes to {min, max}, e.g. "eur: {min: 4.5, max: 4.7}"
+func (f *bandsFile) read() (map[string]base.RateBounds, error) {
+	content, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bands file: %s", err)
+	}
+
+	// file being rewritten is seen truncated first, dropping all bands because of that would be wrong
+	if len(strings.TrimSpace(string(content))) == 0 {
+		return nil, fmt.Errorf("bands file %s is empty", f.path)
+	}
+
+	entries := map[string]bandEntry{}
+	switch strings.ToLower(filepath.Ext(f.path)) {
+	case ".json":
+		err = json.Unmarshal(content, &entries)
+	default:
+		err = yaml.Unmarshal(content, &entries)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse bands file %s: %s", f.path, err)
+	}
+
+	bands := map[string]base.RateBounds{}
+	for currency, entry := range entries {
+		currency = strings.ToLower(strings.TrimSpace(currency))
+		if !base.IsTableCurrency(f.table, currency) {
+			return nil, fmt.Errorf("bands file %s: unknown currency code %q", f.path, currency)
+		}
+		if entry.Min == nil || entry.Max == nil {
+			return nil, fmt.Errorf("bands file %s: %s requires both min and max", f.path, currency)
+		}
+		if *entry.Min > *entry.Max {
+			return nil, fmt.Errorf("bands file %s: %s min must not be greater than max", f.path, currency)
+		}
+		bands[currency] = base.RateBounds{Min: *entry.Min, Max: *entry.Max}
+	}
+
+	return bands, nil
+}
+
+// apply returns copies of targets with bounds of the file, targets of currencies it lacks are kept,
+// a pool works on the returned copies, so a reload never changes bounds in the middle of it
+func (f *bandsFile) apply(targets []*target) []*target {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	applied := make([]*target, 0, len(targets))
+	for _, t := range targets {
+		bounds, ok := f.bands[t.Currency]
+		if !ok {
+			applied = append(applied, t)
+			continue
+		}
+
+		copied := *t
+		copied.Bounds = bounds
+		applied = append(applied, &copied)
+	}
+	return applied
+}
+
+// reload replaces bands with the file content, invalid file is reported and previous bands are kept
+func (f *bandsFile) reload() {
+	bands, err := f.read()
+	if err != nil {
+		logger.Error("Failed to reload bands, keeping previous ones: %s", err)
+		return
+	}
+
+	f.mu.Lock()
+	f.bands = bands
+	f.mu.Unlock()
+
+	logger.Info("Reloaded bands of %d currencies from %s", len(bands), f.path)
+}
+
+// watch reloads bands on every change of the file until ctx is cancelled,
+// directory is watched, so files replaced by editors or config management are followed as well
+func (f *bandsFile) watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create bands file watcher: %s", err)
+	}
+
+	err = watcher.Add(filepath.Dir(f.path))
+	if err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch bands file: %s", err)
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		name := filepath.Clean(f.path)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) == name && event.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+					f.reload()
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Warn("Bands file watcher error: %s", err)
+			}
+		}
+	}()
+
+	return nil
+}
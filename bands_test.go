@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"spyrosoft-recruitment-task/base"
+	"spyrosoft-recruitment-task/logger"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeBandsFile replaces content of the bands file at path
+func writeBandsFile(t *testing.T, path string, content string) {
+	t.Helper()
+
+	err := os.WriteFile(path, []byte(content), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// outOfScopeOfPool runs a pool of cfg and returns the number of its out-of-scope dates
+func outOfScopeOfPool(t *testing.T, cfg *PoolConfig) int {
+	t.Helper()
+
+	allStats, err := runPool(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("runPool() failed: %s", err)
+	}
+	return allStats[0].OutOfScope
+}
+
+// waitForOutOfScope runs pools until one finds want out-of-scope dates, the file watcher reloads bands asynchronously
+func waitForOutOfScope(t *testing.T, cfg *PoolConfig, want int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got := outOfScopeOfPool(t, cfg)
+		if got == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("pool found %d out-of-scope dates, want %d once bands are reloaded", got, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBandsFileReloadAppliesToFollowingPools(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bands.yaml")
+	writeBandsFile(t, path, "eur: {min: 4.3, max: 4.9}\n")
+
+	cfg := newTestPoolConfig(1, testApiUrl)
+	cfg.Fetcher = fetcherFunc(func(ctx context.Context, currency string, count int) (base.ExchangeRatesSummary, error) {
+		return testSummary(currency, 4.4, 4.6, 4.8), nil
+	})
+	bands, err := loadBandsFile(path, cfg.Table)
+	if err != nil {
+		t.Fatalf("loadBandsFile() failed: %s", err)
+	}
+	cfg.Bands = bands
+	// log is captured before the watcher starts logging reloads, as output must not be replaced while it does
+	log := captureLog(t, logger.LevelError)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		// watcher exits asynchronously, give it a moment before the captured log is restored
+		time.Sleep(50 * time.Millisecond)
+	})
+	if err := bands.watch(ctx); err != nil {
+		t.Fatalf("watch() failed: %s", err)
+	}
+
+	if got := outOfScopeOfPool(t, cfg); got != 0 {
+		t.Fatalf("pool found %d out-of-scope dates within 4.3-4.9, want 0", got)
+	}
+
+	writeBandsFile(t, path, "eur: {min: 4.5, max: 4.7}\n")
+	waitForOutOfScope(t, cfg, 2)
+
+	// invalid file is reported and the bands loaded before stay in use
+	writeBandsFile(t, path, "eur: {min: 4.7, max: 4.5}\n")
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(log.String(), "Failed to reload bands, keeping previous ones") {
+		if time.Now().After(deadline) {
+			t.Fatalf("invalid bands file was not reported:\n%s", log.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := outOfScopeOfPool(t, cfg); got != 2 {
+		t.Errorf("pool found %d out-of-scope dates after invalid reload, want 2 of the previous bands", got)
+	}
+}
+
+func TestReadBandsFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    map[string]base.RateBounds
+		wantErr string
+	}{
+		{"yaml", "bands.yaml", "EUR: {min: 4.5, max: 4.7}\nusd: {min: 3.9, max: 4.2}\n",
+			map[string]base.RateBounds{"eur": {Min: 4.5, Max: 4.7}, "usd": {Min: 3.9, Max: 4.2}}, ""},
+		{"json", "bands.json", `{"eur": {"min": 4.5, "max": 4.7}}`, map[string]base.RateBounds{"eur": {Min: 4.5, Max: 4.7}}, ""},
+		{"empty", "bands.yaml", "\n", nil, "is empty"},
+		{"unknown currency", "bands.yaml", "xyz: {min: 1, max: 2}\n", nil, `unknown currency code "xyz"`},
+		{"missing max", "bands.yaml", "eur: {min: 4.5}\n", nil, "requires both min and max"},
+		{"min above max", "bands.yaml", "eur: {min: 4.7, max: 4.5}\n", nil, "min must not be greater than max"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			writeBandsFile(t, path, tt.content)
+
+			f, err := loadBandsFile(path, base.TableA)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("loadBandsFile() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadBandsFile() failed: %s", err)
+			}
+			if len(f.bands) != len(tt.want) {
+				t.Fatalf("bands = %v, want %v", f.bands, tt.want)
+			}
+			for currency, bounds := range tt.want {
+				if f.bands[currency] != bounds {
+					t.Errorf("bands of %s = %v, want %v", currency, f.bands[currency], bounds)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package base
+
+import (
+	"spyrosoft-recruitment-task/marshal"
+	"time"
+)
+
+// newRate returns rate of table number no effective on date given as 2006-01-02, no date when it is empty
+func newRate(no string, date string, mid float64) *ExchangeRate {
+	rate := &ExchangeRate{No: no, Mid: mid}
+	if date != "" {
+		rate.EffectiveDate = &marshal.CustomTime{Time: mustDate(date)}
+	}
+	return rate
+}
+
+func mustDate(date string) time.Time {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// newSummary returns EUR summary of rates
+func newSummary(rates ...*ExchangeRate) ExchangeRatesSummary {
+	return ExchangeRatesSummary{Table: "A", Currency: "euro", Code: "EUR", Rates: rates}
+}
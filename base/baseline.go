@@ -0,0 +1,76 @@
+package base
+
+import (
+	"fmt"
+	"math"
+)
+
+// Scope decides which rates are out of scope, either by static bounds or by deviation from a baseline
+type Scope interface {
+	// Classify returns out-of-scope rates, telling whether they are below or above the scope
+	Classify(rates []*ExchangeRate) []OutOfScopeRate
+	// String describes the scope in output, e.g. "4.50 - 4.70 PLN"
+	String() string
+}
+
+func (b RateBounds) Classify(rates []*ExchangeRate) []OutOfScopeRate {
+	return ClassifyOutOfScope(rates, b)
+}
+
+func (b RateBounds) String() string {
+	return fmt.Sprintf("%.2f - %.2f PLN", b.Min, b.Max)
+}
+
+// Baseline flags rates which mid deviates by more than DeviationPct percent
+// from the average mid of surrounding rates
+type Baseline struct {
+	// number of surrounding rates averaged, half of them before and half after the checked rate
+	Window       int
+	DeviationPct float64
+}
+
+// Classify checks rates ordered by effective date, rates without any neighbour in the window are never flagged
+func (b Baseline) Classify(rates []*ExchangeRate) []OutOfScopeRate {
+	sorted := sortedByDate(rates)
+	half := b.Window / 2
+
+	var outOfScope []OutOfScopeRate
+	for i, rate := range sorted {
+		var sum float64
+		var count int
+		for j := i - half; j <= i+half; j++ {
+			if j < 0 || j >= len(sorted) || j == i {
+				continue
+			}
+			sum += sorted[j].Mid
+			count++
+		}
+		if count == 0 {
+			continue
+		}
+
+		average := sum / float64(count)
+		if average == 0 {
+			continue
+		}
+
+		deviation := (rate.Mid - average) / average * 100
+		if math.Abs(deviation) <= b.DeviationPct {
+			continue
+		}
+
+		item := OutOfScopeRate{No: rate.No, Mid: rate.Mid, Direction: DirectionAbove}
+		if rate.EffectiveDate != nil {
+			item.EffectiveDate = rate.EffectiveDate.Time
+		}
+		if deviation < 0 {
+			item.Direction = DirectionBelow
+		}
+		outOfScope = append(outOfScope, item)
+	}
+	return outOfScope
+}
+
+func (b Baseline) String() string {
+	return fmt.Sprintf("±%.2f%% of %d-rate average", b.DeviationPct, b.Window)
+}
@@ -0,0 +1,55 @@
+package base
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBaselineFlagsOutlierOfSeries(t *testing.T) {
+	// NBP order is not guaranteed, 4.80 of 2024-01-05 stands out of the series once ordered by date
+	rates := []*ExchangeRate{
+		newRate("004/A/NBP/2024", "2024-01-05", 4.80),
+		newRate("001/A/NBP/2024", "2024-01-02", 4.50),
+		newRate("007/A/NBP/2024", "2024-01-10", 4.52),
+		newRate("002/A/NBP/2024", "2024-01-03", 4.51),
+		newRate("005/A/NBP/2024", "2024-01-08", 4.53),
+		newRate("003/A/NBP/2024", "2024-01-04", 4.52),
+		newRate("006/A/NBP/2024", "2024-01-09", 4.54),
+	}
+
+	tests := []struct {
+		name     string
+		baseline Baseline
+		want     []OutOfScopeRate
+	}{
+		// 6.08% above the average of 4.51, 4.52, 4.53 and 4.54
+		{"outlier", Baseline{Window: 4, DeviationPct: 3}, []OutOfScopeRate{
+			{No: "004/A/NBP/2024", EffectiveDate: mustDate("2024-01-05"), Mid: 4.80, Direction: DirectionAbove},
+		}},
+		// 4.51 is 2.1% below the average of 4.50, 4.52 and the outlier
+		{"outlier pulls neighbour", Baseline{Window: 4, DeviationPct: 2}, []OutOfScopeRate{
+			{No: "002/A/NBP/2024", EffectiveDate: mustDate("2024-01-03"), Mid: 4.51, Direction: DirectionBelow},
+			{No: "004/A/NBP/2024", EffectiveDate: mustDate("2024-01-05"), Mid: 4.80, Direction: DirectionAbove},
+		}},
+		{"deviation above outlier", Baseline{Window: 4, DeviationPct: 7}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.baseline.Classify(rates); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Classify() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBaselineOfSingleRate(t *testing.T) {
+	baseline := Baseline{Window: 4, DeviationPct: 1}
+
+	if got := baseline.Classify([]*ExchangeRate{newRate("001/A/NBP/2024", "2024-01-02", 4.5)}); len(got) != 0 {
+		t.Errorf("Classify() of a rate without neighbours = %+v, want none", got)
+	}
+	if got, want := baseline.String(), "±1.00% of 4-rate average"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,48 @@
+package base
+
+import "strings"
+
+const (
+	TableA = "a"
+	TableB = "b"
+	TableC = "c"
+)
+
+// currency codes published in NBP table A
+var tableACurrencies = map[string]struct{}{
+	"THB": {}, "USD": {}, "AUD": {}, "HKD": {}, "CAD": {}, "NZD": {}, "SGD": {},
+	"EUR": {}, "HUF": {}, "CHF": {}, "GBP": {}, "UAH": {}, "JPY": {}, "CZK": {},
+	"DKK": {}, "ISK": {}, "NOK": {}, "SEK": {}, "RON": {}, "BGN": {}, "TRY": {},
+	"ILS": {}, "CLP": {}, "PHP": {}, "MXN": {}, "ZAR": {}, "BRL": {}, "MYR": {},
+	"IDR": {}, "INR": {}, "KRW": {}, "CNY": {}, "XDR": {},
+}
+
+// currency codes published in NBP table C of buy and sell rates
+var tableCCurrencies = map[string]struct{}{
+	"USD": {}, "AUD": {}, "CAD": {}, "EUR": {}, "HUF": {}, "CHF": {}, "GBP": {},
+	"JPY": {}, "CZK": {}, "DKK": {}, "NOK": {}, "SEK": {}, "XDR": {},
+}
+
+func IsTableACurrency(code string) bool {
+	_, ok := tableACurrencies[normalizeCode(code)]
+	return ok
+}
+
+// IsTableCurrency tells whether currency is published in given NBP table,
+// table B lists over a hundred rarely traded currencies, so any three letter code is accepted for it
+func IsTableCurrency(table string, code string) bool {
+	switch strings.ToLower(table) {
+	case TableA:
+		return IsTableACurrency(code)
+	case TableB:
+		return len(normalizeCode(code)) == 3
+	case TableC:
+		_, ok := tableCCurrencies[normalizeCode(code)]
+		return ok
+	}
+	return false
+}
+
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
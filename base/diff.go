@@ -0,0 +1,69 @@
+package base
+
+import (
+	"sort"
+	"time"
+)
+
+// RateChange is a rate which mid changed since the previous pool or which was not fetched before
+type RateChange struct {
+	No            string
+	EffectiveDate time.Time
+	Old           float64
+	New           float64
+	// rate was not present in the previous pool, Old is zero
+	IsNew bool
+}
+
+// IndexRates keys rates of all summaries by their table number, duplicates fetched by many workers collapse
+func IndexRates(summaries []ExchangeRatesSummary) map[string]*ExchangeRate {
+	index := map[string]*ExchangeRate{}
+	for _, summary := range summaries {
+		for _, rate := range summary.Rates {
+			index[rate.No] = rate
+		}
+	}
+	return index
+}
+
+// DistinctRates returns rates of all summaries without duplicates fetched by many workers, ordered by effective date
+func DistinctRates(summaries []ExchangeRatesSummary) []*ExchangeRate {
+	var distinct []*ExchangeRate
+	for _, rate := range IndexRates(summaries) {
+		distinct = append(distinct, rate)
+	}
+	sortRates(distinct)
+	return distinct
+}
+
+// DiffRates returns rates of current which are new or which mid differs from previous, ordered by effective date,
+// every rate is new when previous is nil
+func DiffRates(previous, current map[string]*ExchangeRate) []RateChange {
+	var changes []RateChange
+	for no, rate := range current {
+		change := RateChange{No: no, New: rate.Mid}
+		if rate.EffectiveDate != nil {
+			change.EffectiveDate = rate.EffectiveDate.Time
+		}
+
+		old, ok := previous[no]
+		if !ok {
+			change.IsNew = true
+		} else if old.Mid == rate.Mid {
+			continue
+		} else {
+			change.Old = old.Mid
+		}
+
+		changes = append(changes, change)
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if !changes[i].EffectiveDate.Equal(changes[j].EffectiveDate) {
+			return changes[i].EffectiveDate.Before(changes[j].EffectiveDate)
+		}
+		return changes[i].No < changes[j].No
+	})
+
+	return changes
+}
@@ -0,0 +1,44 @@
+package base
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffRatesReportsOnlyChangedRates(t *testing.T) {
+	previous := IndexRates([]ExchangeRatesSummary{newSummary(
+		newRate("001/A/NBP/2024", "2024-01-02", 4.35),
+		newRate("002/A/NBP/2024", "2024-01-03", 4.36),
+	)})
+	// 002 was corrected by NBP and 003 was published since
+	current := IndexRates([]ExchangeRatesSummary{newSummary(
+		newRate("001/A/NBP/2024", "2024-01-02", 4.35),
+		newRate("002/A/NBP/2024", "2024-01-03", 4.37),
+		newRate("003/A/NBP/2024", "2024-01-04", 4.38),
+	)})
+
+	want := []RateChange{
+		{No: "002/A/NBP/2024", EffectiveDate: mustDate("2024-01-03"), Old: 4.36, New: 4.37},
+		{No: "003/A/NBP/2024", EffectiveDate: mustDate("2024-01-04"), New: 4.38, IsNew: true},
+	}
+	if got := DiffRates(previous, current); !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffRates() = %+v, want %+v", got, want)
+	}
+
+	if got := DiffRates(current, current); len(got) != 0 {
+		t.Errorf("DiffRates() of unchanged rates = %+v, want none", got)
+	}
+}
+
+func TestDiffRatesOfFirstPoolReportsEveryRate(t *testing.T) {
+	current := IndexRates([]ExchangeRatesSummary{
+		newSummary(newRate("002/A/NBP/2024", "2024-01-03", 4.36)),
+		// the same rate fetched by another worker is reported once
+		newSummary(newRate("001/A/NBP/2024", "2024-01-02", 4.35), newRate("002/A/NBP/2024", "2024-01-03", 4.36)),
+	})
+
+	got := DiffRates(nil, current)
+	if len(got) != 2 || got[0].No != "001/A/NBP/2024" || got[1].No != "002/A/NBP/2024" || !got[0].IsNew || !got[1].IsNew {
+		t.Errorf("DiffRates() of the first pool = %+v, want both rates as new, oldest first", got)
+	}
+}
@@ -0,0 +1,57 @@
+package base
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of fetch failures, matched with errors.Is, so callers can react to each of them differently
+var (
+	// ErrDecompress is a body which cannot be decompressed according to its Content-Encoding
+	ErrDecompress = errors.New("failed to decompress body")
+	// ErrUnmarshal is a body of malformed JSON or XML, or of values not matching the summary fields
+	ErrUnmarshal = errors.New("failed to unmarshal body")
+	// ErrEmptyData is a summary of a table and currency but without any rates
+	ErrEmptyData = errors.New("summary contains no rates")
+)
+
+// ErrBadStatus is a response of other status than 200 OK, matched with errors.As to tell its Code
+type ErrBadStatus struct {
+	Code int
+	// status line, e.g. "404 Not Found"
+	Status string
+	// what the body said about it, if anything
+	Err error
+}
+
+func (e *ErrBadStatus) Error() string {
+	status := e.Status
+	if status == "" {
+		status = fmt.Sprint(e.Code)
+	}
+	if e.Err == nil {
+		return "unexpected HTTP status " + status
+	}
+	return "unexpected HTTP status " + status + ": " + e.Err.Error()
+}
+
+func (e *ErrBadStatus) Unwrap() error {
+	return e.Err
+}
+
+// unmarshalError is a decoder failure, matching ErrUnmarshal while still unwrapping to the decoder error
+type unmarshalError struct {
+	err error
+}
+
+func (e *unmarshalError) Error() string {
+	return e.err.Error()
+}
+
+func (e *unmarshalError) Unwrap() error {
+	return e.err
+}
+
+func (e *unmarshalError) Is(target error) bool {
+	return target == ErrUnmarshal
+}
@@ -0,0 +1,61 @@
+package base
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestErrBadStatusAs(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"bare", &ErrBadStatus{Code: 404, Status: "404 Not Found"}},
+		{"wrapped", fmt.Errorf("range too long: %w", &ErrBadStatus{Code: 404, Status: "404 Not Found", Err: errors.New("Błędny zakres dat")})},
+		{"wrapped twice", fmt.Errorf("worker-0: %w", fmt.Errorf("fetch: %w", &ErrBadStatus{Code: 404, Err: &APIError{StatusText: "Not Found"}}))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var badStatus *ErrBadStatus
+			if !errors.As(tt.err, &badStatus) {
+				t.Fatalf("errors.As(%q) did not find *ErrBadStatus", tt.err)
+			}
+			if badStatus.Code != 404 {
+				t.Errorf("Code = %d, want 404", badStatus.Code)
+			}
+		})
+	}
+}
+
+func TestErrBadStatusAsOtherError(t *testing.T) {
+	var badStatus *ErrBadStatus
+	if errors.As(fmt.Errorf("fetch: %w", ErrEmptyData), &badStatus) {
+		t.Errorf("errors.As() found *ErrBadStatus in an error without it: %+v", badStatus)
+	}
+}
+
+func TestErrBadStatusError(t *testing.T) {
+	tests := []struct {
+		err  *ErrBadStatus
+		want string
+	}{
+		{&ErrBadStatus{Code: 503}, "unexpected HTTP status 503"},
+		{&ErrBadStatus{Code: 404, Status: "404 Not Found", Err: errors.New("Brak danych")}, "unexpected HTTP status 404 Not Found: Brak danych"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestUnmarshalErrorIs(t *testing.T) {
+	_, err := ParseSummary(strings.NewReader(`{"code": "EUR", "rates": [{"mid": "4.5"}]}`))
+	if !errors.Is(err, ErrUnmarshal) {
+		t.Errorf("ParseSummary() of mid of the wrong type returned %v, want ErrUnmarshal", err)
+	}
+}
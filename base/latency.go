@@ -0,0 +1,52 @@
+package base
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Latencies are percentiles of request times of a requests pool
+type Latencies struct {
+	Samples int
+	P50     time.Duration
+	P95     time.Duration
+	P99     time.Duration
+}
+
+// NewLatencies computes nearest-rank percentiles of samples,
+// percentiles needing more samples than there are fall back to the slowest one
+func NewLatencies(samples []time.Duration) Latencies {
+	if len(samples) == 0 {
+		return Latencies{}
+	}
+
+	sorted := make([]time.Duration, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	return Latencies{
+		Samples: len(sorted),
+		P50:     Percentile(sorted, 50),
+		P95:     Percentile(sorted, 95),
+		P99:     Percentile(sorted, 99),
+	}
+}
+
+// Percentile returns nearest-rank p-th percentile of ascending sorted samples, 0 when there are none
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
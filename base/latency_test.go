@@ -0,0 +1,52 @@
+package base
+
+import (
+	"testing"
+	"time"
+)
+
+// milliseconds returns samples of given milliseconds
+func milliseconds(ms ...int) []time.Duration {
+	samples := make([]time.Duration, 0, len(ms))
+	for _, m := range ms {
+		samples = append(samples, time.Duration(m)*time.Millisecond)
+	}
+	return samples
+}
+
+func TestNewLatencies(t *testing.T) {
+	hundred := make([]int, 0, 100)
+	for i := 100; i >= 1; i-- {
+		hundred = append(hundred, i)
+	}
+
+	tests := []struct {
+		name    string
+		samples []time.Duration
+		want    Latencies
+	}{
+		{"100 samples", milliseconds(hundred...), Latencies{Samples: 100, P50: 50 * time.Millisecond, P95: 95 * time.Millisecond, P99: 99 * time.Millisecond}},
+		// p95 and p99 need more samples than there are, so they are the slowest one
+		{"10 samples", milliseconds(70, 10, 100, 40, 20, 90, 30, 60, 80, 50), Latencies{Samples: 10, P50: 50 * time.Millisecond, P95: 100 * time.Millisecond, P99: 100 * time.Millisecond}},
+		{"single sample", milliseconds(132), Latencies{Samples: 1, P50: 132 * time.Millisecond, P95: 132 * time.Millisecond, P99: 132 * time.Millisecond}},
+		{"no samples", nil, Latencies{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewLatencies(tt.samples); got != tt.want {
+				t.Errorf("NewLatencies() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewLatenciesKeepsSamplesOrder(t *testing.T) {
+	samples := milliseconds(30, 10, 20)
+
+	NewLatencies(samples)
+
+	if samples[0] != 30*time.Millisecond || samples[1] != 10*time.Millisecond {
+		t.Errorf("NewLatencies() sorted the given samples: %v", samples)
+	}
+}
@@ -0,0 +1,188 @@
+package base
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// errorPrefixLength is how much of the body is kept to report NBP plain-text errors
+const errorPrefixLength = 200
+
+// APIError is an error reported by NBP instead of rates, e.g. "404 NotFound - Not Found - Brak danych"
+type APIError struct {
+	StatusText string
+}
+
+func (e *APIError) Error() string {
+	return "API error: " + e.StatusText
+}
+
+// InvalidJsonError is a body which looks like JSON but is malformed, unlike a body of the wrong schema
+type InvalidJsonError struct {
+	// beginning of the body
+	Snippet string
+	Err     error
+}
+
+func (e *InvalidJsonError) Error() string {
+	return "invalid JSON body: " + e.Err.Error()
+}
+
+func (e *InvalidJsonError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes invalid JSON match ErrUnmarshal
+func (e *InvalidJsonError) Is(target error) bool {
+	return target == ErrUnmarshal
+}
+
+// apiErrorBody holds fields of a JSON error object, which has no table and rates
+type apiErrorBody struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+func (b apiErrorBody) toError() error {
+	if b.Message == "" {
+		return nil
+	}
+	if b.Status != 0 {
+		return &APIError{StatusText: fmt.Sprintf("%d %s", b.Status, b.Message)}
+	}
+	return &APIError{StatusText: b.Message}
+}
+
+// ParseSummary decodes table A or B JSON summary with rates sorted ascending by effective date,
+// NBP plain-text or JSON error bodies are returned as *APIError
+func ParseSummary(r io.Reader) (ExchangeRatesSummary, error) {
+	var body struct {
+		ExchangeRatesSummary
+		apiErrorBody
+	}
+	err := parseJson(r, &body)
+	if err != nil {
+		return ExchangeRatesSummary{}, err
+	}
+
+	if body.Table == "" {
+		if apiErr := body.toError(); apiErr != nil {
+			return ExchangeRatesSummary{}, apiErr
+		}
+	}
+	body.ExchangeRatesSummary.Sort()
+	return body.ExchangeRatesSummary, nil
+}
+
+// ParseSummaryC decodes table C JSON summary, using bid or ask price as the mid rate,
+// rates are sorted and errors are reported like by ParseSummary
+func ParseSummaryC(r io.Reader, priceField string) (ExchangeRatesSummary, error) {
+	var body struct {
+		ExchangeRatesSummaryC
+		apiErrorBody
+	}
+	err := parseJson(r, &body)
+	if err != nil {
+		return ExchangeRatesSummary{}, err
+	}
+
+	if body.Table == "" {
+		if apiErr := body.toError(); apiErr != nil {
+			return ExchangeRatesSummary{}, apiErr
+		}
+	}
+	summary := body.ExchangeRatesSummaryC.ToSummary(priceField)
+	summary.Sort()
+	return summary, nil
+}
+
+// ParseSummaryXml decodes XML series of any table with rates sorted ascending by effective date,
+// plain-text error bodies are returned as *APIError
+func ParseSummaryXml(r io.Reader, priceField string) (ExchangeRatesSummary, error) {
+	prefix := &prefixBuffer{limit: errorPrefixLength}
+
+	var series ExchangeRatesSeriesXml
+	err := xml.NewDecoder(io.TeeReader(r, prefix)).Decode(&series)
+	if err != nil {
+		text := strings.TrimSpace(prefix.String())
+		if !errors.Is(err, io.ErrUnexpectedEOF) && text != "" && !strings.HasPrefix(text, "<") {
+			return ExchangeRatesSummary{}, &APIError{StatusText: text}
+		}
+
+		var syntaxErr *xml.SyntaxError
+		var unmarshalErr xml.UnmarshalError
+		if errors.As(err, &syntaxErr) || errors.As(err, &unmarshalErr) {
+			return ExchangeRatesSummary{}, &unmarshalError{err: err}
+		}
+		return ExchangeRatesSummary{}, err
+	}
+	summary := series.ToSummary(priceField)
+	summary.Sort()
+	return summary, nil
+}
+
+// parseJson decodes one JSON value and, like json.Valid, rejects anything trailing it,
+// body which is not JSON at all is taken as NBP plain-text error
+func parseJson(r io.Reader, v interface{}) error {
+	prefix := &prefixBuffer{limit: errorPrefixLength}
+	decoder := json.NewDecoder(io.TeeReader(r, prefix))
+
+	err := decoder.Decode(v)
+	if err != nil {
+		// decoder may fail in any way on plain text, e.g. "404 NotFound" starts with a valid number
+		text := strings.TrimSpace(prefix.String())
+		if !errors.Is(err, io.ErrUnexpectedEOF) && text != "" && !looksLikeJson(text) {
+			return &APIError{StatusText: text}
+		}
+
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return &InvalidJsonError{Snippet: text, Err: err}
+		}
+
+		// reader failures, e.g. ErrResponseTooLarge, are returned as they are
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return &unmarshalError{err: err}
+		}
+		return err
+	}
+
+	_, err = decoder.Token()
+	if err == io.EOF {
+		return nil
+	}
+
+	// failure of the underlying reader, e.g. broken compressed stream, is not about the data itself
+	var syntaxErr *json.SyntaxError
+	if err != nil && !errors.As(err, &syntaxErr) {
+		return err
+	}
+	return &InvalidJsonError{Snippet: strings.TrimSpace(prefix.String()), Err: errors.New("unexpected data after JSON value")}
+}
+
+func looksLikeJson(text string) bool {
+	return strings.HasPrefix(text, "{") || strings.HasPrefix(text, "[")
+}
+
+// prefixBuffer keeps only the first limit bytes written to it
+type prefixBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *prefixBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,180 @@
+package base
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// openTestdata opens response body of testdata, closed once the test ends
+func openTestdata(t *testing.T, name string) *os.File {
+	t.Helper()
+
+	file, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+	return file
+}
+
+func TestParseSummaryOfTableB(t *testing.T) {
+	// body of GET /api/exchangerates/rates/b/afn/2024-06-19/2024-07-03/, table B is published weekly
+	summary, err := ParseSummary(openTestdata(t, "afn_b_2024-06-19_2024-07-03.json"))
+	if err != nil {
+		t.Fatalf("ParseSummary() failed: %s", err)
+	}
+
+	if summary.Table != "B" || summary.Code != "AFN" || summary.Currency != "afgani (Afganistan)" || len(summary.Rates) != 3 {
+		t.Fatalf("summary = %+v, want 3 AFN rates of table B", summary)
+	}
+	if last := summary.Rates[2]; last.No != "027/B/NBP/2024" || last.Mid != 0.0568 || !last.EffectiveDate.Equal(mustDate("2024-07-03")) {
+		t.Errorf("last rate = %+v, want 027/B/NBP/2024 of mid 0.0568 on 2024-07-03", last)
+	}
+}
+
+func TestParseSummaryCOfBidAndAsk(t *testing.T) {
+	tests := []struct {
+		priceField string
+		want       []float64
+	}{
+		{PriceBid, []float64{4.2738, 4.2726, 4.2689}},
+		{PriceAsk, []float64{4.3602, 4.3590, 4.3551}},
+	}
+
+	for _, tt := range tests {
+		// body of GET /api/exchangerates/rates/c/eur/2024-07-01/2024-07-03/
+		summary, err := ParseSummaryC(openTestdata(t, "eur_c_2024-07-01_2024-07-03.json"), tt.priceField)
+		if err != nil {
+			t.Fatalf("ParseSummaryC() failed: %s", err)
+		}
+
+		if summary.Table != "C" || summary.Code != "EUR" || len(summary.Rates) != len(tt.want) {
+			t.Fatalf("summary = %+v, want %d EUR rates of table C", summary, len(tt.want))
+		}
+		for i, rate := range summary.Rates {
+			if rate.Mid != tt.want[i] {
+				t.Errorf("%s price of rate %s = %v, want %v", tt.priceField, rate.No, rate.Mid, tt.want[i])
+			}
+		}
+		if err := ValidateSummary(summary); err != nil {
+			t.Errorf("ValidateSummary() of table C = %v, want nil", err)
+		}
+	}
+}
+
+func TestParseSummaryXml(t *testing.T) {
+	// body of GET /api/exchangerates/rates/a/eur/2024-07-01/2024-07-05/ asked for with Accept: application/xml
+	summary, err := ParseSummaryXml(openTestdata(t, "eur_a_2024-07-01_2024-07-05.xml"), "")
+	if err != nil {
+		t.Fatalf("ParseSummaryXml() failed: %s", err)
+	}
+
+	if summary.Table != "A" || summary.Code != "EUR" || summary.Currency != "euro" || len(summary.Rates) != 5 {
+		t.Fatalf("summary = %+v, want 5 EUR rates of table A", summary)
+	}
+	for i, rate := range summary.Rates {
+		want := time.Date(2024, 7, 1+i, 0, 0, 0, 0, time.UTC)
+		if rate.EffectiveDate == nil || !rate.EffectiveDate.Equal(want) {
+			t.Errorf("rate %s effective date = %v, want %s", rate.No, rate.EffectiveDate, want)
+		}
+	}
+	if first := summary.Rates[0]; first.No != "126/A/NBP/2024" || first.Mid != 4.3179 {
+		t.Errorf("first rate = %+v, want 126/A/NBP/2024 of mid 4.3179", first)
+	}
+}
+
+func TestParseSummaryOfErrorBodies(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantApiErr string
+	}{
+		{"valid body", `{"table":"A","currency":"euro","code":"EUR","rates":[{"no":"001/A/NBP/2024","effectiveDate":"2024-01-02","mid":4.35}]}`, ""},
+		{"JSON error object", `{"status":404,"message":"Not Found - Brak danych"}`, "404 Not Found - Brak danych"},
+		{"plain-text error", "404 NotFound - Not Found - Brak danych", "404 NotFound - Not Found - Brak danych"},
+		{"plain-text bad request", "400 BadRequest - Błędny zakres dat / Invalid date range", "400 BadRequest - Błędny zakres dat / Invalid date range"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary, err := ParseSummary(strings.NewReader(tt.body))
+
+			if tt.wantApiErr == "" {
+				if err != nil || summary.Code != "EUR" || len(summary.Rates) != 1 {
+					t.Errorf("ParseSummary() = %+v, %v, want the EUR rate", summary, err)
+				}
+				return
+			}
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusText != tt.wantApiErr {
+				t.Errorf("ParseSummary() error = %v, want *APIError of %q", err, tt.wantApiErr)
+			}
+		})
+	}
+}
+
+func TestParseSummaryOfMalformedJson(t *testing.T) {
+	for _, body := range []string{`{"table":"A","rates":[`, `{"table":"A",}`, `{"table":"A","rates":[]} {}`} {
+		_, err := ParseSummary(strings.NewReader(body))
+
+		var apiErr *APIError
+		if err == nil || errors.As(err, &apiErr) {
+			t.Errorf("ParseSummary() of %s = %v, want error not taken as API error", body, err)
+		}
+	}
+}
+
+func TestParseSummarySortsRatesByDate(t *testing.T) {
+	// two records of 2024-01-03 keep the order NBP sent them in, rate without date comes first
+	body := `{"table":"A","currency":"euro","code":"EUR","rates":[` +
+		`{"no":"003/A/NBP/2024","effectiveDate":"2024-01-04","mid":4.7},` +
+		`{"no":"002/A/NBP/2024","effectiveDate":"2024-01-03","mid":4.6},` +
+		`{"no":"001/A/NBP/2024","effectiveDate":"2024-01-02","mid":4.5},` +
+		`{"no":"002/A/NBP/2024","effectiveDate":"2024-01-03","mid":4.65},` +
+		`{"no":"000/A/NBP/2024","mid":4.4}]}`
+
+	summary, err := ParseSummary(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("ParseSummary() failed: %s", err)
+	}
+
+	var got []float64
+	for _, rate := range summary.Rates {
+		got = append(got, rate.Mid)
+	}
+	if want := []float64{4.4, 4.5, 4.6, 4.65, 4.7}; !reflect.DeepEqual(got, want) {
+		t.Errorf("mids of parsed rates = %v, want %v", got, want)
+	}
+}
+
+func TestParseSummaryOfNbpResponse(t *testing.T) {
+	// body of GET /api/exchangerates/rates/a/eur/2024-07-01/2024-07-05/ in the exact layout NBP sends it
+	summary, err := ParseSummary(openTestdata(t, "eur_a_2024-07-01_2024-07-05.json"))
+	if err != nil {
+		t.Fatalf("ParseSummary() failed: %s", err)
+	}
+
+	if summary.Table != "A" || summary.Code != "EUR" || len(summary.Rates) != 5 {
+		t.Fatalf("summary = %+v, want 5 EUR rates of table A", summary)
+	}
+
+	for i, rate := range summary.Rates {
+		want := time.Date(2024, 7, 1+i, 0, 0, 0, 0, time.UTC)
+		if rate.EffectiveDate == nil || rate.EffectiveDate.IsZero() {
+			t.Fatalf("rate %s has no effective date", rate.No)
+		}
+		if !rate.EffectiveDate.Equal(want) {
+			t.Errorf("rate %s effective date = %s, want %s", rate.No, rate.EffectiveDate.Time, want)
+		}
+	}
+
+	if first := summary.Rates[0]; first.No != "126/A/NBP/2024" || first.Mid != 4.3179 {
+		t.Errorf("first rate = %+v, want 126/A/NBP/2024 of mid 4.3179", first)
+	}
+}
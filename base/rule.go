@@ -0,0 +1,396 @@
+package base
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DirectionMatched marks rates flagged by a Rule, which tells nothing about being above or below anything
+const DirectionMatched Direction = "matched"
+
+// Rule flags rates an expression is true of, e.g. "mid < 4.5 || mid > 4.7 || abs(change_pct) > 2".
+// Expression is made of numbers, variables mid and change_pct, abs(), arithmetic negation,
+// comparisons < <= > >= == != and boolean operators ! && || with parentheses.
+// change_pct is the change of mid in percent since the previous rate, 0 for the oldest one.
+type Rule struct {
+	source string
+	root   boolNode
+}
+
+// ruleVars are values of variables of a single rate
+type ruleVars struct {
+	mid       float64
+	changePct float64
+}
+
+type numNode interface {
+	num(v ruleVars) float64
+}
+
+type boolNode interface {
+	truth(v ruleVars) bool
+}
+
+// ParseRule parses expression into a Rule, expression must evaluate to a boolean
+func ParseRule(source string) (*Rule, error) {
+	tokens, err := tokenizeRule(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &ruleParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.kind != tokenEnd {
+		return nil, fmt.Errorf("unexpected %s at position %d", next, next.pos)
+	}
+
+	root, ok := node.(boolNode)
+	if !ok {
+		return nil, fmt.Errorf("rule %q is a number, expected a condition, e.g. mid > 4.7", source)
+	}
+	return &Rule{source: source, root: root}, nil
+}
+
+// Classify checks rates ordered by effective date, so change_pct is the one since the previous publication
+func (r *Rule) Classify(rates []*ExchangeRate) []OutOfScopeRate {
+	sorted := sortedByDate(rates)
+
+	var outOfScope []OutOfScopeRate
+	for i, rate := range sorted {
+		vars := ruleVars{mid: rate.Mid}
+		if i > 0 && sorted[i-1].Mid != 0 {
+			previous := sorted[i-1].Mid
+			vars.changePct = (rate.Mid - previous) / previous * 100
+		}
+
+		if !r.root.truth(vars) {
+			continue
+		}
+
+		item := OutOfScopeRate{No: rate.No, Mid: rate.Mid, Direction: DirectionMatched}
+		if rate.EffectiveDate != nil {
+			item.EffectiveDate = rate.EffectiveDate.Time
+		}
+		outOfScope = append(outOfScope, item)
+	}
+	return outOfScope
+}
+
+func (r *Rule) String() string {
+	return fmt.Sprintf("of rule %q", r.source)
+}
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenNumber
+	tokenIdent
+	tokenOperator
+)
+
+type ruleToken struct {
+	kind tokenKind
+	text string
+	// byte offset in the expression, told in errors
+	pos int
+}
+
+func (t ruleToken) String() string {
+	if t.kind == tokenEnd {
+		return "end of rule"
+	}
+	return strconv.Quote(t.text)
+}
+
+// ruleOperators are ordered so that two-character operators are matched before their prefixes
+var ruleOperators = []string{"||", "&&", "<=", ">=", "==", "!=", "<", ">", "!", "(", ")", "-"}
+
+func tokenizeRule(source string) ([]ruleToken, error) {
+	var tokens []ruleToken
+	for pos := 0; pos < len(source); {
+		c := rune(source[pos])
+		switch {
+		case unicode.IsSpace(c):
+			pos++
+		case unicode.IsDigit(c) || c == '.':
+			end := pos
+			for end < len(source) && (unicode.IsDigit(rune(source[end])) || source[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, ruleToken{kind: tokenNumber, text: source[pos:end], pos: pos})
+			pos = end
+		case unicode.IsLetter(c) || c == '_':
+			end := pos
+			for end < len(source) && (unicode.IsLetter(rune(source[end])) || unicode.IsDigit(rune(source[end])) || source[end] == '_') {
+				end++
+			}
+			tokens = append(tokens, ruleToken{kind: tokenIdent, text: source[pos:end], pos: pos})
+			pos = end
+		default:
+			matched := false
+			for _, op := range ruleOperators {
+				if strings.HasPrefix(source[pos:], op) {
+					tokens = append(tokens, ruleToken{kind: tokenOperator, text: op, pos: pos})
+					pos += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected %q at position %d", c, pos)
+			}
+		}
+	}
+	return append(tokens, ruleToken{kind: tokenEnd, pos: len(source)}), nil
+}
+
+// ruleParser is a recursive descent parser, from the lowest precedence: ||, &&, !, comparisons, negation
+type ruleParser struct {
+	tokens []ruleToken
+	next   int
+}
+
+func (p *ruleParser) peek() ruleToken {
+	return p.tokens[p.next]
+}
+
+func (p *ruleParser) take() ruleToken {
+	token := p.tokens[p.next]
+	if token.kind != tokenEnd {
+		p.next++
+	}
+	return token
+}
+
+func (p *ruleParser) takeOperator(op string) bool {
+	if token := p.peek(); token.kind == tokenOperator && token.text == op {
+		p.next++
+		return true
+	}
+	return false
+}
+
+func (p *ruleParser) parseOr() (interface{}, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().text == "||" {
+		op := p.take()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l, r, err := bothBool(op, left, right)
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{l, r}
+	}
+	return left, nil
+}
+
+func (p *ruleParser) parseAnd() (interface{}, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().text == "&&" {
+		op := p.take()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l, r, err := bothBool(op, left, right)
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{l, r}
+	}
+	return left, nil
+}
+
+func (p *ruleParser) parseNot() (interface{}, error) {
+	token := p.peek()
+	if !p.takeOperator("!") {
+		return p.parseComparison()
+	}
+
+	operand, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	b, ok := operand.(boolNode)
+	if !ok {
+		return nil, fmt.Errorf("operand of ! at position %d is a number, expected a condition", token.pos)
+	}
+	return notNode{b}, nil
+}
+
+func (p *ruleParser) parseComparison() (interface{}, error) {
+	left, err := p.parseNegation()
+	if err != nil {
+		return nil, err
+	}
+
+	token := p.peek()
+	switch token.text {
+	case "<", "<=", ">", ">=", "==", "!=":
+	default:
+		return left, nil
+	}
+	p.take()
+
+	right, err := p.parseNegation()
+	if err != nil {
+		return nil, err
+	}
+	l, lok := left.(numNode)
+	r, rok := right.(numNode)
+	if !lok || !rok {
+		return nil, fmt.Errorf("operands of %s at position %d must be numbers", token.text, token.pos)
+	}
+	return comparisonNode{op: token.text, left: l, right: r}, nil
+}
+
+func (p *ruleParser) parseNegation() (interface{}, error) {
+	token := p.peek()
+	if !p.takeOperator("-") {
+		return p.parsePrimary()
+	}
+
+	operand, err := p.parseNegation()
+	if err != nil {
+		return nil, err
+	}
+	n, ok := operand.(numNode)
+	if !ok {
+		return nil, fmt.Errorf("operand of - at position %d is a condition, expected a number", token.pos)
+	}
+	return negNode{n}, nil
+}
+
+func (p *ruleParser) parsePrimary() (interface{}, error) {
+	token := p.take()
+	switch token.kind {
+	case tokenNumber:
+		value, err := strconv.ParseFloat(token.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", token.text, token.pos)
+		}
+		return numberNode(value), nil
+	case tokenIdent:
+		switch token.text {
+		case "mid":
+			return midNode{}, nil
+		case "change_pct":
+			return changePctNode{}, nil
+		case "abs":
+			return p.parseAbs(token)
+		}
+		return nil, fmt.Errorf("unknown name %q at position %d, expected mid, change_pct or abs", token.text, token.pos)
+	case tokenOperator:
+		if token.text == "(" {
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.takeOperator(")") {
+				return nil, fmt.Errorf("missing ) of ( at position %d", token.pos)
+			}
+			return node, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %s at position %d", token, token.pos)
+}
+
+func (p *ruleParser) parseAbs(name ruleToken) (interface{}, error) {
+	if !p.takeOperator("(") {
+		return nil, fmt.Errorf("abs at position %d must be followed by (", name.pos)
+	}
+	operand, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.takeOperator(")") {
+		return nil, fmt.Errorf("missing ) of abs at position %d", name.pos)
+	}
+
+	n, ok := operand.(numNode)
+	if !ok {
+		return nil, fmt.Errorf("argument of abs at position %d is a condition, expected a number", name.pos)
+	}
+	return absNode{n}, nil
+}
+
+func bothBool(op ruleToken, left interface{}, right interface{}) (boolNode, boolNode, error) {
+	l, lok := left.(boolNode)
+	r, rok := right.(boolNode)
+	if !lok || !rok {
+		return nil, nil, fmt.Errorf("operands of %s at position %d must be conditions, e.g. mid > 4.7", op.text, op.pos)
+	}
+	return l, r, nil
+}
+
+type numberNode float64
+
+func (n numberNode) num(ruleVars) float64 { return float64(n) }
+
+type midNode struct{}
+
+func (midNode) num(v ruleVars) float64 { return v.mid }
+
+type changePctNode struct{}
+
+func (changePctNode) num(v ruleVars) float64 { return v.changePct }
+
+type negNode struct{ operand numNode }
+
+func (n negNode) num(v ruleVars) float64 { return -n.operand.num(v) }
+
+type absNode struct{ operand numNode }
+
+func (n absNode) num(v ruleVars) float64 { return math.Abs(n.operand.num(v)) }
+
+type comparisonNode struct {
+	op    string
+	left  numNode
+	right numNode
+}
+
+func (n comparisonNode) truth(v ruleVars) bool {
+	l, r := n.left.num(v), n.right.num(v)
+	switch n.op {
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	case ">=":
+		return l >= r
+	case "==":
+		return l == r
+	default:
+		return l != r
+	}
+}
+
+type notNode struct{ operand boolNode }
+
+func (n notNode) truth(v ruleVars) bool { return !n.operand.truth(v) }
+
+type andNode struct{ left, right boolNode }
+
+func (n andNode) truth(v ruleVars) bool { return n.left.truth(v) && n.right.truth(v) }
+
+type orNode struct{ left, right boolNode }
+
+func (n orNode) truth(v ruleVars) bool { return n.left.truth(v) || n.right.truth(v) }
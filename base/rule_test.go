@@ -0,0 +1,108 @@
+package base
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRuleEvaluatesExpressions(t *testing.T) {
+	tests := []struct {
+		rule      string
+		mid       float64
+		changePct float64
+		want      bool
+	}{
+		{"mid < 4.5 || mid > 4.7", 4.4, 0, true},
+		{"mid < 4.5 || mid > 4.7", 4.6, 0, false},
+		{"mid < 4.5 || mid > 4.7", 4.8, 0, true},
+		{"mid < 4.5 || mid > 4.7 || change_pct > 2", 4.6, 2.5, true},
+		{"mid < 4.5 || mid > 4.7 || change_pct > 2", 4.6, -2.5, false},
+		{"abs(change_pct) > 2", 4.6, -2.5, true},
+		{"abs(change_pct) >= 2", 4.6, 2, true},
+		{"change_pct < -1", 4.6, -1.5, true},
+		{"-change_pct > 1", 4.6, -1.5, true},
+		{"mid == 4.6 && change_pct != 0", 4.6, 0, false},
+		{"mid <= 4.6 && !(change_pct == 0)", 4.6, 1, true},
+		{"!mid > 4.7", 4.6, 0, true},
+		// && binds tighter than ||
+		{"mid > 5 && mid < 6 || change_pct > 1", 4.6, 2, true},
+		{"mid > 5 && (mid < 6 || change_pct > 1)", 4.6, 2, false},
+		{" mid>4.5&&mid<4.7 ", 4.6, 0, true},
+	}
+
+	for _, tt := range tests {
+		rule, err := ParseRule(tt.rule)
+		if err != nil {
+			t.Errorf("ParseRule(%q) failed: %s", tt.rule, err)
+			continue
+		}
+		if got := rule.root.truth(ruleVars{mid: tt.mid, changePct: tt.changePct}); got != tt.want {
+			t.Errorf("%q of mid %v change_pct %v = %t, want %t", tt.rule, tt.mid, tt.changePct, got, tt.want)
+		}
+	}
+}
+
+func TestParseRuleRejectsInvalidExpression(t *testing.T) {
+	tests := []struct {
+		rule string
+		// part of the error
+		want string
+	}{
+		{"", "unexpected end of rule at position 0"},
+		{"mid", "is a number, expected a condition"},
+		{"mid > 4.7 extra", `unexpected "extra" at position 10`},
+		{"mid > 4.7 || 4.5", "operands of || at position 10 must be conditions"},
+		{"mid > (mid < 4)", "operands of > at position 4 must be numbers"},
+		{"rate > 4.7", `unknown name "rate" at position 0`},
+		{"mid > 4.7 $", `unexpected '$' at position 10`},
+		{"(mid > 4.7", "missing ) of ( at position 0"},
+		{"abs mid > 2", "abs at position 0 must be followed by ("},
+		{"abs(mid > 2) > 1", "argument of abs at position 0 is a condition"},
+		{"!mid", "operand of ! at position 0 is a number"},
+		{"-(mid > 2)", "operand of - at position 0 is a condition"},
+		{"mid > 4..7", `invalid number "4..7" at position 6`},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseRule(tt.rule)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseRule(%q) error = %v, want error with %q", tt.rule, err, tt.want)
+		}
+	}
+}
+
+func TestRuleClassifiesRatesOrderedByDate(t *testing.T) {
+	// change_pct is the one since the previous date, not the previous rate in NBP order
+	rates := []*ExchangeRate{
+		newRate("003/A/NBP/2024", "2024-01-04", 4.70),
+		newRate("001/A/NBP/2024", "2024-01-02", 4.40),
+		newRate("004/A/NBP/2024", "2024-01-05", 4.60),
+		newRate("002/A/NBP/2024", "2024-01-03", 4.55),
+	}
+	rule, err := ParseRule("mid < 4.5 || abs(change_pct) > 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 4.40 is below, 4.55 is 3.41% up from 4.40, 4.70 is 3.30% up from 4.55 and 4.60 is 2.13% down from 4.70
+	want := []OutOfScopeRate{
+		{No: "001/A/NBP/2024", EffectiveDate: mustDate("2024-01-02"), Mid: 4.40, Direction: DirectionMatched},
+		{No: "002/A/NBP/2024", EffectiveDate: mustDate("2024-01-03"), Mid: 4.55, Direction: DirectionMatched},
+		{No: "003/A/NBP/2024", EffectiveDate: mustDate("2024-01-04"), Mid: 4.70, Direction: DirectionMatched},
+		{No: "004/A/NBP/2024", EffectiveDate: mustDate("2024-01-05"), Mid: 4.60, Direction: DirectionMatched},
+	}
+	if got := rule.Classify(rates); !reflect.DeepEqual(got, want) {
+		t.Errorf("Classify() = %+v, want %+v", got, want)
+	}
+
+	rule, err = ParseRule("change_pct > 3.35")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the oldest rate has no change
+	want = want[1:2]
+	if got := rule.Classify(rates); !reflect.DeepEqual(got, want) {
+		t.Errorf("Classify() of %s = %+v, want %+v", rule, got, want)
+	}
+}
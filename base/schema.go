@@ -0,0 +1,81 @@
+package base
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+//go:embed schema/*.schema.json
+var schemaFiles embed.FS
+
+var (
+	summarySchema  = mustCompileSchema("schema/summary.schema.json")
+	summaryCSchema = mustCompileSchema("schema/summary_c.schema.json")
+)
+
+func mustCompileSchema(path string) *jsonschema.Schema {
+	content, err := schemaFiles.ReadFile(path)
+	if err != nil {
+		panic(fmt.Sprintf("embedded schema %s: %s", path, err))
+	}
+	return jsonschema.MustCompileString(path, string(content))
+}
+
+// SchemaError lists every place where a body does not conform to the NBP response schema
+type SchemaError struct {
+	// e.g. "/rates/0/mid: expected number, but got string"
+	Violations []string
+}
+
+func (e *SchemaError) Error() string {
+	return "response does not match schema: " + strings.Join(e.Violations, "; ")
+}
+
+// ValidateSchema checks JSON body of table rates against the embedded schema of the table,
+// which catches renamed fields and changed types the decoder silently ignores
+func ValidateSchema(body []byte, table string) error {
+	schema := summarySchema
+	if strings.ToLower(table) == TableC {
+		schema = summaryCSchema
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	// validator needs numbers as json.Number, float64 would lose the distinction of integers
+	decoder.UseNumber()
+
+	var document interface{}
+	err := decoder.Decode(&document)
+	if err != nil {
+		return fmt.Errorf("failed to decode body for schema validation: %s", err)
+	}
+
+	err = schema.Validate(document)
+	var validationErr *jsonschema.ValidationError
+	if errors.As(err, &validationErr) {
+		return &SchemaError{Violations: schemaViolations(validationErr)}
+	}
+	return err
+}
+
+// schemaViolations flattens validation error into messages of its leaf causes prefixed with their paths
+func schemaViolations(err *jsonschema.ValidationError) []string {
+	if len(err.Causes) == 0 {
+		location := err.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		return []string{location + ": " + err.Message}
+	}
+
+	var violations []string
+	for _, cause := range err.Causes {
+		violations = append(violations, schemaViolations(cause)...)
+	}
+	return violations
+}
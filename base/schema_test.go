@@ -0,0 +1,55 @@
+package base
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestValidateSchema(t *testing.T) {
+	conforming, err := io.ReadAll(openTestdata(t, "eur_a_2024-07-01_2024-07-05.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conformingC, err := io.ReadAll(openTestdata(t, "eur_c_2024-07-01_2024-07-03.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		table          string
+		wantViolations []string
+	}{
+		{"conforming", string(conforming), TableA, nil},
+		{"conforming table C", string(conformingC), TableC, nil},
+		{"wrong field type",
+			`{"table":"A","currency":"euro","code":"EUR","rates":[{"no":"126/A/NBP/2024","effectiveDate":"2024-07-01","mid":"4.3179"}]}`,
+			TableA, []string{"/rates/0/mid: expected number, but got string"}},
+		{"missing required field",
+			`{"table":"A","currency":"euro","code":"EUR","rates":[{"no":"126/A/NBP/2024","mid":4.3179}]}`,
+			TableA, []string{"/rates/0: missing properties: 'effectiveDate'"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSchema([]byte(tt.body), tt.table)
+
+			if tt.wantViolations == nil {
+				if err != nil {
+					t.Errorf("ValidateSchema() failed: %s", err)
+				}
+				return
+			}
+			var schemaErr *SchemaError
+			if !errors.As(err, &schemaErr) {
+				t.Fatalf("ValidateSchema() error = %v, want *SchemaError", err)
+			}
+			if !reflect.DeepEqual(schemaErr.Violations, tt.wantViolations) {
+				t.Errorf("violations = %q, want %q", schemaErr.Violations, tt.wantViolations)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package base
+
+import "time"
+
+// PoolStats aggregates rates fetched by all successful workers of a requests pool
+type PoolStats struct {
+	// currency the stats are of, empty when the pool fetches a single one
+	Currency string
+	Fetches  int
+	Rates    int
+	Min      float64
+	Max      float64
+	Average  float64
+	// distinct effective dates of out-of-scope rates, however many workers fetched them
+	OutOfScope int
+	// latest effective date among all rates, zero when unknown
+	Newest time.Time
+	// dates published more than once, workers fetching the same records report each date once
+	Duplicates []DuplicateDate
+	// standard deviation of mid of distinct rates
+	StdDev float64
+	// distinct rates which mid moved by more than VolatilityPct percent day-over-day
+	VolatileDays  []*ExchangeRate
+	VolatilityPct float64
+	// request times of workers which performed a request, cache hits are not counted
+	Latency Latencies
+}
+
+// NewPoolStats computes stats of given summaries, all values stay zero when there are no rates,
+// out-of-scope rates are the ones scope classifies as such,
+// rates moving by more than volatilityPct percent day-over-day are counted as volatile days
+func NewPoolStats(summaries []ExchangeRatesSummary, scope Scope, volatilityPct float64) PoolStats {
+	stats := PoolStats{Fetches: len(summaries), VolatilityPct: volatilityPct}
+
+	var sum float64
+	var outOfScope []OutOfScopeRate
+	duplicateDates := map[time.Time]bool{}
+	for _, summary := range summaries {
+		for _, rate := range summary.Rates {
+			if stats.Rates == 0 || rate.Mid < stats.Min {
+				stats.Min = rate.Mid
+			}
+			if stats.Rates == 0 || rate.Mid > stats.Max {
+				stats.Max = rate.Mid
+			}
+			sum += rate.Mid
+			stats.Rates++
+		}
+		outOfScope = append(outOfScope, scope.Classify(summary.Rates)...)
+
+		if newest, ok := summary.Newest(); ok && newest.After(stats.Newest) {
+			stats.Newest = newest
+		}
+
+		for _, duplicate := range summary.Duplicates() {
+			if !duplicateDates[duplicate.Date] {
+				duplicateDates[duplicate.Date] = true
+				stats.Duplicates = append(stats.Duplicates, duplicate)
+			}
+		}
+	}
+
+	if stats.Rates > 0 {
+		stats.Average = sum / float64(stats.Rates)
+	}
+	// every worker fetches the same window, so the same rate is out of scope in each of their summaries
+	stats.OutOfScope = countDates(outOfScope)
+
+	// every worker fetches the same window, so series is built of distinct records
+	distinct := DistinctRates(summaries)
+	stats.StdDev = StdDev(distinct)
+	stats.VolatileDays = VolatileDays(distinct, volatilityPct)
+
+	return stats
+}
+
+// countDates returns number of distinct effective dates of rates, rates without the date are counted one by one
+func countDates(rates []OutOfScopeRate) int {
+	dates := map[time.Time]bool{}
+	count := 0
+	for _, rate := range rates {
+		if rate.EffectiveDate.IsZero() || !dates[rate.EffectiveDate] {
+			dates[rate.EffectiveDate] = true
+			count++
+		}
+	}
+	return count
+}
+
+// Staleness returns how old the newest rate is at given moment, false when there are no dated rates
+func (s PoolStats) Staleness(now time.Time) (time.Duration, bool) {
+	if s.Newest.IsZero() {
+		return 0, false
+	}
+	return now.Sub(s.Newest), true
+}
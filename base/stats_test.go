@@ -0,0 +1,60 @@
+package base
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNewPoolStatsAcrossWorkers(t *testing.T) {
+	// every worker fetches the same window, the second one got a newer rate too
+	first := newSummary(
+		newRate("001/A/NBP/2024", "2024-01-02", 4.4),
+		newRate("002/A/NBP/2024", "2024-01-03", 4.6),
+	)
+	second := newSummary(
+		newRate("001/A/NBP/2024", "2024-01-02", 4.4),
+		newRate("002/A/NBP/2024", "2024-01-03", 4.6),
+		newRate("003/A/NBP/2024", "2024-01-04", 4.8),
+	)
+
+	stats := NewPoolStats([]ExchangeRatesSummary{first, second}, RateBounds{Min: 4.5, Max: 4.7}, 0.5)
+
+	if stats.Fetches != 2 {
+		t.Errorf("Fetches = %d, want 2", stats.Fetches)
+	}
+	if stats.Rates != 5 {
+		t.Errorf("Rates = %d, want 5", stats.Rates)
+	}
+	if stats.Min != 4.4 || stats.Max != 4.8 {
+		t.Errorf("Min/Max = %.2f/%.2f, want 4.40/4.80", stats.Min, stats.Max)
+	}
+	if want := (4.4 + 4.6 + 4.4 + 4.6 + 4.8) / 5; math.Abs(stats.Average-want) > 1e-9 {
+		t.Errorf("Average = %.4f, want %.4f", stats.Average, want)
+	}
+	// 2024-01-02 below and 2024-01-04 above, each counted once although both workers fetched the first one
+	if stats.OutOfScope != 2 {
+		t.Errorf("OutOfScope = %d, want 2 distinct dates", stats.OutOfScope)
+	}
+	if want := mustDate("2024-01-04"); !stats.Newest.Equal(want) {
+		t.Errorf("Newest = %s, want %s", stats.Newest, want)
+	}
+}
+
+func TestNewPoolStatsWithoutRates(t *testing.T) {
+	stats := NewPoolStats([]ExchangeRatesSummary{newSummary()}, RateBounds{Min: 4.5, Max: 4.7}, 0.5)
+
+	if stats.Fetches != 1 || stats.Rates != 0 {
+		t.Errorf("Fetches/Rates = %d/%d, want 1/0", stats.Fetches, stats.Rates)
+	}
+	if stats.Average != 0 || stats.Min != 0 || stats.Max != 0 || stats.OutOfScope != 0 {
+		t.Errorf("stats of no rates = %+v, want all zero", stats)
+	}
+}
+
+func TestNewPoolStatsOfNoSummaries(t *testing.T) {
+	stats := NewPoolStats(nil, RateBounds{Min: 4.5, Max: 4.7}, 0.5)
+
+	if stats.Fetches != 0 || stats.Rates != 0 || stats.Average != 0 {
+		t.Errorf("stats of no summaries = %+v, want all zero", stats)
+	}
+}
@@ -0,0 +1,41 @@
+package base
+
+import "spyrosoft-recruitment-task/marshal"
+
+const (
+	PriceBid = "bid"
+	PriceAsk = "ask"
+)
+
+// RateC is a buy (bid) and sell (ask) rate of NBP table C
+type RateC struct {
+	No            string              `json:"no"`
+	EffectiveDate *marshal.CustomTime `json:"effectiveDate"`
+	Bid           float64             `json:"bid"`
+	Ask           float64             `json:"ask"`
+}
+
+type ExchangeRatesSummaryC struct {
+	Table    string   `json:"table"`
+	Currency string   `json:"currency"`
+	Code     string   `json:"code"`
+	Rates    []*RateC `json:"rates"`
+}
+
+// ToSummary converts table C summary to the common one, using bid or ask price as the mid rate
+func (s ExchangeRatesSummaryC) ToSummary(priceField string) ExchangeRatesSummary {
+	summary := ExchangeRatesSummary{Table: s.Table, Currency: s.Currency, Code: s.Code}
+	for _, rate := range s.Rates {
+		if rate == nil {
+			summary.Rates = append(summary.Rates, nil)
+			continue
+		}
+
+		price := rate.Bid
+		if priceField == PriceAsk {
+			price = rate.Ask
+		}
+		summary.Rates = append(summary.Rates, &ExchangeRate{No: rate.No, EffectiveDate: rate.EffectiveDate, Mid: price})
+	}
+	return summary
+}
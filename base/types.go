@@ -1,6 +1,10 @@
 package base
 
-import "spyrosoft-recruitment-task/marshal"
+import (
+	"sort"
+	"spyrosoft-recruitment-task/marshal"
+	"time"
+)
 
 type ExchangeRate struct {
 	No            string              `json:"no"`
@@ -14,3 +18,161 @@ type ExchangeRatesSummary struct {
 	Code     string          `json:"code"`
 	Rates    []*ExchangeRate `json:"rates"`
 }
+
+type RateBounds struct {
+	Min float64
+	Max float64
+}
+
+func (b RateBounds) Contains(mid float64) bool {
+	return mid >= b.Min && mid <= b.Max
+}
+
+// OutOfScope returns rates with mid outside of given bounds, in the order of summary
+func (s ExchangeRatesSummary) OutOfScope(bounds RateBounds) []*ExchangeRate {
+	var outOfScope []*ExchangeRate
+	for _, rate := range s.Rates {
+		if !bounds.Contains(rate.Mid) {
+			outOfScope = append(outOfScope, rate)
+		}
+	}
+	return outOfScope
+}
+
+// Average returns mean mid of all rates, 0 when there are none
+func (s ExchangeRatesSummary) Average() float64 {
+	if len(s.Rates) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, rate := range s.Rates {
+		sum += rate.Mid
+	}
+	return sum / float64(len(s.Rates))
+}
+
+type Direction string
+
+const (
+	DirectionBelow Direction = "below"
+	DirectionAbove Direction = "above"
+)
+
+// OutOfScopeRate is a rate which mid is below or above accepted bounds
+type OutOfScopeRate struct {
+	No            string
+	EffectiveDate time.Time
+	Mid           float64
+	Direction     Direction
+}
+
+// ClassifyOutOfScope returns rates with mid outside of bounds, telling whether they are below or above them
+func ClassifyOutOfScope(rates []*ExchangeRate, bounds RateBounds) []OutOfScopeRate {
+	var outOfScope []OutOfScopeRate
+	for _, rate := range rates {
+		if bounds.Contains(rate.Mid) {
+			continue
+		}
+
+		item := OutOfScopeRate{No: rate.No, Mid: rate.Mid, Direction: DirectionAbove}
+		if rate.EffectiveDate != nil {
+			item.EffectiveDate = rate.EffectiveDate.Time
+		}
+		if rate.Mid < bounds.Min {
+			item.Direction = DirectionBelow
+		}
+		outOfScope = append(outOfScope, item)
+	}
+	return outOfScope
+}
+
+// Newest returns the latest effective date among rates, false when no rate has it set
+func (s ExchangeRatesSummary) Newest() (time.Time, bool) {
+	var newest time.Time
+	for _, rate := range s.Rates {
+		if rate.EffectiveDate != nil && rate.EffectiveDate.After(newest) {
+			newest = rate.EffectiveDate.Time
+		}
+	}
+	return newest, !newest.IsZero()
+}
+
+// Within returns the summary with only rates effective at most window before the newest rate,
+// rates without effective date are kept, as their age is unknown. Window of 0 keeps all rates.
+func (s ExchangeRatesSummary) Within(window time.Duration) ExchangeRatesSummary {
+	newest, ok := s.Newest()
+	if window <= 0 || !ok {
+		return s
+	}
+
+	cutoff := newest.Add(-window)
+	rates := make([]*ExchangeRate, 0, len(s.Rates))
+	for _, rate := range s.Rates {
+		if rate.EffectiveDate == nil || !rate.EffectiveDate.Before(cutoff) {
+			rates = append(rates, rate)
+		}
+	}
+
+	s.Rates = rates
+	return s
+}
+
+// DuplicateDate is an effective date published by NBP more than once
+type DuplicateDate struct {
+	Date time.Time
+	// mids of all records with the date, in the order of summary
+	Mids []float64
+}
+
+// Duplicates groups rates by effective date and returns dates with more than one record, ordered by date
+func (s ExchangeRatesSummary) Duplicates() []DuplicateDate {
+	mids := map[time.Time][]float64{}
+	var dates []time.Time
+	for _, rate := range s.Rates {
+		if rate.EffectiveDate == nil {
+			continue
+		}
+
+		date := rate.EffectiveDate.Time
+		if _, ok := mids[date]; !ok {
+			dates = append(dates, date)
+		}
+		mids[date] = append(mids[date], rate.Mid)
+	}
+
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].Before(dates[j])
+	})
+
+	var duplicates []DuplicateDate
+	for _, date := range dates {
+		if len(mids[date]) > 1 {
+			duplicates = append(duplicates, DuplicateDate{Date: date, Mids: mids[date]})
+		}
+	}
+	return duplicates
+}
+
+// Sort orders rates ascending by effective date, keeping NBP order of rates with equal dates
+func (s *ExchangeRatesSummary) Sort() {
+	sortRates(s.Rates)
+}
+
+// sortRates sorts rates in place by effective date, rates without the date go first
+func sortRates(rates []*ExchangeRate) {
+	sort.SliceStable(rates, func(i, j int) bool {
+		a, b := effectiveDate(rates[i]), effectiveDate(rates[j])
+		if a == nil || b == nil {
+			return a == nil && b != nil
+		}
+		return a.Before(b.Time)
+	})
+}
+
+func effectiveDate(rate *ExchangeRate) *marshal.CustomTime {
+	if rate == nil {
+		return nil
+	}
+	return rate.EffectiveDate
+}
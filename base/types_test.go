@@ -0,0 +1,165 @@
+package base
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOutOfScope(t *testing.T) {
+	bounds := RateBounds{Min: 4.5, Max: 4.7}
+	summary := newSummary(
+		newRate("001/A/NBP/2024", "2024-01-02", 4.49),
+		newRate("002/A/NBP/2024", "2024-01-03", 4.5),
+		newRate("003/A/NBP/2024", "2024-01-04", 4.6),
+		newRate("004/A/NBP/2024", "2024-01-05", 4.7),
+		newRate("005/A/NBP/2024", "2024-01-08", 4.71),
+	)
+
+	outOfScope := summary.OutOfScope(bounds)
+	if len(outOfScope) != 2 || outOfScope[0].No != "001/A/NBP/2024" || outOfScope[1].No != "005/A/NBP/2024" {
+		t.Errorf("OutOfScope() = %v, want rates below and above bounds, ones at the bounds are within them", outOfScope)
+	}
+}
+
+func TestOutOfScopeOfRatesWithinBounds(t *testing.T) {
+	summary := newSummary(newRate("001/A/NBP/2024", "2024-01-02", 4.55), newRate("002/A/NBP/2024", "2024-01-03", 4.65))
+
+	if outOfScope := summary.OutOfScope(RateBounds{Min: 4.5, Max: 4.7}); len(outOfScope) != 0 {
+		t.Errorf("OutOfScope() = %v, want none", outOfScope)
+	}
+}
+
+func TestSummaryOutOfScopeAndAverage(t *testing.T) {
+	tests := []struct {
+		name           string
+		rates          []*ExchangeRate
+		wantOutOfScope []string
+		wantAverage    float64
+	}{
+		{"empty rates", nil, nil, 0},
+		{"all in band", []*ExchangeRate{
+			newRate("001/A/NBP/2024", "2024-01-02", 4.5),
+			newRate("002/A/NBP/2024", "2024-01-03", 4.7),
+		}, nil, 4.6},
+		{"mixed", []*ExchangeRate{
+			newRate("001/A/NBP/2024", "2024-01-02", 4.4),
+			newRate("002/A/NBP/2024", "2024-01-03", 4.6),
+			newRate("003/A/NBP/2024", "2024-01-04", 4.8),
+		}, []string{"001/A/NBP/2024", "003/A/NBP/2024"}, 4.6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary := newSummary(tt.rates...)
+
+			var got []string
+			for _, rate := range summary.OutOfScope(RateBounds{Min: 4.5, Max: 4.7}) {
+				got = append(got, rate.No)
+			}
+			if len(got) != len(tt.wantOutOfScope) {
+				t.Fatalf("OutOfScope() = %v, want %v", got, tt.wantOutOfScope)
+			}
+			for i := range got {
+				if got[i] != tt.wantOutOfScope[i] {
+					t.Errorf("OutOfScope() = %v, want %v", got, tt.wantOutOfScope)
+				}
+			}
+
+			if average := summary.Average(); math.Abs(average-tt.wantAverage) > 1e-9 {
+				t.Errorf("Average() = %v, want %v", average, tt.wantAverage)
+			}
+		})
+	}
+}
+
+func TestClassifyOutOfScope(t *testing.T) {
+	rates := []*ExchangeRate{
+		newRate("001/A/NBP/2024", "2024-01-02", 4.3),
+		newRate("002/A/NBP/2024", "2024-01-03", 4.6),
+		newRate("003/A/NBP/2024", "2024-01-04", 4.9),
+	}
+
+	got := ClassifyOutOfScope(rates, RateBounds{Min: 4.5, Max: 4.7})
+
+	want := []OutOfScopeRate{
+		{No: "001/A/NBP/2024", EffectiveDate: mustDate("2024-01-02"), Mid: 4.3, Direction: DirectionBelow},
+		{No: "003/A/NBP/2024", EffectiveDate: mustDate("2024-01-04"), Mid: 4.9, Direction: DirectionAbove},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ClassifyOutOfScope() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("out-of-scope rate %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary ExchangeRatesSummary
+		want    []DuplicateDate
+	}{
+		{"clean", newSummary(
+			newRate("001/A/NBP/2024", "2024-01-02", 4.4),
+			newRate("002/A/NBP/2024", "2024-01-03", 4.6),
+		), nil},
+		{"duplicate date", newSummary(
+			newRate("002/A/NBP/2024", "2024-01-03", 4.6),
+			newRate("001/A/NBP/2024", "2024-01-02", 4.4),
+			newRate("002/A/NBP/2024", "2024-01-03", 4.65),
+		), []DuplicateDate{{Date: mustDate("2024-01-03"), Mids: []float64{4.6, 4.65}}}},
+		// rates without effective date cannot collide
+		{"no dates", newSummary(newRate("001/A/NBP/2024", "", 4.4), newRate("002/A/NBP/2024", "", 4.6)), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.summary.Duplicates(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Duplicates() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummaryWithin(t *testing.T) {
+	// NBP order is not guaranteed, the window is counted back from the newest date
+	summary := newSummary(
+		newRate("003/A/NBP/2024", "2024-01-04", 4.6),
+		newRate("001/A/NBP/2024", "2024-01-02", 4.4),
+		newRate("005/A/NBP/2024", "2024-01-08", 4.65),
+		newRate("004/A/NBP/2024", "2024-01-05", 4.8),
+		newRate("002/A/NBP/2024", "2024-01-03", 4.5),
+	)
+
+	tests := []struct {
+		name   string
+		window time.Duration
+		want   []string
+	}{
+		{"disabled", 0, []string{"003/A/NBP/2024", "001/A/NBP/2024", "005/A/NBP/2024", "004/A/NBP/2024", "002/A/NBP/2024"}},
+		{"newest only", time.Hour, []string{"005/A/NBP/2024"}},
+		// rate exactly at the cutoff is within the window
+		{"4 days", 4 * 24 * time.Hour, []string{"003/A/NBP/2024", "005/A/NBP/2024", "004/A/NBP/2024"}},
+		{"longer than all rates", 30 * 24 * time.Hour, []string{"003/A/NBP/2024", "001/A/NBP/2024", "005/A/NBP/2024", "004/A/NBP/2024", "002/A/NBP/2024"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, rate := range summary.Within(tt.window).Rates {
+				got = append(got, rate.No)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Within(%s) = %v, want %v", tt.window, got, tt.want)
+			}
+		})
+	}
+
+	if len(summary.Rates) != 5 {
+		t.Errorf("Within() changed rates of the summary to %d, want it to return a filtered copy", len(summary.Rates))
+	}
+}
@@ -0,0 +1,33 @@
+package base
+
+import "fmt"
+
+// ValidateSummary checks that unmarshalled summary carries the NBP table data,
+// json.Unmarshal happily turns an unrelated JSON object into an empty summary
+func ValidateSummary(summary ExchangeRatesSummary) error {
+	var missing []string
+	if summary.Table == "" {
+		missing = append(missing, "table")
+	}
+	if summary.Currency == "" {
+		missing = append(missing, "currency")
+	}
+	if summary.Code == "" {
+		missing = append(missing, "code")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("summary is missing required fields: %v", missing)
+	}
+
+	if len(summary.Rates) == 0 {
+		return ErrEmptyData
+	}
+
+	for i, rate := range summary.Rates {
+		if rate == nil {
+			return fmt.Errorf("rate at position %d is null", i)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package base
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestValidateSummary(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"well-formed", `{"table":"A","currency":"euro","code":"EUR","rates":[{"no":"001/A/NBP/2024","effectiveDate":"2024-01-02","mid":4.6}]}`, ""},
+		{"empty rates", `{"table":"A","currency":"euro","code":"EUR","rates":[]}`, "summary contains no rates"},
+		{"missing code", `{"table":"A","currency":"euro","rates":[{"no":"001/A/NBP/2024","effectiveDate":"2024-01-02","mid":4.6}]}`, "missing required fields: [code]"},
+		{"unrelated object", `{"id":1}`, "missing required fields: [table currency code]"},
+		{"null rate", `{"table":"A","currency":"euro","code":"EUR","rates":[null]}`, "rate at position 0 is null"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var summary ExchangeRatesSummary
+			err := json.Unmarshal([]byte(tt.body), &summary)
+			if err != nil {
+				t.Fatalf("json.Unmarshal() failed: %s", err)
+			}
+
+			err = ValidateSummary(summary)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateSummary() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateSummary() = %v, want error of %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package base
+
+import "math"
+
+// sortedByDate returns copy of rates ordered by effective date
+func sortedByDate(rates []*ExchangeRate) []*ExchangeRate {
+	sorted := make([]*ExchangeRate, len(rates))
+	copy(sorted, rates)
+	sortRates(sorted)
+	return sorted
+}
+
+// StdDev returns population standard deviation of mid of given rates, 0 when there are less than two
+func StdDev(rates []*ExchangeRate) float64 {
+	if len(rates) < 2 {
+		return 0
+	}
+
+	var sum float64
+	for _, rate := range rates {
+		sum += rate.Mid
+	}
+	mean := sum / float64(len(rates))
+
+	var squares float64
+	for _, rate := range rates {
+		squares += (rate.Mid - mean) * (rate.Mid - mean)
+	}
+	return math.Sqrt(squares / float64(len(rates)))
+}
+
+// VolatileDays returns rates which mid moved by more than pct percent since the previous publication,
+// rates are ordered by effective date first, as NBP order is not guaranteed
+func VolatileDays(rates []*ExchangeRate, pct float64) []*ExchangeRate {
+	sorted := sortedByDate(rates)
+
+	var volatile []*ExchangeRate
+	for i := 1; i < len(sorted); i++ {
+		previous := sorted[i-1].Mid
+		if previous == 0 {
+			continue
+		}
+
+		change := math.Abs(sorted[i].Mid-previous) / previous * 100
+		if change > pct {
+			volatile = append(volatile, sorted[i])
+		}
+	}
+	return volatile
+}
@@ -0,0 +1,73 @@
+package base
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStdDev(t *testing.T) {
+	tests := []struct {
+		name string
+		mids []float64
+		want float64
+	}{
+		// mean of 5 and squared deviations summing to 32 over 8 rates
+		{"known series", []float64{2, 4, 4, 4, 5, 5, 7, 9}, 2},
+		{"constant", []float64{4.5, 4.5, 4.5}, 0},
+		{"single rate", []float64{4.5}, 0},
+		{"no rates", nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rates := make([]*ExchangeRate, 0, len(tt.mids))
+			for _, mid := range tt.mids {
+				rates = append(rates, newRate("", "", mid))
+			}
+			if got := StdDev(rates); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("StdDev(%v) = %v, want %v", tt.mids, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVolatileDaysOrdersRatesByDate(t *testing.T) {
+	// NBP order is not guaranteed, moves are 1%, 0%, -3.47% and 0.26% once ordered by date
+	rates := []*ExchangeRate{
+		newRate("004/A/NBP/2024", "2024-01-05", 3.9),
+		newRate("001/A/NBP/2024", "2024-01-02", 4.0),
+		newRate("005/A/NBP/2024", "2024-01-08", 3.91),
+		newRate("003/A/NBP/2024", "2024-01-04", 4.04),
+		newRate("002/A/NBP/2024", "2024-01-03", 4.04),
+	}
+
+	tests := []struct {
+		pct  float64
+		want []string
+	}{
+		{0.5, []string{"002/A/NBP/2024", "004/A/NBP/2024"}},
+		{2, []string{"004/A/NBP/2024"}},
+		{5, nil},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, rate := range VolatileDays(rates, tt.pct) {
+			got = append(got, rate.No)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("VolatileDays(%v%%) = %v, want %v", tt.pct, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("VolatileDays(%v%%) = %v, want %v", tt.pct, got, tt.want)
+				break
+			}
+		}
+	}
+
+	if rates[0].No != "004/A/NBP/2024" {
+		t.Errorf("VolatileDays() reordered the given rates")
+	}
+}
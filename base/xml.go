@@ -0,0 +1,46 @@
+package base
+
+import (
+	"encoding/xml"
+	"spyrosoft-recruitment-task/marshal"
+	"strings"
+)
+
+// RateXml is a rate of any NBP table as returned in XML format, only fields of the requested table are set
+type RateXml struct {
+	No            string              `xml:"No"`
+	EffectiveDate *marshal.CustomTime `xml:"EffectiveDate"`
+	Mid           float64             `xml:"Mid"`
+	Bid           float64             `xml:"Bid"`
+	Ask           float64             `xml:"Ask"`
+}
+
+type ExchangeRatesSeriesXml struct {
+	XMLName  xml.Name   `xml:"ExchangeRatesSeries"`
+	Table    string     `xml:"Table"`
+	Currency string     `xml:"Currency"`
+	Code     string     `xml:"Code"`
+	Rates    []*RateXml `xml:"Rates>Rate"`
+}
+
+// ToSummary converts XML series to the common summary, for table C bid or ask price is used as the mid rate
+func (s ExchangeRatesSeriesXml) ToSummary(priceField string) ExchangeRatesSummary {
+	summary := ExchangeRatesSummary{Table: s.Table, Currency: s.Currency, Code: s.Code}
+	isTableC := strings.ToLower(s.Table) == TableC
+	for _, rate := range s.Rates {
+		if rate == nil {
+			summary.Rates = append(summary.Rates, nil)
+			continue
+		}
+
+		price := rate.Mid
+		if isTableC {
+			price = rate.Bid
+			if priceField == PriceAsk {
+				price = rate.Ask
+			}
+		}
+		summary.Rates = append(summary.Rates, &ExchangeRate{No: rate.No, EffectiveDate: rate.EffectiveDate, Mid: price})
+	}
+	return summary
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"spyrosoft-recruitment-task/logger"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned instead of fetching while the circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open, request skipped")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	// cooldown passed, a single probe request decides whether the breaker closes or opens again
+	breakerHalfOpen
+)
+
+// circuitBreaker stops requests to the API after threshold consecutive failures,
+// so workers of following pools do not hammer an endpoint which is down
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	clock     Clock
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	// probe request of half-open breaker is in flight
+	probing bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration, clock Clock) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, clock: clock}
+}
+
+// allow tells whether a request may be performed, it lets through a single probe once cooldown passes
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerOpen {
+		if b.clock.Now().Sub(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		logger.Info("Circuit breaker half-open, probing API with a single request")
+		b.state = breakerHalfOpen
+		b.probing = false
+	}
+
+	if b.state == breakerHalfOpen {
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+	}
+
+	return nil
+}
+
+// record updates the breaker with the outcome of an allowed request
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		if b.state != breakerClosed {
+			logger.Info("Circuit breaker closed, API responds again")
+		}
+		b.state = breakerClosed
+		b.failures = 0
+		b.probing = false
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || (b.state == breakerClosed && b.failures >= b.threshold) {
+		logger.Warn("Circuit breaker opened after %d consecutive failures, requests are skipped for %s", b.failures, b.cooldown)
+		b.state = breakerOpen
+		b.openedAt = b.clock.Now()
+		b.probing = false
+	}
+}
+
+// abandon releases the probe of a request cancelled before it had an outcome
+func (b *circuitBreaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// breakerFetch fetches only while breaker allows it, requests cancelled by the pool are not counted as failures
+func breakerFetch(breaker *circuitBreaker, fetch fetchFunc) fetchFunc {
+	return func(ctx context.Context, index int) (*fetchResult, error) {
+		err := breaker.allow()
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := fetch(ctx, index)
+		if ctx.Err() != nil {
+			breaker.abandon()
+			return result, err
+		}
+
+		// no publication today and a summary without rates are answers of a healthy API
+		if errors.Is(err, ErrNoDataToday) || !fetchFailed(err) {
+			breaker.record(nil)
+		} else {
+			breaker.record(err)
+		}
+		return result, err
+	}
+}
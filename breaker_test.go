@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerGoesThroughAllStates(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC))
+	breaker := newCircuitBreaker(3, time.Minute, clock)
+
+	// outcome of the next request reaching the API
+	var outcome error
+	var requests int
+	fetch := breakerFetch(breaker, func(ctx context.Context, index int) (*fetchResult, error) {
+		requests++
+		if outcome != nil {
+			return nil, outcome
+		}
+		return okResult(), nil
+	})
+	unavailable := errors.New("unexpected HTTP status 503 Service Unavailable")
+
+	steps := []struct {
+		name         string
+		advance      time.Duration
+		outcome      error
+		wantErr      error
+		wantRequests int
+		wantState    breakerState
+	}{
+		{"first failure", 0, unavailable, unavailable, 1, breakerClosed},
+		{"second failure", 0, unavailable, unavailable, 2, breakerClosed},
+		{"threshold reached", 0, unavailable, unavailable, 3, breakerOpen},
+		{"skipped while open", 59 * time.Second, nil, ErrCircuitOpen, 3, breakerOpen},
+		{"failed probe", time.Second, unavailable, unavailable, 4, breakerOpen},
+		{"skipped after failed probe", 0, nil, ErrCircuitOpen, 4, breakerOpen},
+		{"successful probe", time.Minute, nil, nil, 5, breakerClosed},
+		// failures counted before the breaker closed are forgotten
+		{"failure after closing", 0, unavailable, unavailable, 6, breakerClosed},
+	}
+
+	for _, step := range steps {
+		clock.Advance(step.advance)
+		outcome = step.outcome
+
+		_, err := fetch(context.Background(), 0)
+
+		if !errors.Is(err, step.wantErr) || (step.wantErr == nil && err != nil) {
+			t.Errorf("%s: fetch() error = %v, want %v", step.name, err, step.wantErr)
+		}
+		if requests != step.wantRequests {
+			t.Errorf("%s: %d requests reached the API, want %d", step.name, requests, step.wantRequests)
+		}
+		if breaker.state != step.wantState {
+			t.Errorf("%s: breaker state = %d, want %d", step.name, breaker.state, step.wantState)
+		}
+	}
+}
+
+func TestHalfOpenCircuitBreakerLetsThroughSingleProbe(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC))
+	breaker := newCircuitBreaker(1, time.Minute, clock)
+	breaker.allow()
+	breaker.record(errors.New("connection refused"))
+	clock.Advance(time.Minute)
+
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("allow() of the probe = %v, want nil", err)
+	}
+	if err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("allow() while the probe is in flight = %v, want %v", err, ErrCircuitOpen)
+	}
+
+	// probe cancelled by the pool has no outcome, the next request probes instead
+	breaker.abandon()
+	if err := breaker.allow(); err != nil {
+		t.Errorf("allow() after abandoned probe = %v, want nil", err)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"spyrosoft-recruitment-task/logger"
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	result    fetchResult
+	expiresAt time.Time
+}
+
+// responseCache keeps decoded API responses keyed by request URL for ttl, it is safe for concurrent workers
+type responseCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	clock   Clock
+	entries map[string]cacheEntry
+}
+
+func newResponseCache(ttl time.Duration, clock Clock) *responseCache {
+	return &responseCache{ttl: ttl, clock: clock, entries: map[string]cacheEntry{}}
+}
+
+func (c *responseCache) get(key string, now time.Time) (fetchResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return fetchResult{}, false
+	}
+
+	if !now.Before(entry.expiresAt) {
+		delete(c.entries, key)
+		return fetchResult{}, false
+	}
+
+	return entry.result, true
+}
+
+func (c *responseCache) set(key string, result fetchResult, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = cacheEntry{result: result, expiresAt: now.Add(c.ttl)}
+}
+
+// cachedFetch serves results from cache while they are fresh and caches results of successful fetches
+func cachedFetch(cache *responseCache, key string, fetch fetchFunc) fetchFunc {
+	return func(ctx context.Context, index int) (*fetchResult, error) {
+		if cached, ok := cache.get(key, cache.clock.Now()); ok {
+			logger.Info("%s Cache hit, skipping request", workerTag(ctx, index))
+
+			cached.elapsed = 0
+			cached.cached = true
+			return &cached, nil
+		}
+
+		result, err := fetch(ctx, index)
+		if err != nil {
+			return nil, err
+		}
+
+		cache.set(key, *result, cache.clock.Now())
+		return result, nil
+	}
+}
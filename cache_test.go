@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"spyrosoft-recruitment-task/base"
+	"spyrosoft-recruitment-task/logger"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// okResult returns result of a successful request of EUR summary without rates
+func okResult() *fetchResult {
+	return &fetchResult{
+		summary:     base.ExchangeRatesSummary{Table: "A", Currency: "euro", Code: "EUR"},
+		elapsed:     100 * time.Millisecond,
+		statusCode:  200,
+		contentType: "application/json",
+		isJsonValid: true,
+	}
+}
+
+func TestResponseCacheExpiresAfterTtl(t *testing.T) {
+	cache := newResponseCache(10*time.Second, realClock{})
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	cache.set(testApiUrl, *okResult(), now)
+
+	if _, ok := cache.get(testApiUrl, now.Add(10*time.Second-time.Nanosecond)); !ok {
+		t.Errorf("get() within ttl missed, want a cache hit")
+	}
+	if _, ok := cache.get(testApiUrl, now.Add(10*time.Second)); ok {
+		t.Errorf("get() once ttl passed hit, want a miss")
+	}
+	if _, ok := cache.get("other", now); ok {
+		t.Errorf("get() of other key hit, want a miss")
+	}
+}
+
+func TestCachedFetchLogsCacheHit(t *testing.T) {
+	var requests int
+	fetch := cachedFetch(newResponseCache(time.Minute, realClock{}), testApiUrl, func(ctx context.Context, index int) (*fetchResult, error) {
+		requests++
+		return okResult(), nil
+	})
+	output := captureLog(t, logger.LevelInfo)
+
+	for index := 0; index < 2; index++ {
+		result, err := fetch(context.Background(), index)
+		if err != nil {
+			t.Fatalf("fetch failed: %s", err)
+		}
+		if index == 1 && (result.elapsed != 0 || !result.cached) {
+			t.Errorf("cached result took %s, cached %t, want 0 and marked as cached", result.elapsed, result.cached)
+		}
+	}
+
+	if requests != 1 {
+		t.Errorf("%d requests, want 1 of the first fetch", requests)
+	}
+	if got := strings.Count(output.String(), "Cache hit, skipping request"); got != 1 {
+		t.Errorf("log has %d cache hits, want 1 of the second fetch:\n%s", got, output)
+	}
+}
+
+func TestCachedFetchOfConcurrentWorkers(t *testing.T) {
+	cache := newResponseCache(time.Minute, realClock{})
+	captureLog(t, logger.LevelInfo)
+
+	var wg sync.WaitGroup
+	for _, key := range []string{"eur", "usd", "chf"} {
+		code := strings.ToUpper(key)
+		fetch := cachedFetch(cache, key, func(ctx context.Context, index int) (*fetchResult, error) {
+			result := okResult()
+			result.summary.Code = code
+			return result, nil
+		})
+
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(code string, index int) {
+				defer wg.Done()
+				result, err := fetch(context.Background(), index)
+				if err != nil || result.summary.Code != code {
+					t.Errorf("fetch of %s = %+v, %v, want its own summary", code, result, err)
+				}
+			}(code, i)
+		}
+	}
+	wg.Wait()
+
+	for _, key := range []string{"eur", "usd", "chf"} {
+		if _, ok := cache.get(key, time.Now()); !ok {
+			t.Errorf("result of %s is not cached", key)
+		}
+	}
+}
+
+func TestCachedFetchExpiresOnClock(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC))
+	cache := newResponseCache(10*time.Second, clock)
+
+	var requests int
+	fetch := cachedFetch(cache, testApiUrl, func(ctx context.Context, index int) (*fetchResult, error) {
+		requests++
+		return okResult(), nil
+	})
+	captureLog(t, logger.LevelInfo)
+
+	fetchAfter := func(advance time.Duration) int {
+		t.Helper()
+		clock.Advance(advance)
+		if _, err := fetch(context.Background(), 0); err != nil {
+			t.Fatalf("fetch failed: %s", err)
+		}
+		return requests
+	}
+
+	if got := fetchAfter(0); got != 1 {
+		t.Fatalf("first fetch made %d requests, want 1", got)
+	}
+	if got := fetchAfter(10*time.Second - time.Nanosecond); got != 1 {
+		t.Errorf("fetch within ttl made a request, want a cache hit")
+	}
+	if got := fetchAfter(time.Nanosecond); got != 2 {
+		t.Errorf("fetch once ttl passed was served from cache, want a request")
+	}
+}
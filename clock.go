@@ -0,0 +1,26 @@
+package main
+
+import "time"
+
+// Clock abstracts time used by scheduling of requests pools, cache expiry, retries and request timing,
+// so it can be replaced in tests
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is Clock backed by the time package, telling time in -timezone
+type realClock struct {
+	location *time.Location
+}
+
+func (c realClock) Now() time.Time {
+	if c.location == nil {
+		return time.Now()
+	}
+	return time.Now().In(c.location)
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// command is a mode of the program selected by the first argument, parsing the rest with its own FlagSet
+// of only flags which matter to it, a single -config file or NBP_* environment still works with every one of them
+type command struct {
+	name        string
+	description string
+	// flags parsed by the command, the rest keep their defaults
+	flags []flagGroup
+	// sets fields of Config implied by the command
+	apply func(cfg *Config)
+}
+
+// commands of the program
+var commands = []command{
+	{
+		name:        "watch",
+		description: "Fetch rates every -interval until stopped.",
+		flags:       []flagGroup{sourceFlags, scopeFlags, logFlags, poolFlags, watchFlags},
+		apply:       func(cfg *Config) {},
+	},
+	{
+		name:        "once",
+		description: "Run a single requests pool and exit, same as -once.",
+		flags:       []flagGroup{sourceFlags, scopeFlags, logFlags, poolFlags, onceFlags},
+		apply:       func(cfg *Config) { cfg.Once = true },
+	},
+	{
+		name:        "health",
+		description: "Fetch every currency once, log OK or FAIL and exit with code 0 or 2, same as -healthcheck.",
+		flags:       []flagGroup{sourceFlags, logFlags},
+		apply:       func(cfg *Config) { cfg.Healthcheck = true },
+	},
+}
+
+// defaultCommand runs when the first argument is not a command name, e.g. a flag,
+// it watches like before commands were added, taking flags of all of them, including -once and -healthcheck
+var defaultCommand = command{
+	name:        "watch",
+	description: "Fetch rates every -interval until stopped, or as -once or -healthcheck select.",
+	flags:       allFlagGroups,
+	apply:       func(cfg *Config) {},
+}
+
+// parseCommand returns command named by the first of args and arguments following it,
+// args which do not start with a command name are all passed to defaultCommand
+func parseCommand(args []string) (command, []string) {
+	if len(args) > 0 {
+		for _, c := range commands {
+			if args[0] == c.name {
+				return c, args[1:]
+			}
+		}
+	}
+	return defaultCommand, args
+}
+
+// loadConfig parses args with a FlagSet of the command, which usage tells about other commands too,
+// usage and parse errors are printed to output, flag.ErrHelp is returned once usage is printed for -h
+func (c command) loadConfig(args []string, output io.Writer) (Config, error) {
+	// parse errors are returned instead of exiting, so they exit with ExitInvalidConfig
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		names := make([]string, 0, len(commands))
+		for _, other := range commands {
+			names = append(names, other.name)
+		}
+
+		fmt.Fprintf(fs.Output(), "Usage: %s [%s] [flags]\n\n", os.Args[0], strings.Join(names, "|"))
+		fmt.Fprintf(fs.Output(), "%s: %s\n\nFlags:\n", c.name, c.description)
+		fs.PrintDefaults()
+	}
+
+	cfg, err := loadConfig(fs, args, c.flags)
+	if err != nil {
+		return Config{}, err
+	}
+
+	c.apply(&cfg)
+	return cfg, nil
+}
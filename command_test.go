@@ -0,0 +1,172 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"spyrosoft-recruitment-task/logger"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		args     []string
+		wantName string
+		wantRest []string
+	}{
+		{[]string{"once", "-currency", "usd"}, "once", []string{"-currency", "usd"}},
+		{[]string{"health"}, "health", []string{}},
+		{[]string{"watch", "-interval", "10s"}, "watch", []string{"-interval", "10s"}},
+		// flags without a command are passed on as they are
+		{[]string{"-once", "-currency", "usd"}, "watch", []string{"-once", "-currency", "usd"}},
+		{nil, "watch", nil},
+	}
+
+	for _, tt := range tests {
+		cmd, rest := parseCommand(tt.args)
+		if cmd.name != tt.wantName || strings.Join(rest, " ") != strings.Join(tt.wantRest, " ") {
+			t.Errorf("parseCommand(%q) = %s %q, want %s %q", tt.args, cmd.name, rest, tt.wantName, tt.wantRest)
+		}
+	}
+}
+
+func TestCommandParsesOnlyItsFlags(t *testing.T) {
+	tests := []struct {
+		args    []string
+		wantErr bool
+		check   func(cfg Config) bool
+	}{
+		{[]string{"once", "-workers", "3", "-fail-on-out-of-scope"}, false, func(cfg Config) bool {
+			return cfg.Once && !cfg.Healthcheck && cfg.Workers == 3 && cfg.FailOutOfScope
+		}},
+		{[]string{"watch", "-max-runtime", "1m", "-http-addr", ":8080"}, false, func(cfg Config) bool {
+			return !cfg.Once && !cfg.Healthcheck && cfg.MaxRuntime == time.Minute && cfg.HttpAddr == ":8080"
+		}},
+		// flags of pools are not parsed, but keep their defaults
+		{[]string{"health", "-currency", "usd"}, false, func(cfg Config) bool {
+			return cfg.Healthcheck && cfg.Currency == "usd" && cfg.Workers == DefaultWorkers && cfg.Interval == DefaultInterval
+		}},
+		{[]string{"-once", "-max-runtime", "1m", "-pushgateway-url", "http://localhost:9091"}, false, func(cfg Config) bool {
+			return cfg.Once && cfg.MaxRuntime == time.Minute && cfg.PushgatewayUrl == "http://localhost:9091"
+		}},
+		{[]string{"health", "-workers", "3"}, true, nil},
+		{[]string{"once", "-max-runtime", "1m"}, true, nil},
+		{[]string{"once", "-once"}, true, nil},
+		{[]string{"watch", "-pushgateway-url", "http://localhost:9091"}, true, nil},
+	}
+
+	for _, tt := range tests {
+		cmd, rest := parseCommand(tt.args)
+		cfg, err := cmd.loadConfig(rest, io.Discard)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("loadConfig of %q succeeded, want error of a flag of another command", tt.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("loadConfig of %q failed: %s", tt.args, err)
+			continue
+		}
+		if !tt.check(cfg) {
+			t.Errorf("loadConfig of %q = %+v, not parsed as expected", tt.args, cfg)
+		}
+	}
+}
+
+func TestCommandSkipsConfigFileOptionsOfOtherCommands(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte("currency: usd\nworkers: 3\nmax-runtime: 1m\npushgateway-url: http://localhost:9091\n"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd, rest := parseCommand([]string{"health", "-config", path})
+	cfg, err := cmd.loadConfig(rest, io.Discard)
+	if err != nil {
+		t.Fatalf("health with config file of options of other commands failed: %s", err)
+	}
+	if cfg.Currency != "usd" || cfg.Workers != DefaultWorkers || cfg.MaxRuntime != 0 || cfg.PushgatewayUrl != "" {
+		t.Errorf("health applied options of other commands: %+v", cfg)
+	}
+
+	cmd, rest = parseCommand([]string{"once", "-config", path})
+	cfg, err = cmd.loadConfig(rest, io.Discard)
+	if err != nil {
+		t.Fatalf("once with config file of options of other commands failed: %s", err)
+	}
+	if cfg.Workers != 3 || cfg.PushgatewayUrl != "http://localhost:9091" || cfg.MaxRuntime != 0 {
+		t.Errorf("once did not apply just its options: %+v", cfg)
+	}
+
+	err = os.WriteFile(path, []byte("no-such-option: 1\n"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cmd.loadConfig([]string{"-config", path}, io.Discard)
+	if err == nil {
+		t.Error("loadConfig of config file with an option of no command succeeded, want error")
+	}
+}
+
+func TestRunDispatchesCommands(t *testing.T) {
+	server := newEncodedNbpServer(t, "", summaryJson("eur", 4.55, 4.6))
+
+	tests := []struct {
+		args []string
+		// lines expected in log of the command and how many times
+		want map[string]int
+	}{
+		{[]string{"health"}, map[string]int{"Healthcheck OK": 1, "Successful Requests": 0}},
+		{[]string{"once", "-workers", "2"}, map[string]int{"Healthcheck OK": 0, "Successful Requests: 2": 1}},
+		{[]string{"-healthcheck"}, map[string]int{"Healthcheck OK": 1, "Successful Requests": 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.args[0], func(t *testing.T) {
+			logFile := filepath.Join(t.TempDir(), "log.txt")
+			args := append(tt.args, "-api-base-url", server.URL, "-color", "never", "-log-file", logFile)
+
+			logger.Close()
+			code := run(args)
+			initTestLogger(logger.LevelError, io.Discard)
+
+			if code != ExitOk {
+				t.Fatalf("run(%q) = %d, want %d", args, code, ExitOk)
+			}
+			content, err := os.ReadFile(logFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for line, count := range tt.want {
+				if got := strings.Count(string(content), line); got != count {
+					t.Errorf("log of %q has %d lines of %q, want %d:\n%s", args, got, line, count, content)
+				}
+			}
+		})
+	}
+}
+
+func TestRunWatchRepeatsPoolsUntilMaxRuntime(t *testing.T) {
+	server := newEncodedNbpServer(t, "", summaryJson("eur", 4.55, 4.6))
+	logFile := filepath.Join(t.TempDir(), "log.txt")
+	args := []string{"watch", "-workers", "1", "-interval", "20ms", "-cache-ttl", "-1s", "-max-runtime", "300ms",
+		"-api-base-url", server.URL, "-color", "never", "-log-file", logFile}
+
+	logger.Close()
+	code := run(args)
+	initTestLogger(logger.LevelError, io.Discard)
+
+	if code != ExitOk {
+		t.Fatalf("run(%q) = %d, want %d", args, code, ExitOk)
+	}
+	content, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pools := strings.Count(string(content), "Successful Requests: 1"); pools < 2 {
+		t.Errorf("watch ran %d pools within -max-runtime, want more than one:\n%s", pools, content)
+	}
+}
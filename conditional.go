@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+)
+
+// validators are ETag and Last-Modified of the last successful response of an URL, along with its result
+type validators struct {
+	etag         string
+	lastModified string
+	result       fetchResult
+}
+
+// conditionalStore makes repeated requests of an URL conditional, so NBP can answer 304 Not Modified
+// instead of sending the same rates again
+type conditionalStore struct {
+	mu      sync.Mutex
+	entries map[string]validators
+}
+
+// addHeaders sets If-None-Match and If-Modified-Since of the last response of apiUrl, if any
+func (s *conditionalStore) addHeaders(req *http.Request, apiUrl string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[apiUrl]
+	if !ok {
+		return
+	}
+	if entry.etag != "" {
+		req.Header.Set("If-None-Match", entry.etag)
+	}
+	if entry.lastModified != "" {
+		req.Header.Set("If-Modified-Since", entry.lastModified)
+	}
+}
+
+// remember keeps validators of a successful response, responses without any are not kept
+func (s *conditionalStore) remember(apiUrl string, header http.Header, result fetchResult) {
+	entry := validators{
+		etag:         header.Get("ETag"),
+		lastModified: header.Get("Last-Modified"),
+		result:       result,
+	}
+	if entry.etag == "" && entry.lastModified == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.entries == nil {
+		s.entries = map[string]validators{}
+	}
+	s.entries[apiUrl] = entry
+}
+
+// lastResult returns result of the response 304 Not Modified refers to
+func (s *conditionalStore) lastResult(apiUrl string) (fetchResult, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[apiUrl]
+	return entry.result, ok
+}
@@ -0,0 +1,610 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"spyrosoft-recruitment-task/api"
+	"spyrosoft-recruitment-task/base"
+	"spyrosoft-recruitment-task/logger"
+	"spyrosoft-recruitment-task/metrics"
+	"spyrosoft-recruitment-task/notify"
+	"spyrosoft-recruitment-task/report"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	DefaultApiBaseUrl = "http://api.nbp.pl/api/exchangerates/rates/"
+
+	DefaultCurrency = "eur"
+	DefaultCount    = 100
+	DefaultWorkers  = 10
+
+	DefaultInterval       = 5 * time.Second
+	DefaultRequestTimeout = 3 * time.Second
+
+	// even 255 records of table C take far less, more means a misbehaving endpoint
+	DefaultMaxBodyBytes = 4 << 20
+
+	// consecutive failed fetches opening the circuit breaker, and how long it stays open
+	DefaultTripThreshold = 5
+	DefaultTripCooldown  = 30 * time.Second
+
+	// NBP does not publish on weekends and holidays, so a few days old data is expected
+	DefaultMaxStaleness = 4 * 24 * time.Hour
+
+	// NBP refuses to return more than 255 records in a single query
+	MaxCount = 255
+
+	// day-over-day change of mid in percent above which a day is reported as volatile
+	DefaultVolatilityPct = 0.5
+
+	// with -mode baseline, rates deviating by more than DefaultDeviationPct percent
+	// from the average of DefaultBaselineWindow surrounding rates are out of scope
+	DefaultBaselineWindow = 4
+	DefaultDeviationPct   = 1.0
+
+	DefaultRateMin = 4.5
+	DefaultRateMax = 4.7
+)
+
+// modes of deciding which rates are out of scope
+const (
+	ModeBand     = "band"
+	ModeBaseline = "baseline"
+	ModeRule     = "rule"
+)
+
+// Version is set at build time with -ldflags "-X main.Version=<version>"
+var Version = "dev"
+
+type Config struct {
+	ApiBaseUrl     string
+	Host           string
+	UserAgent      string
+	Proxy          string
+	CaFile         string
+	TlsSkipVerify  bool
+	ResponseFormat string
+	InputFile      string
+	Strict         bool
+	Table          string
+	PriceField     string
+	Currency       string
+	Currencies     string
+	Count          int
+	From           string
+	To             string
+	Today          bool
+	Workers        int
+	MaxConcurrency int
+	MaxRetries     int
+	MaxRetryAfter  time.Duration
+	TripThreshold  int
+	TripCooldown   time.Duration
+	RequestTimeout time.Duration
+	LatencySla     time.Duration
+	MaxBodyBytes   int64
+	Bounds         base.RateBounds
+	Bands          string
+	BandsFile      string
+	Mode           string
+	BaselineWindow int
+	DeviationPct   float64
+	Rule           string
+	VolatilityPct  float64
+	LogFormat      string
+	LogLevel       string
+	LogFile        string
+	Color          string
+	Quiet          bool
+	OrderedOutput  bool
+	OutputCsv      string
+	ReportFile     string
+	ReportFormat   string
+	ReportAppend   bool
+	DbPath         string
+	MetricsAddr    string
+	PushgatewayUrl string
+	PushgatewayJob string
+	OtelEndpoint   string
+	HttpAddr       string
+	HistorySize    int
+	Notifier       string
+	WebhookUrl     string
+	SmtpAddr       string
+	SmtpFrom       string
+	SmtpTo         string
+	SmtpUsername   string
+	SmtpPassword   string
+	AlertCooldown  time.Duration
+	Once           bool
+	FailOutOfScope bool
+	DryRun         bool
+	Healthcheck    bool
+	MaxRuntime     time.Duration
+	RateLimit      float64
+	Burst          int
+	Interval       time.Duration
+	MaxInterval    time.Duration
+	DateFormat     string
+	Timezone       string
+	MaxStaleness   time.Duration
+	AnalysisWindow time.Duration
+	Dedupe         bool
+	Diff           bool
+	CacheTtl       time.Duration
+	DumpResponse   bool
+	Verbose        bool
+	Trace          bool
+}
+
+// loadConfig builds Config from command line args parsed by fs,
+// with precedence: command line flags > environment variables > -config file > built-in defaults.
+// Config file is a flat YAML or JSON object keyed by flag names, e.g. {"currency": "usd", "interval": "10s"}.
+// Environment variable of a flag is its upper-cased name prefixed with NBP_, e.g. NBP_RATE_MIN for -rate-min.
+// Only flags of groups are parsed, the rest keep their defaults.
+func loadConfig(fs *flag.FlagSet, args []string, groups []flagGroup) (Config, error) {
+	var cfg Config
+	// registering sets defaults, flags of other commands are only known here
+	all := flag.NewFlagSet("all", flag.ContinueOnError)
+	for _, register := range allFlagGroups {
+		register(all, &cfg)
+	}
+
+	configFile := fs.String("config", "", "path of YAML or JSON config file, keys are flag names")
+	for _, register := range groups {
+		register(fs, &cfg)
+	}
+
+	err := fs.Parse(args)
+	if err != nil {
+		return Config{}, err
+	}
+
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	if *configFile != "" {
+		err = applyConfigFile(fs, all, explicit, *configFile)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	err = applyEnv(fs, explicit)
+	if err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// flagGroup registers flags of related Config fields, commands parse only groups which matter to them
+type flagGroup func(fs *flag.FlagSet, cfg *Config)
+
+// allFlagGroups are flags of all commands, parsed when no command is named
+var allFlagGroups = []flagGroup{sourceFlags, scopeFlags, logFlags, poolFlags, watchFlags, onceFlags, legacyFlags}
+
+// sourceFlags select what is fetched and how, every command fetches rates
+func sourceFlags(fs *flag.FlagSet, cfg *Config) {
+	fs.StringVar(&cfg.ApiBaseUrl, "api-base-url", DefaultApiBaseUrl, "base URL of NBP rates API, table and currency are appended to it")
+	fs.StringVar(&cfg.Host, "host", "", "Host header sent to API, e.g. api.nbp.pl when -api-base-url points at a mock server, host of -api-base-url when empty")
+	fs.StringVar(&cfg.UserAgent, "user-agent", "spyrosoft-recruitment-task/"+Version, "User-Agent header sent to API")
+	fs.StringVar(&cfg.Proxy, "proxy", "", "http://, https:// or socks5:// proxy URL of API requests, HTTP_PROXY/HTTPS_PROXY are used when empty")
+	fs.StringVar(&cfg.CaFile, "ca-file", "", "path of PEM bundle of CAs trusted in addition to system ones, e.g. of an internal API mirror")
+	fs.BoolVar(&cfg.TlsSkipVerify, "insecure-skip-verify", false, "do not verify API TLS certificate, for testing only")
+	fs.StringVar(&cfg.ResponseFormat, "format", ResponseFormatJson, "format of API responses: json or xml")
+	fs.BoolVar(&cfg.Strict, "strict", false, "validate JSON responses against the NBP response schema, failing fetches with renamed fields or changed types")
+	fs.StringVar(&cfg.InputFile, "input-file", "", "path of a saved API response in -format read by every worker instead of querying the API, for offline use")
+	fs.StringVar(&cfg.Table, "table", base.TableA, "NBP table to fetch rates from: a, b or c")
+	fs.StringVar(&cfg.PriceField, "price-field", base.PriceBid, "price checked against rate bounds for table c: bid or ask")
+	fs.StringVar(&cfg.Currency, "currency", DefaultCurrency, "currency code to fetch rates for, must be published in selected table")
+	fs.StringVar(&cfg.Currencies, "currencies", "", "comma separated currency codes fetched by every pool, e.g. eur,usd,gbp, replaces -currency")
+	fs.IntVar(&cfg.Count, "count", DefaultCount, "number of most recent rate records requested from NBP")
+	fs.StringVar(&cfg.From, "from", "", "start date (YYYY-MM-DD) of a date range query, requires -to, replaces -count")
+	fs.StringVar(&cfg.To, "to", "", "end date (YYYY-MM-DD) of a date range query, requires -from")
+	fs.BoolVar(&cfg.Today, "today", false, "query only the rate published today, replaces -count, 404 of a day without publication is logged as no data instead of a failure")
+	fs.IntVar(&cfg.MaxRetries, "max-retries", DefaultMaxRetries, "number of retries of a failed API request")
+	fs.DurationVar(&cfg.MaxRetryAfter, "max-retry-after", DefaultMaxRetryAfter, "longest Retry-After of a 429 Too Many Requests response waited for before retrying")
+	fs.DurationVar(&cfg.RequestTimeout, "request-timeout", DefaultRequestTimeout, "maximum duration of a single API request")
+	fs.Int64Var(&cfg.MaxBodyBytes, "max-body-bytes", DefaultMaxBodyBytes, "maximum size of an API response body in bytes, applied before and after decompression")
+	fs.BoolVar(&cfg.DryRun, "dry-run", false, "log the API request which would be sent and exit without sending it")
+	fs.Float64Var(&cfg.RateLimit, "rate-limit", 0, "maximum number of API requests per second shared by all workers, unlimited when 0")
+	fs.IntVar(&cfg.Burst, "burst", 1, "number of API requests allowed to exceed -rate-limit at once")
+	fs.BoolVar(&cfg.DumpResponse, "dump-response", false, "log indented response body of the first worker of each pool, requires -log-level debug")
+	fs.BoolVar(&cfg.Verbose, "verbose", false, "log request and response headers of every API request, requires -log-level debug")
+	fs.BoolVar(&cfg.Trace, "trace", false, "log DNS lookup, connect, TLS handshake and time to first byte of every API request, requires -log-level debug")
+}
+
+// scopeFlags decide which rates are out of scope
+func scopeFlags(fs *flag.FlagSet, cfg *Config) {
+	fs.Float64Var(&cfg.Bounds.Min, "rate-min", DefaultRateMin, "lower bound of the accepted mid rate")
+	fs.Float64Var(&cfg.Bounds.Max, "rate-max", DefaultRateMax, "upper bound of the accepted mid rate")
+	fs.StringVar(&cfg.Bands, "bands", "", "accepted mid rate bounds per currency, e.g. eur=4.5:4.7,usd=3.9:4.2, other currencies use -rate-min and -rate-max")
+	fs.StringVar(&cfg.Mode, "mode", ModeBand, "how out-of-scope rates are found: band checks -rate-min and -rate-max or -bands, baseline checks deviation from a moving average, rule evaluates -rule")
+	fs.IntVar(&cfg.BaselineWindow, "baseline-window", DefaultBaselineWindow, "number of surrounding rates averaged into the baseline of a rate, half before and half after it, with -mode baseline")
+	fs.Float64Var(&cfg.DeviationPct, "deviation-pct", DefaultDeviationPct, "deviation from the baseline in percent above which a rate is out of scope, with -mode baseline")
+	fs.StringVar(&cfg.Rule, "rule", "", "expression flagging rates with -mode rule, e.g. \"mid < 4.5 || mid > 4.7 || abs(change_pct) > 2\", of mid, change_pct since the previous rate, abs(), comparisons and ! && ||")
+}
+
+// logFlags shape log output of every command
+func logFlags(fs *flag.FlagSet, cfg *Config) {
+	fs.StringVar(&cfg.LogFormat, "log-format", string(logger.FormatText), "log output format: text or json")
+	fs.StringVar(&cfg.LogLevel, "log-level", logger.LevelInfo.String(), "minimal level of logged messages: debug, info, warn or error")
+	fs.StringVar(&cfg.Color, "color", string(logger.ColorAuto), "color out-of-scope dates and status codes in terminal output: auto, always or never, auto respects NO_COLOR")
+	fs.StringVar(&cfg.LogFile, "log-file", "", "path of size-rotated log file, log.txt in working directory is used when empty")
+	fs.StringVar(&cfg.DateFormat, "date-format", logger.DefaultDateLayout, "Go time layout of dates in log output, e.g. 02.01.2006")
+	fs.StringVar(&cfg.Timezone, "timezone", "UTC", "IANA time zone of timestamps in output and of NBP effective dates in staleness checks, e.g. Europe/Warsaw")
+}
+
+// poolFlags configure requests pools of watch and once, and what is done with their rates
+func poolFlags(fs *flag.FlagSet, cfg *Config) {
+	fs.IntVar(&cfg.Workers, "workers", DefaultWorkers, "number of concurrent fetches per requests pool")
+	fs.IntVar(&cfg.MaxConcurrency, "max-concurrency", 0, "maximum number of workers of a pool running requests at the same time, unlimited when 0")
+	fs.IntVar(&cfg.TripThreshold, "breaker-threshold", DefaultTripThreshold, "consecutive failed fetches after which requests are skipped for -breaker-cooldown, circuit breaker is disabled when 0")
+	fs.DurationVar(&cfg.TripCooldown, "breaker-cooldown", DefaultTripCooldown, "time circuit breaker stays open before a single probe request is let through")
+	fs.DurationVar(&cfg.LatencySla, "latency-sla", 0, "warn about responses slower than this, which indicate degraded NBP service, disabled when 0")
+	fs.StringVar(&cfg.BandsFile, "bands-file", "", "YAML or JSON file of bounds per currency, e.g. eur: {min: 4.5, max: 4.7}, reloaded on change, overrides -bands")
+	fs.Float64Var(&cfg.VolatilityPct, "volatility-pct", DefaultVolatilityPct, "day-over-day change of mid in percent above which a day is reported as volatile")
+	fs.BoolVar(&cfg.Quiet, "quiet", false, "omit requests pool banners, also at debug log level")
+	fs.BoolVar(&cfg.OrderedOutput, "ordered-output", false, "log results of workers ordered by worker index once the pool is done instead of as they finish")
+	fs.StringVar(&cfg.OutputCsv, "output-csv", "", "path of CSV file the fetched rates are appended to")
+	fs.StringVar(&cfg.ReportFile, "report-file", "", "path of report file of rates of the last pool, rewritten by every pool, disabled when empty")
+	fs.StringVar(&cfg.ReportFormat, "report-format", report.FormatMarkdown, "format of -report-file: markdown")
+	fs.BoolVar(&cfg.ReportAppend, "report-append", false, "append report of every pool to -report-file under its time instead of rewriting the file")
+	fs.StringVar(&cfg.DbPath, "db", "", "path of SQLite database the fetched rates are upserted into")
+	fs.StringVar(&cfg.MetricsAddr, "metrics-addr", "", "address of Prometheus /metrics endpoint, e.g. :9090, disabled when empty")
+	fs.StringVar(&cfg.OtelEndpoint, "otel-endpoint", "", "URL of OpenTelemetry collector spans and metrics are exported to over OTLP/HTTP, e.g. http://localhost:4318, disabled when empty")
+	fs.StringVar(&cfg.Notifier, "notifier", "", "where out-of-scope rates are notified: webhook, slack or email, webhook when empty and -webhook-url is set, disabled otherwise")
+	fs.StringVar(&cfg.WebhookUrl, "webhook-url", "", "URL out-of-scope rates are posted to as JSON, or Slack incoming webhook URL with -notifier slack")
+	fs.StringVar(&cfg.SmtpAddr, "smtp-addr", "", "host:port of SMTP server of -notifier email")
+	fs.StringVar(&cfg.SmtpFrom, "smtp-from", "", "sender address of -notifier email")
+	fs.StringVar(&cfg.SmtpTo, "smtp-to", "", "comma separated recipient addresses of -notifier email")
+	fs.StringVar(&cfg.SmtpUsername, "smtp-username", "", "username of SMTP PLAIN authentication, no authentication when empty")
+	fs.StringVar(&cfg.SmtpPassword, "smtp-password", "", "password of SMTP PLAIN authentication, better passed as NBP_SMTP_PASSWORD than on command line")
+	fs.DurationVar(&cfg.AlertCooldown, "alert-cooldown", 0, "time before an out-of-scope date of a currency is notified again, 0 notifies it once per run")
+	fs.DurationVar(&cfg.Interval, "interval", DefaultInterval, "interval between starts of consecutive requests pools")
+	fs.DurationVar(&cfg.MaxStaleness, "max-staleness", DefaultMaxStaleness, "warn when the newest fetched rate is older than this, disabled when 0")
+	fs.DurationVar(&cfg.AnalysisWindow, "analysis-window", 0, "only rates effective within this duration of the newest rate are checked and summarized, e.g. 720h, exports still get all rates, disabled when 0")
+	fs.BoolVar(&cfg.Dedupe, "dedupe", false, "perform a single API request per pool and share its result with all workers")
+	fs.BoolVar(&cfg.Diff, "diff", false, "log only rates which are new or which mid changed since the previous pool instead of every worker's request info")
+	fs.DurationVar(&cfg.CacheTtl, "cache-ttl", 0, "how long fetched rates are reused instead of requesting API again, 0 matches -interval, negative disables cache")
+}
+
+// watchFlags only matter to pools repeated every -interval
+func watchFlags(fs *flag.FlagSet, cfg *Config) {
+	fs.StringVar(&cfg.HttpAddr, "http-addr", "", "address of JSON rates API, e.g. :8080, disabled when empty")
+	fs.IntVar(&cfg.HistorySize, "history-size", api.DefaultHistorySize, "number of last pools summarized at /history of the rates API")
+	fs.DurationVar(&cfg.MaxRuntime, "max-runtime", 0, "stop after this long, cancelling in-flight requests, and exit with code 0, runs forever when 0")
+	fs.DurationVar(&cfg.MaxInterval, "max-interval", 0, "interval is doubled up to this while newest rate date does not change, disabled when 0")
+}
+
+// onceFlags only matter to a single pool run as a check
+func onceFlags(fs *flag.FlagSet, cfg *Config) {
+	fs.StringVar(&cfg.PushgatewayUrl, "pushgateway-url", "", "URL of Prometheus Pushgateway metrics are pushed to after -once pool, disabled when empty")
+	fs.StringVar(&cfg.PushgatewayJob, "pushgateway-job", metrics.DefaultPushJob, "job label of metrics pushed to Pushgateway")
+	fs.BoolVar(&cfg.FailOutOfScope, "fail-on-out-of-scope", false, "with -once, exit with code 1 when any rate is out of bounds")
+}
+
+// legacyFlags select a command without its name, so invocations of before commands keep working
+func legacyFlags(fs *flag.FlagSet, cfg *Config) {
+	fs.BoolVar(&cfg.Once, "once", false, "run a single requests pool and exit, exit code is 2 if any worker failed")
+	fs.BoolVar(&cfg.Healthcheck, "healthcheck", false, "fetch every currency once, log OK or FAIL and exit with code 0 or 2, e.g. as Docker HEALTHCHECK")
+}
+
+// applyEnv sets flags from NBP_* environment variables, skipping the ones explicitly given on command line
+func applyEnv(fs *flag.FlagSet, explicit map[string]bool) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || explicit[f.Name] || f.Name == "config" {
+			return
+		}
+
+		name := envName(f.Name)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+
+		setErr := fs.Set(f.Name, value)
+		if setErr != nil {
+			err = fmt.Errorf("invalid value %q of environment variable %s: %s", value, name, describeSetError(f, value, setErr))
+		}
+	})
+
+	return err
+}
+
+// describeSetError replaces generic "parse error" returned by flag.Set with the underlying parser error
+func describeSetError(f *flag.Flag, value string, err error) error {
+	getter, ok := f.Value.(flag.Getter)
+	if !ok {
+		return err
+	}
+
+	var parseErr error
+	switch getter.Get().(type) {
+	case time.Duration:
+		_, parseErr = time.ParseDuration(value)
+	case int:
+		_, parseErr = strconv.Atoi(value)
+	case float64:
+		_, parseErr = strconv.ParseFloat(value, 64)
+	case bool:
+		_, parseErr = strconv.ParseBool(value)
+	}
+
+	if parseErr != nil {
+		return parseErr
+	}
+	return err
+}
+
+func envName(flagName string) string {
+	return "NBP_" + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applyConfigFile sets flags from the config file, skipping the ones explicitly given on command line,
+// options of flags in all but not in fs are of other commands and are skipped as well
+func applyConfigFile(fs *flag.FlagSet, all *flag.FlagSet, explicit map[string]bool, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %s", err)
+	}
+
+	values := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(content, &values)
+	default:
+		err = yaml.Unmarshal(content, &values)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %s", path, err)
+	}
+
+	for name, value := range values {
+		if name == "config" || all.Lookup(name) == nil {
+			return fmt.Errorf("unknown option %q in config file %s", name, path)
+		}
+
+		// a single file serves all commands, options of other ones are left for them
+		if fs.Lookup(name) == nil || explicit[name] {
+			continue
+		}
+
+		err = fs.Set(name, fmt.Sprint(value))
+		if err != nil {
+			return fmt.Errorf("invalid value of %q in config file %s: %s", name, path, describeSetError(fs.Lookup(name), fmt.Sprint(value), err))
+		}
+	}
+
+	return nil
+}
+
+func (cfg Config) validate() error {
+	if cfg.Bounds.Min > cfg.Bounds.Max {
+		return fmt.Errorf("-rate-min (%.4f) must not be greater than -rate-max (%.4f)", cfg.Bounds.Min, cfg.Bounds.Max)
+	}
+
+	if cfg.Mode != ModeBand && cfg.Mode != ModeBaseline && cfg.Mode != ModeRule {
+		return fmt.Errorf("unknown -mode %q, expected %s, %s or %s", cfg.Mode, ModeBand, ModeBaseline, ModeRule)
+	}
+
+	if cfg.Mode == ModeRule {
+		if cfg.Rule == "" {
+			return fmt.Errorf("-mode %s requires -rule", ModeRule)
+		}
+		_, err := base.ParseRule(cfg.Rule)
+		if err != nil {
+			return fmt.Errorf("invalid -rule: %s", err)
+		}
+	} else if cfg.Rule != "" {
+		return fmt.Errorf("-rule is only evaluated with -mode %s", ModeRule)
+	}
+
+	if cfg.BaselineWindow < 2 {
+		return fmt.Errorf("-baseline-window %d must be at least 2", cfg.BaselineWindow)
+	}
+
+	if cfg.DeviationPct <= 0 {
+		return fmt.Errorf("-deviation-pct %g must be positive", cfg.DeviationPct)
+	}
+
+	if cfg.VolatilityPct < 0 {
+		return fmt.Errorf("-volatility-pct %g must not be negative", cfg.VolatilityPct)
+	}
+
+	apiBaseUrl, err := url.Parse(cfg.ApiBaseUrl)
+	if err != nil || apiBaseUrl.Scheme == "" || apiBaseUrl.Host == "" {
+		return fmt.Errorf("-api-base-url %q is not a valid absolute URL", cfg.ApiBaseUrl)
+	}
+
+	if cfg.ResponseFormat != ResponseFormatJson && cfg.ResponseFormat != ResponseFormatXml {
+		return fmt.Errorf("unknown -format %q, expected json or xml", cfg.ResponseFormat)
+	}
+
+	if cfg.Strict && cfg.ResponseFormat != ResponseFormatJson {
+		return fmt.Errorf("-strict validates JSON responses, it requires -format %s", ResponseFormatJson)
+	}
+
+	if cfg.InputFile != "" {
+		info, err := os.Stat(cfg.InputFile)
+		if err != nil {
+			return fmt.Errorf("-input-file: %s", err)
+		}
+		if info.IsDir() {
+			return fmt.Errorf("-input-file %s is a directory", cfg.InputFile)
+		}
+	}
+
+	_, err = parseProxyUrl(cfg.Proxy)
+	if err != nil {
+		return err
+	}
+
+	if cfg.WebhookUrl != "" {
+		webhookUrl, err := url.Parse(cfg.WebhookUrl)
+		if err != nil || webhookUrl.Scheme == "" || webhookUrl.Host == "" {
+			return fmt.Errorf("-webhook-url %q is not a valid absolute URL", cfg.WebhookUrl)
+		}
+	}
+
+	switch cfg.Notifier {
+	case "":
+	case notify.KindWebhook, notify.KindSlack:
+		if cfg.WebhookUrl == "" {
+			return fmt.Errorf("-notifier %s requires -webhook-url", cfg.Notifier)
+		}
+	case notify.KindEmail:
+		if cfg.SmtpAddr == "" || cfg.SmtpFrom == "" || len(parseAddresses(cfg.SmtpTo)) == 0 {
+			return fmt.Errorf("-notifier %s requires -smtp-addr, -smtp-from and -smtp-to", cfg.Notifier)
+		}
+		_, _, err := net.SplitHostPort(cfg.SmtpAddr)
+		if err != nil {
+			return fmt.Errorf("-smtp-addr %q is not host:port", cfg.SmtpAddr)
+		}
+	default:
+		return fmt.Errorf("unknown -notifier %q, expected %s, %s or %s", cfg.Notifier, notify.KindWebhook, notify.KindSlack, notify.KindEmail)
+	}
+
+	if cfg.HistorySize < 1 {
+		return fmt.Errorf("-history-size %d must be at least 1", cfg.HistorySize)
+	}
+
+	if cfg.PushgatewayUrl != "" && cfg.PushgatewayJob == "" {
+		return errors.New("-pushgateway-job must not be empty")
+	}
+
+	if cfg.OtelEndpoint != "" {
+		otelEndpoint, err := url.Parse(cfg.OtelEndpoint)
+		if err != nil || (otelEndpoint.Scheme != "http" && otelEndpoint.Scheme != "https") || otelEndpoint.Host == "" {
+			return fmt.Errorf("-otel-endpoint %q is not a valid http or https URL", cfg.OtelEndpoint)
+		}
+	}
+
+	table := strings.ToLower(cfg.Table)
+	if table != base.TableA && table != base.TableB && table != base.TableC {
+		return fmt.Errorf("unknown -table %q, expected a, b or c", cfg.Table)
+	}
+
+	if cfg.PriceField != base.PriceBid && cfg.PriceField != base.PriceAsk {
+		return fmt.Errorf("unknown -price-field %q, expected bid or ask", cfg.PriceField)
+	}
+
+	if !base.IsTableCurrency(table, cfg.Currency) {
+		return fmt.Errorf("unknown -currency code %q: not published in NBP table %s", cfg.Currency, strings.ToUpper(table))
+	}
+
+	for _, currency := range parseCurrencies(cfg.Currencies) {
+		if !base.IsTableCurrency(table, currency) {
+			return fmt.Errorf("unknown -currencies code %q: not published in NBP table %s", currency, strings.ToUpper(table))
+		}
+	}
+
+	if _, err := parseBands(cfg.Bands); err != nil {
+		return err
+	}
+
+	if cfg.Today && (cfg.From != "" || cfg.To != "") {
+		return errors.New("-today cannot be combined with -from and -to")
+	}
+
+	if cfg.Count < 1 || cfg.Count > MaxCount {
+		return fmt.Errorf("-count %d must be between 1 and %d", cfg.Count, MaxCount)
+	}
+
+	if cfg.Workers < 1 {
+		return fmt.Errorf("-workers %d: at least one worker is required", cfg.Workers)
+	}
+
+	if cfg.MaxConcurrency < 0 {
+		return fmt.Errorf("-max-concurrency %d must not be negative", cfg.MaxConcurrency)
+	}
+
+	if cfg.MaxRetries < 0 || cfg.MaxRetries > MaxRetries {
+		return fmt.Errorf("-max-retries %d must be between 0 and %d", cfg.MaxRetries, MaxRetries)
+	}
+
+	if cfg.MaxRetryAfter < 0 {
+		return fmt.Errorf("-max-retry-after %s must not be negative", cfg.MaxRetryAfter)
+	}
+
+	if cfg.TripThreshold < 0 {
+		return fmt.Errorf("-breaker-threshold %d must not be negative", cfg.TripThreshold)
+	}
+
+	if cfg.TripCooldown <= 0 {
+		return fmt.Errorf("-breaker-cooldown %s must be positive", cfg.TripCooldown)
+	}
+
+	if cfg.RequestTimeout <= 0 {
+		return fmt.Errorf("-request-timeout %s must be positive", cfg.RequestTimeout)
+	}
+
+	if cfg.MaxBodyBytes <= 0 {
+		return fmt.Errorf("-max-body-bytes %d must be positive", cfg.MaxBodyBytes)
+	}
+
+	if cfg.Interval <= 0 {
+		return fmt.Errorf("-interval %s must be positive", cfg.Interval)
+	}
+
+	if cfg.MaxInterval != 0 && cfg.MaxInterval < cfg.Interval {
+		return fmt.Errorf("-max-interval %s must not be shorter than -interval %s", cfg.MaxInterval, cfg.Interval)
+	}
+
+	if cfg.MaxRuntime < 0 {
+		return fmt.Errorf("-max-runtime %s must not be negative", cfg.MaxRuntime)
+	}
+
+	if cfg.ReportFormat != report.FormatMarkdown {
+		return fmt.Errorf("unknown -report-format %q, expected %s", cfg.ReportFormat, report.FormatMarkdown)
+	}
+
+	if cfg.FailOutOfScope && !cfg.Once {
+		return errors.New("-fail-on-out-of-scope requires -once")
+	}
+
+	if cfg.AlertCooldown < 0 {
+		return fmt.Errorf("-alert-cooldown %s must not be negative", cfg.AlertCooldown)
+	}
+
+	if cfg.LatencySla < 0 {
+		return fmt.Errorf("-latency-sla %s must not be negative", cfg.LatencySla)
+	}
+
+	if cfg.MaxStaleness < 0 {
+		return fmt.Errorf("-max-staleness %s must not be negative", cfg.MaxStaleness)
+	}
+
+	if cfg.AnalysisWindow < 0 {
+		return fmt.Errorf("-analysis-window %s must not be negative", cfg.AnalysisWindow)
+	}
+
+	if cfg.DateFormat == "" {
+		return errors.New("-date-format must not be empty")
+	}
+
+	if cfg.RateLimit < 0 {
+		return fmt.Errorf("-rate-limit %g must not be negative", cfg.RateLimit)
+	}
+
+	if cfg.Burst < 1 {
+		return fmt.Errorf("-burst %d must be at least 1", cfg.Burst)
+	}
+
+	return nil
+}
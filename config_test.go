@@ -0,0 +1,191 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"spyrosoft-recruitment-task/base"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateRule(t *testing.T) {
+	tests := []struct {
+		args    []string
+		wantErr bool
+	}{
+		{[]string{"-mode", "rule", "-rule", "mid < 4.5 || abs(change_pct) > 2"}, false},
+		{[]string{"-mode", "rule"}, true},
+		{[]string{"-mode", "rule", "-rule", "mid >"}, true},
+		{[]string{"-rule", "mid > 4.7"}, true},
+	}
+
+	for _, tt := range tests {
+		err := loadTestConfig(t, tt.args...).validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validate() of %q = %v, want error: %t", tt.args, err, tt.wantErr)
+		}
+	}
+}
+
+// writeConfigFile writes content to config file of name in a temporary directory, returning its path
+func writeConfigFile(t *testing.T, name string, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(path, []byte(content), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// loadTestConfig returns Config of args on top of built-in defaults
+func loadTestConfig(t *testing.T, args ...string) Config {
+	t.Helper()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	cfg, err := loadConfig(fs, args, allFlagGroups)
+	if err != nil {
+		t.Fatalf("loadConfig(%q) failed: %s", args, err)
+	}
+	return cfg
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	yamlPath := writeConfigFile(t, "config.yaml", "currency: usd\ninterval: 10s\nrate-min: 3.9\n")
+	jsonPath := writeConfigFile(t, "config.json", `{"currency": "chf", "interval": "15s", "workers": 4}`)
+
+	cfg := loadTestConfig(t, "-config", yamlPath)
+	if cfg.Currency != "usd" || cfg.Interval != 10*time.Second || cfg.Bounds.Min != 3.9 {
+		t.Errorf("config of YAML file = %+v, want usd every 10s above 3.9", cfg)
+	}
+
+	cfg = loadTestConfig(t, "-config", jsonPath)
+	if cfg.Currency != "chf" || cfg.Interval != 15*time.Second || cfg.Workers != 4 {
+		t.Errorf("config of JSON file = %+v, want chf every 15s by 4 workers", cfg)
+	}
+
+	// flags win over the file, options of the file not given as flags still apply
+	cfg = loadTestConfig(t, "-config", yamlPath, "-currency", "gbp")
+	if cfg.Currency != "gbp" || cfg.Interval != 10*time.Second {
+		t.Errorf("config of YAML file and -currency = %+v, want gbp every 10s", cfg)
+	}
+}
+
+func TestLoadConfigFileRejectsMalformedFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"config.yaml", "currency: [usd\n", "failed to parse config file"},
+		{"config.json", `{"currency": "usd",}`, "failed to parse config file"},
+		{"config.yaml", "interval: soon\n", `invalid value of "interval"`},
+		{"config.yaml", "no-such-option: 1\n", `unknown option "no-such-option"`},
+	}
+
+	for _, tt := range tests {
+		path := writeConfigFile(t, tt.name, tt.content)
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+
+		_, err := loadConfig(fs, []string{"-config", path}, allFlagGroups)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("loadConfig() of %s %q = %v, want error of %q", tt.name, tt.content, err, tt.wantErr)
+		}
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	_, err := loadConfig(fs, []string{"-config", filepath.Join(t.TempDir(), "missing.yaml")}, allFlagGroups)
+	if err == nil || !strings.Contains(err.Error(), "failed to read config file") {
+		t.Errorf("loadConfig() of missing file = %v, want read error", err)
+	}
+}
+
+func TestLoadConfigDefaultsAndOverrides(t *testing.T) {
+	cfg := loadTestConfig(t)
+	if cfg.ApiBaseUrl != DefaultApiBaseUrl || cfg.Interval != DefaultInterval || cfg.Workers != DefaultWorkers ||
+		cfg.Count != DefaultCount || cfg.Currency != DefaultCurrency {
+		t.Errorf("config without flags = %+v, want defaults", cfg)
+	}
+	// defaults of the constants the config replaced
+	if DefaultApiBaseUrl != "http://api.nbp.pl/api/exchangerates/rates/" || DefaultInterval != 5*time.Second || DefaultWorkers != 10 {
+		t.Errorf("defaults changed: %s every %s by %d workers", DefaultApiBaseUrl, DefaultInterval, DefaultWorkers)
+	}
+
+	cfg = loadTestConfig(t, "-api-base-url", "http://localhost:8080/rates/", "-interval", "1m", "-workers", "3", "-count", "10")
+	if cfg.ApiBaseUrl != "http://localhost:8080/rates/" || cfg.Interval != time.Minute || cfg.Workers != 3 || cfg.Count != 10 {
+		t.Errorf("config of flags = %+v, want them all applied", cfg)
+	}
+}
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	t.Setenv("NBP_CURRENCY", "usd")
+	t.Setenv("NBP_INTERVAL", "30s")
+	t.Setenv("NBP_WORKERS", "4")
+	t.Setenv("NBP_RATE_MIN", "3.8")
+	t.Setenv("NBP_RATE_MAX", "4.1")
+
+	cfg := loadTestConfig(t)
+	if cfg.Currency != "usd" || cfg.Interval != 30*time.Second || cfg.Workers != 4 || cfg.Bounds.Min != 3.8 || cfg.Bounds.Max != 4.1 {
+		t.Errorf("config of environment = %+v, want usd every 30s by 4 workers within 3.8 - 4.1", cfg)
+	}
+
+	// flags win over the environment
+	cfg = loadTestConfig(t, "-currency", "gbp", "-workers", "2")
+	if cfg.Currency != "gbp" || cfg.Workers != 2 || cfg.Interval != 30*time.Second {
+		t.Errorf("config of environment and flags = %+v, want gbp by 2 workers every 30s", cfg)
+	}
+}
+
+func TestLoadConfigFromEnvNamesInvalidVariable(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr string
+	}{
+		{"NBP_INTERVAL", "soon", `invalid value "soon" of environment variable NBP_INTERVAL: time: invalid duration "soon"`},
+		{"NBP_WORKERS", "ten", `invalid value "ten" of environment variable NBP_WORKERS: strconv.Atoi: parsing "ten": invalid syntax`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.name, tt.value)
+
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			_, err := loadConfig(fs, nil, allFlagGroups)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("loadConfig() = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigOfTable(t *testing.T) {
+	cfg := loadTestConfig(t, "-table", "c", "-currency", "usd", "-price-field", "ask")
+	if cfg.Table != base.TableC || cfg.PriceField != base.PriceAsk {
+		t.Errorf("config of table flags = %+v, want ask price of table c", cfg)
+	}
+
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"-table", "d"}, `unknown -table "d", expected a, b or c`},
+		{[]string{"-table", "c", "-price-field", "mid"}, `unknown -price-field "mid", expected bid or ask`},
+		{[]string{"-table", "c", "-currency", "thb"}, `unknown -currency code "thb": not published in NBP table C`},
+	}
+
+	for _, tt := range tests {
+		err := loadTestConfig(t, tt.args...).validate()
+		if err == nil || err.Error() != tt.wantErr {
+			t.Errorf("validate() of %q = %v, want %s", tt.args, err, tt.wantErr)
+		}
+	}
+}
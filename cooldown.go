@@ -0,0 +1,51 @@
+package main
+
+import (
+	"spyrosoft-recruitment-task/base"
+	"strings"
+	"sync"
+	"time"
+)
+
+// alertKey is an out-of-scope effective date of a currency
+type alertKey struct {
+	currency string
+	date     time.Time
+}
+
+// alertCooldown keeps an out-of-scope condition from being reported by every pool,
+// state is kept in memory, so it starts over with every run
+type alertCooldown struct {
+	// 0 reports every condition once per run
+	window time.Duration
+	clock  Clock
+
+	mu       sync.Mutex
+	reported map[alertKey]time.Time
+}
+
+func newAlertCooldown(window time.Duration, clock Clock) *alertCooldown {
+	return &alertCooldown{
+		window:   window,
+		clock:    clock,
+		reported: map[alertKey]time.Time{},
+	}
+}
+
+// filter returns rates of currency not reported within the cooldown window and marks them as reported now
+func (c *alertCooldown) filter(currency string, rates []base.OutOfScopeRate) []base.OutOfScopeRate {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	var fresh []base.OutOfScopeRate
+	for _, rate := range rates {
+		key := alertKey{currency: strings.ToLower(currency), date: rate.EffectiveDate}
+		if reportedAt, ok := c.reported[key]; ok && (c.window == 0 || now.Sub(reportedAt) < c.window) {
+			continue
+		}
+		c.reported[key] = now
+		fresh = append(fresh, rate)
+	}
+	return fresh
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"spyrosoft-recruitment-task/base"
+	"testing"
+	"time"
+)
+
+// outOfScopeOn returns out-of-scope rates effective on given days of January 2024
+func outOfScopeOn(days ...int) []base.OutOfScopeRate {
+	rates := make([]base.OutOfScopeRate, 0, len(days))
+	for _, day := range days {
+		rates = append(rates, base.OutOfScopeRate{EffectiveDate: time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC), Mid: 4.8})
+	}
+	return rates
+}
+
+func TestAlertCooldownSuppressesConditionWithinWindow(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC))
+	cooldown := newAlertCooldown(time.Hour, clock)
+
+	steps := []struct {
+		advance  time.Duration
+		currency string
+		days     []int
+		want     int
+	}{
+		{0, "eur", []int{2, 3}, 2},
+		// both are reported again by the next pool, one more date is new
+		{30 * time.Minute, "EUR", []int{2, 3, 4}, 1},
+		// the same dates of another currency are another condition
+		{0, "usd", []int{2}, 1},
+		{29 * time.Minute, "eur", []int{2, 3, 4}, 0},
+		// window of the first two dates has passed, the third one was reported 30 minutes ago
+		{time.Minute, "eur", []int{2, 3, 4}, 2},
+		{31 * time.Minute, "eur", []int{4}, 1},
+	}
+
+	for i, step := range steps {
+		clock.Advance(step.advance)
+		if got := cooldown.filter(step.currency, outOfScopeOn(step.days...)); len(got) != step.want {
+			t.Errorf("step %d: filter() of %s on %v = %d rates, want %d", i, step.currency, step.days, len(got), step.want)
+		}
+	}
+}
+
+func TestAlertCooldownWithoutWindowReportsOncePerRun(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC))
+	cooldown := newAlertCooldown(0, clock)
+
+	if got := cooldown.filter("eur", outOfScopeOn(2)); len(got) != 1 {
+		t.Fatalf("filter() = %d rates, want the first report", len(got))
+	}
+	clock.Advance(365 * 24 * time.Hour)
+	if got := cooldown.filter("eur", outOfScopeOn(2)); len(got) != 0 {
+		t.Errorf("filter() = %d rates a year later, want none", len(got))
+	}
+}
@@ -0,0 +1,104 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"spyrosoft-recruitment-task/base"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// csvHeader starts with currency, as NBP numbers a table once for all of its currencies
+var csvHeader = []string{"currency", "no", "effective_date", "mid", "fetched_at"}
+
+// CsvWriter appends fetched rates to a CSV file, it is safe for use by concurrent workers
+type CsvWriter struct {
+	mu     sync.Mutex
+	file   *os.File
+	writer *csv.Writer
+}
+
+// NewCsvWriter opens path for appending, a file with other columns, e.g. one written before currency was recorded, is rejected
+func NewCsvWriter(path string) (*CsvWriter, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CSV file: %s", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to stat CSV file: %s", err)
+	}
+
+	cw := &CsvWriter{file: file, writer: csv.NewWriter(file)}
+
+	// header is written only once, appending to an existing file continues its rows
+	if info.Size() == 0 {
+		err = cw.writer.Write(csvHeader)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to write CSV header: %s", err)
+		}
+		return cw, nil
+	}
+
+	header, err := csv.NewReader(io.NewSectionReader(file, 0, info.Size())).Read()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read CSV header: %s", err)
+	}
+	if strings.Join(header, ",") != strings.Join(csvHeader, ",") {
+		file.Close()
+		return nil, fmt.Errorf("CSV file %s has columns %s, expected %s, use a new file", path, strings.Join(header, ","), strings.Join(csvHeader, ","))
+	}
+
+	return cw, nil
+}
+
+// WriteRates appends rates of currency, fetched at fetchedAt
+func (cw *CsvWriter) WriteRates(currency string, rates []*base.ExchangeRate, fetchedAt time.Time) error {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	for _, rate := range rates {
+		effectiveDate := ""
+		if rate.EffectiveDate != nil {
+			effectiveDate = rate.EffectiveDate.Format("2006-01-02")
+		}
+
+		record := []string{
+			currency,
+			rate.No,
+			effectiveDate,
+			strconv.FormatFloat(rate.Mid, 'f', -1, 64),
+			fetchedAt.Format(time.RFC3339),
+		}
+
+		err := cw.writer.Write(record)
+		if err != nil {
+			return fmt.Errorf("failed to write CSV record: %s", err)
+		}
+	}
+
+	cw.writer.Flush()
+	return cw.writer.Error()
+}
+
+func (cw *CsvWriter) Close() error {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	cw.writer.Flush()
+	err := cw.writer.Error()
+	if err != nil {
+		cw.file.Close()
+		return fmt.Errorf("failed to flush CSV file: %s", err)
+	}
+
+	return cw.file.Close()
+}
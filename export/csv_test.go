@@ -0,0 +1,123 @@
+package export
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"spyrosoft-recruitment-task/base"
+	"spyrosoft-recruitment-task/marshal"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newRate(no string, date string, mid float64) *base.ExchangeRate {
+	effectiveDate, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		panic(err)
+	}
+	return &base.ExchangeRate{No: no, EffectiveDate: &marshal.CustomTime{Time: effectiveDate}, Mid: mid}
+}
+
+func readCsv(t *testing.T, path string) [][]string {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("written CSV is malformed: %s", err)
+	}
+	return records
+}
+
+func TestCsvWriterOfConcurrentWorkers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.csv")
+	writer, err := NewCsvWriter(path)
+	if err != nil {
+		t.Fatalf("NewCsvWriter() failed: %s", err)
+	}
+
+	fetchedAt := time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC)
+	rates := []*base.ExchangeRate{newRate("001/A/NBP/2024", "2024-01-02", 4.4), newRate("002/A/NBP/2024", "2024-01-03", 4.6)}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := writer.WriteRates("EUR", rates, fetchedAt)
+			if err != nil {
+				t.Errorf("WriteRates() failed: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	err = writer.Close()
+	if err != nil {
+		t.Fatalf("Close() failed: %s", err)
+	}
+
+	records := readCsv(t, path)
+	if got := strings.Join(records[0], ","); got != "currency,no,effective_date,mid,fetched_at" {
+		t.Errorf("header = %s, want currency,no,effective_date,mid,fetched_at", got)
+	}
+
+	rows := map[string]int{}
+	for _, record := range records[1:] {
+		rows[strings.Join(record, ",")]++
+	}
+	want := map[string]int{
+		"EUR,001/A/NBP/2024,2024-01-02,4.4,2024-01-03T12:00:00Z": 2,
+		"EUR,002/A/NBP/2024,2024-01-03,4.6,2024-01-03T12:00:00Z": 2,
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("rows = %v, want %v", rows, want)
+	}
+	for row, count := range want {
+		if rows[row] != count {
+			t.Errorf("row %s written %d times, want %d", row, rows[row], count)
+		}
+	}
+}
+
+func TestCsvWriterAppendsWithoutSecondHeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.csv")
+	fetchedAt := time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC)
+
+	for _, currency := range []string{"EUR", "USD"} {
+		writer, err := NewCsvWriter(path)
+		if err != nil {
+			t.Fatalf("NewCsvWriter() failed: %s", err)
+		}
+		err = writer.WriteRates(currency, []*base.ExchangeRate{newRate("001/A/NBP/2024", "2024-01-02", 4.4)}, fetchedAt)
+		if err != nil {
+			t.Fatalf("WriteRates() failed: %s", err)
+		}
+		writer.Close()
+	}
+
+	records := readCsv(t, path)
+	if len(records) != 3 || records[1][0] != "EUR" || records[2][0] != "USD" {
+		t.Errorf("records = %v, want header followed by EUR and USD rows", records)
+	}
+}
+
+func TestNewCsvWriterRejectsFileOfOtherColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.csv")
+	err := os.WriteFile(path, []byte("no,effective_date,mid,fetched_at\n001/A/NBP/2024,2024-01-02,4.4,2024-01-03T12:00:00Z\n"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewCsvWriter(path)
+	if err == nil {
+		t.Fatal("NewCsvWriter() of a file without currency column succeeded, want error")
+	}
+}
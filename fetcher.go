@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"spyrosoft-recruitment-task/base"
+	"spyrosoft-recruitment-task/logger"
+	"spyrosoft-recruitment-task/metrics"
+	"strings"
+
+	"golang.org/x/time/rate"
+)
+
+// Fetcher fetches the summary of count latest rates of currency, HTTPFetcher queries the NBP API,
+// other sources, e.g. a local file or a different provider, can be swapped in through PoolConfig.Fetcher
+type Fetcher interface {
+	Fetch(ctx context.Context, currency string, count int) (base.ExchangeRatesSummary, error)
+}
+
+// targetFetcher is implemented by fetchers able to serve a worker the details of its response,
+// workers fetch through it when available and fall back to Fetcher otherwise
+type targetFetcher interface {
+	fetchTarget(ctx context.Context, index int, t *target) (*fetchResult, error)
+}
+
+// HTTPFetcher fetches rates from the NBP API
+type HTTPFetcher struct {
+	Config
+
+	// single client shared by all workers, so connections are kept alive and reused between requests
+	Client *http.Client
+	// shared by all workers to keep request rate within NBP limits
+	Limiter *rate.Limiter
+	// times requests and waits between retries
+	Clock Clock
+
+	conditional conditionalStore
+}
+
+// Fetch performs the API request of currency, the date range of the config is queried instead of count when set
+func (f *HTTPFetcher) Fetch(ctx context.Context, currency string, count int) (base.ExchangeRatesSummary, error) {
+	cfg := f.Config
+	cfg.Currency = currency
+	cfg.Count = count
+
+	apiUrl, err := buildApiUrl(cfg)
+	if err != nil {
+		return base.ExchangeRatesSummary{}, err
+	}
+
+	request, err := prepareHttpRequest(ctx, apiUrl, newRequestOptions(cfg))
+	if err != nil {
+		return base.ExchangeRatesSummary{}, err
+	}
+
+	result, err := f.fetchTarget(ctx, 0, &target{Currency: strings.ToLower(currency), ApiUrl: apiUrl, request: request})
+	if err != nil {
+		return base.ExchangeRatesSummary{}, err
+	}
+	return result.summary, nil
+}
+
+// fetchTarget performs the API request of target currency and decodes its response
+func (f *HTTPFetcher) fetchTarget(ctx context.Context, index int, t *target) (*fetchResult, error) {
+	// trace is attached per fetch, as the request prepared at startup is shared by all workers
+	var trace *requestTrace
+	if f.Trace {
+		trace = &requestTrace{}
+		ctx = trace.withClientTrace(ctx)
+	}
+
+	// headers are copied, so conditional ones stay with this request only
+	req := t.request.Clone(ctx)
+	f.conditional.addHeaders(req, t.ApiUrl)
+
+	if f.Verbose {
+		logHeaders(ctx, index, "Request headers", req.Header)
+	}
+
+	startTime := f.Clock.Now()
+	resp, err := doWithRetry(ctx, f.Client, f.Limiter, f.Clock, req, f.MaxRetries, f.MaxRetryAfter)
+	if trace != nil {
+		trace.log(ctx, index)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to perform GET request: %w", err)
+	}
+
+	elapsed := f.Clock.Now().Sub(startTime)
+	metrics.ObserveRequestDuration(elapsed)
+
+	defer func() {
+		err := resp.Body.Close()
+		if err != nil {
+			logger.Warn("%s Failed to close response body: %s", workerTag(ctx, index), err)
+		}
+	}()
+
+	if f.Verbose {
+		logHeaders(ctx, index, "Response headers", resp.Header)
+	}
+
+	statusCode := resp.StatusCode
+	contentType := resp.Header.Get("Content-Type")
+
+	if statusCode == http.StatusNotModified {
+		previous, ok := f.conditional.lastResult(t.ApiUrl)
+		if !ok {
+			return nil, errors.New("304 Not Modified response to an unconditional request")
+		}
+		logger.Info("%s Not modified, reusing previous response", workerTag(ctx, index))
+
+		previous.elapsed = elapsed
+		previous.statusCode = statusCode
+		return &previous, nil
+	}
+
+	// NBP answers errors with a plain text body, there is nothing to decompress or unmarshal
+	if statusCode != http.StatusOK {
+		snippet := readBodySnippet(resp)
+
+		if statusCode == http.StatusNotFound && f.Today {
+			return nil, ErrNoDataToday
+		}
+
+		// NBP rejects too long date ranges with 400 "Przekroczony limit 367 dni / Limit of 367 days has been exceeded"
+		if statusCode == http.StatusBadRequest && f.From != "" && strings.Contains(strings.ToLower(snippet), "limit") {
+			return nil, fmt.Errorf("range too long (max %d days): %w", MaxRangeDays, &base.ErrBadStatus{Code: statusCode, Status: resp.Status, Err: errors.New(snippet)})
+		}
+
+		return nil, &base.ErrBadStatus{Code: statusCode, Status: resp.Status, Err: &base.APIError{StatusText: snippet}}
+	}
+
+	// decompress byte stream according to Content-Encoding and decode JSON straight from it
+	bodyReader, err := newBodyReader(resp, f.MaxBodyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body content: %w", err)
+	}
+	defer bodyReader.Close()
+
+	dump := f.DumpResponse && index == 0 && logger.Enabled(logger.LevelDebug)
+
+	var body io.Reader = bodyReader
+	var content []byte
+	if dump || f.Strict {
+		// dump and schema validation need the whole body, decoding continues from the buffered copy
+		content, err = io.ReadAll(bodyReader)
+		if errors.Is(err, ErrResponseTooLarge) {
+			return nil, fmt.Errorf("%w: body exceeds %d bytes", ErrResponseTooLarge, f.MaxBodyBytes)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read body content: %w", err)
+		}
+		if dump {
+			dumpResponse(ctx, index, content)
+		}
+		body = bytes.NewReader(content)
+	}
+
+	summary, err := decodeSummary(body, f.ResponseFormat, f.Table, f.PriceField)
+	if errors.Is(err, ErrResponseTooLarge) {
+		return nil, fmt.Errorf("%w: body exceeds %d bytes", ErrResponseTooLarge, f.MaxBodyBytes)
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		// compressed or plain stream ended before the JSON value was complete
+		return nil, fmt.Errorf("truncated response body: %w", err)
+	}
+	var typeErr *json.UnmarshalTypeError
+	if f.Strict && errors.As(err, &typeErr) {
+		// schema tells the path of every changed type, decoder stops at the first one
+		if schemaErr := base.ValidateSchema(content, f.Table); schemaErr != nil {
+			return nil, fmt.Errorf("strict validation failed: %w", schemaErr)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshall request content: %w", err)
+	}
+
+	// decoder rejects malformed body, so decoded one is always syntactically valid
+	isJsonValid := true
+
+	// checked after decoding, so NBP error bodies are still reported as such
+	if f.Strict {
+		err = base.ValidateSchema(content, f.Table)
+		if err != nil {
+			return nil, fmt.Errorf("strict validation failed: %w", err)
+		}
+	}
+
+	err = base.ValidateSummary(summary)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected response content: %w", err)
+	}
+
+	result := fetchResult{
+		summary:     summary,
+		elapsed:     elapsed,
+		statusCode:  statusCode,
+		contentType: contentType,
+		isJsonValid: isJsonValid,
+	}
+	f.conditional.remember(t.ApiUrl, resp.Header, result)
+
+	return &result, nil
+}
+
+// dumpResponse logs indented response body, raw body is logged when it is not valid JSON
+func dumpResponse(ctx context.Context, index int, content []byte) {
+	var indented bytes.Buffer
+	err := json.Indent(&indented, content, "", "  ")
+	if err != nil {
+		indented.Reset()
+		indented.Write(content)
+	}
+
+	logger.Debug("%s Response body:\n%s", workerTag(ctx, index), indented.String())
+}
+
+// logHeaders logs headers at debug level, sorted by key for stable output
+func logHeaders(ctx context.Context, index int, title string, header http.Header) {
+	logger.Debug("%s %s:%s", workerTag(ctx, index), title, formatHeaders(header))
+}
+
+// formatHeaders renders headers as indented lines sorted by key
+func formatHeaders(header http.Header) string {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var lines strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&lines, "\n  %s: %s", key, strings.Join(header[key], ", "))
+	}
+	return lines.String()
+}
+
+// decodeSummary decodes table A and B mid rates directly,
+// table C bid/ask rates are converted using priceField as the mid rate
+func decodeSummary(r io.Reader, format string, table string, priceField string) (base.ExchangeRatesSummary, error) {
+	if format == ResponseFormatXml {
+		return base.ParseSummaryXml(r, priceField)
+	}
+
+	if strings.ToLower(table) != base.TableC {
+		return base.ParseSummary(r)
+	}
+	return base.ParseSummaryC(r, priceField)
+}
+
+// FileFetcher reads rates from a saved API response instead of the network,
+// the file is read on every fetch whatever currency and count are asked for
+type FileFetcher struct {
+	Path string
+	// ResponseFormatJson or ResponseFormatXml
+	Format     string
+	Table      string
+	PriceField string
+}
+
+// Fetch reads and decodes the file
+func (f *FileFetcher) Fetch(ctx context.Context, currency string, count int) (base.ExchangeRatesSummary, error) {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return base.ExchangeRatesSummary{}, fmt.Errorf("failed to open input file: %w", err)
+	}
+	defer file.Close()
+
+	summary, err := decodeSummary(file, f.Format, f.Table, f.PriceField)
+	if err != nil {
+		return base.ExchangeRatesSummary{}, fmt.Errorf("failed to unmarshall input file %s: %w", f.Path, err)
+	}
+
+	err = base.ValidateSummary(summary)
+	if err != nil {
+		return base.ExchangeRatesSummary{}, fmt.Errorf("unexpected input file content: %w", err)
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,653 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"spyrosoft-recruitment-task/base"
+	"spyrosoft-recruitment-task/logger"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPFetcherAbortsRequestAfterTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// hangs far longer than the timeout
+		select {
+		case <-release:
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	captureLog(t, logger.LevelInfo)
+	cfg := newTestPoolConfig(1, server.URL)
+	f := httpFetcher(cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := f.fetchTarget(ctx, 0, cfg.Targets[0])
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("fetchTarget() error = %v, want deadline exceeded", err)
+	}
+	if elapsed > time.Second {
+		t.Errorf("fetchTarget() returned after %s, want request aborted after timeout of 50ms", elapsed)
+	}
+}
+
+func TestHTTPFetcherDoesNotParseBodyOfNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// claimed encoding is not applied, so any attempt to decompress or decode the body fails
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("404 NotFound - Not Found - Brak danych"))
+	}))
+	defer server.Close()
+	cfg := newTestPoolConfig(1, server.URL)
+	f := httpFetcher(cfg)
+
+	_, err := f.fetchTarget(context.Background(), 0, cfg.Targets[0])
+
+	var apiErr *base.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusText != "404 NotFound - Not Found - Brak danych" {
+		t.Errorf("fetchTarget() error = %v, want plain-text body of NBP reported", err)
+	}
+	if want := "unexpected HTTP status 404 Not Found: "; err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("fetchTarget() error = %v, want it to start with %q", err, want)
+	}
+}
+
+func TestDecodeSummaryOfTableC(t *testing.T) {
+	content := []byte(`{"table":"C","currency":"euro","code":"EUR","rates":[` +
+		`{"no":"126/C/NBP/2024","effectiveDate":"2024-07-01","bid":4.2738,"ask":4.3602}]}`)
+
+	for priceField, want := range map[string]float64{base.PriceBid: 4.2738, base.PriceAsk: 4.3602} {
+		summary, err := decodeSummary(bytes.NewReader(content), ResponseFormatJson, "C", priceField)
+		if err != nil {
+			t.Fatalf("decodeSummary() failed: %s", err)
+		}
+		if len(summary.Rates) != 1 || summary.Rates[0].Mid != want {
+			t.Errorf("decodeSummary() of %s price = %+v, want mid %v", priceField, summary.Rates, want)
+		}
+	}
+}
+
+func TestHTTPFetcherReportsRangeTooLong(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "400 BadRequest - Przekroczony limit 367 dni / Limit of 367 days has been exceeded", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	// range is within the limit client-side, the server still refuses it
+	cfg := newTestPoolConfig(1, server.URL)
+	f := httpFetcher(cfg)
+	f.From, f.To = "2024-01-01", "2024-12-31"
+	_, err := f.fetchTarget(context.Background(), 0, cfg.Targets[0])
+
+	if err == nil || !strings.HasPrefix(err.Error(), "range too long (max 367 days): ") {
+		t.Errorf("fetchTarget() error = %v, want range too long", err)
+	}
+
+	// the same status of a query of last rates is reported as it is
+	f.From, f.To = "", ""
+	_, err = f.fetchTarget(context.Background(), 0, cfg.Targets[0])
+	if err == nil || !strings.HasPrefix(err.Error(), "unexpected HTTP status 400 Bad Request: ") {
+		t.Errorf("fetchTarget() error = %v, want unexpected HTTP status", err)
+	}
+}
+
+func TestHTTPFetcherDumpsIndentedResponse(t *testing.T) {
+	server := newEncodedNbpServer(t, "gzip", testSummaryJson)
+	cfg := newTestPoolConfig(2, server.URL)
+	f := httpFetcher(cfg)
+	f.DumpResponse = true
+	output := captureLog(t, logger.LevelDebug)
+
+	for index := 0; index < 2; index++ {
+		if _, err := f.fetchTarget(context.Background(), index, cfg.Targets[0]); err != nil {
+			t.Fatalf("fetchTarget() failed: %s", err)
+		}
+	}
+
+	// only the first worker dumps its response
+	content := output.String()
+	if got := strings.Count(content, "Response body:"); got != 1 {
+		t.Fatalf("log has %d dumped responses, want 1:\n%s", got, content)
+	}
+	dumped := strings.TrimSpace(content[strings.Index(content, "Response body:\n")+len("Response body:\n"):])
+	var want bytes.Buffer
+	json.Indent(&want, []byte(testSummaryJson), "", "  ")
+	if dumped != want.String() {
+		t.Errorf("dumped response =\n%s\nwant indented body\n%s", dumped, want.String())
+	}
+}
+
+func TestHTTPFetcherDoesNotDumpResponseAboveDebugLevel(t *testing.T) {
+	server := newEncodedNbpServer(t, "", testSummaryJson)
+	cfg := newTestPoolConfig(1, server.URL)
+	f := httpFetcher(cfg)
+	f.DumpResponse = true
+	output := captureLog(t, logger.LevelInfo)
+
+	if _, err := f.fetchTarget(context.Background(), 0, cfg.Targets[0]); err != nil {
+		t.Fatalf("fetchTarget() failed: %s", err)
+	}
+	if strings.Contains(output.String(), "Response body:") {
+		t.Errorf("response is dumped at info level:\n%s", output)
+	}
+}
+
+// closeRecorder is response body telling whether it was closed
+type closeRecorder struct {
+	io.Reader
+	closed int32
+}
+
+func (c *closeRecorder) Close() error {
+	atomic.AddInt32(&c.closed, 1)
+	return nil
+}
+
+func TestHTTPFetcherClosesResponseBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		body     string
+		status   int
+	}{
+		{"gzip", "gzip", summaryJson("eur", 4.6), http.StatusOK},
+		{"deflate", "deflate", summaryJson("eur", 4.6), http.StatusOK},
+		{"plain", "", summaryJson("eur", 4.6), http.StatusOK},
+		{"truncated gzip", "gzip", summaryJson("eur", 4.6)[:20], http.StatusOK},
+		{"error status", "", "404 NotFound", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bodies []*closeRecorder
+			cfg := newTestPoolConfig(1, testApiUrl)
+			f := httpFetcher(cfg)
+			httpFetcher(cfg).Client.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				resp := encodedResponse(tt.encoding, tt.body)
+				resp.StatusCode = tt.status
+				body := &closeRecorder{Reader: resp.Body}
+				bodies = append(bodies, body)
+				resp.Body = body
+				return resp, nil
+			})
+
+			f.fetchTarget(context.Background(), 0, cfg.Targets[0])
+
+			if len(bodies) != 1 || atomic.LoadInt32(&bodies[0].closed) == 0 {
+				t.Errorf("body of the response is not closed")
+			}
+		})
+	}
+}
+
+func TestHTTPFetcherLeaksNoGoroutines(t *testing.T) {
+	server := newEncodedNbpServer(t, "gzip", summaryJson("eur", 4.55, 4.6))
+	cfg := newTestPoolConfig(1, server.URL)
+	f := httpFetcher(cfg)
+
+	fetch := func(count int) {
+		for i := 0; i < count; i++ {
+			if _, err := f.fetchTarget(context.Background(), 0, cfg.Targets[0]); err != nil {
+				t.Fatalf("fetchTarget() failed: %s", err)
+			}
+		}
+	}
+
+	// the first fetches start goroutines of the kept-alive connection, which are reused later
+	fetch(5)
+	before := runtime.NumGoroutine()
+	fetch(100)
+
+	// goroutines of finished requests may take a moment to exit
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("%d goroutines after 100 fetches, %d before them", after, before)
+	}
+}
+
+func TestHTTPFetcherOfEmptyAndTruncatedGzip(t *testing.T) {
+	compressed := compressBody("gzip", summaryJson("eur", 4.55, 4.6))
+
+	tests := []struct {
+		name    string
+		body    []byte
+		wantErr string
+	}{
+		{"valid", compressed, ""},
+		{"empty", nil, "failed to read body content: failed to decompress body: empty gzip body"},
+		{"truncated", compressed[:len(compressed)/2], "truncated response body"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Encoding", "gzip")
+				w.Write(tt.body)
+			}))
+			defer server.Close()
+			cfg := newTestPoolConfig(1, server.URL)
+			f := httpFetcher(cfg)
+
+			result, err := f.fetchTarget(context.Background(), 0, cfg.Targets[0])
+
+			if tt.wantErr == "" {
+				if err != nil || len(result.summary.Rates) != 2 {
+					t.Errorf("fetchTarget() = %+v, %v, want 2 rates", result, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("fetchTarget() error = %v, want %q", err, tt.wantErr)
+			}
+			if tt.name == "truncated" && !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Errorf("fetchTarget() error = %v, want it to wrap io.ErrUnexpectedEOF", err)
+			}
+		})
+	}
+}
+
+func TestHTTPFetcherLimitsBodySize(t *testing.T) {
+	// valid JSON padded with trailing whitespace to exactly 2000 bytes
+	body := summaryJson("eur", 4.55, 4.6)
+	body += strings.Repeat(" ", 2000-len(body))
+
+	tests := []struct {
+		name         string
+		encoding     string
+		maxBodyBytes int64
+		wantErr      bool
+	}{
+		{"at limit", "", 2000, false},
+		{"over limit", "", 1999, true},
+		{"decompressed at limit", "gzip", 2000, false},
+		// compressed body is far smaller, the limit applies to what it decompresses to
+		{"decompressed over limit", "gzip", 1999, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newEncodedNbpServer(t, tt.encoding, body)
+			cfg := newTestPoolConfig(1, server.URL)
+			f := httpFetcher(cfg)
+			f.MaxBodyBytes = tt.maxBodyBytes
+
+			result, err := f.fetchTarget(context.Background(), 0, cfg.Targets[0])
+
+			if !tt.wantErr {
+				if err != nil || len(result.summary.Rates) != 2 {
+					t.Errorf("fetchTarget() = %+v, %v, want 2 rates", result, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrResponseTooLarge) || !strings.Contains(err.Error(), "response too large") {
+				t.Errorf("fetchTarget() error = %v, want %v", err, ErrResponseTooLarge)
+			}
+		})
+	}
+}
+
+func TestHTTPFetcherLogsHeadersWhenVerbose(t *testing.T) {
+	server := newEncodedNbpServer(t, "gzip", testSummaryJson)
+	cfg := newTestPoolConfig(1, server.URL)
+	f := httpFetcher(cfg)
+	f.Verbose = true
+	cfg.Targets[0].request = testRequest(server.URL, requestOptions{UserAgent: "rates-test/1.0"})
+	output := captureLog(t, logger.LevelDebug)
+
+	if _, err := f.fetchTarget(context.Background(), 0, cfg.Targets[0]); err != nil {
+		t.Fatalf("fetchTarget() failed: %s", err)
+	}
+
+	content := output.String()
+	for _, want := range []string{"Request headers:", "  User-Agent: rates-test/1.0", "Response headers:", "  Content-Encoding: gzip"} {
+		if !strings.Contains(content, want+"\n") {
+			t.Errorf("verbose log is missing %q:\n%s", want, content)
+		}
+	}
+}
+
+func TestLogHeadersSortsKeys(t *testing.T) {
+	header := http.Header{"User-Agent": {"rates-test/1.0"}, "Accept": {"application/json"}, "Accept-Encoding": {"deflate", "gzip"}}
+	output := captureLog(t, logger.LevelDebug)
+
+	logHeaders(withRequestId(context.Background(), "0a1b2c3d"), 0, "Request headers", header)
+
+	want := "<worker-0 0a1b2c3d> Request headers:\n  Accept: application/json\n  Accept-Encoding: deflate, gzip\n  User-Agent: rates-test/1.0\n"
+	if got := output.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("logHeaders() logged %q, want %q", got, want)
+	}
+}
+
+func TestHTTPFetcherSendsHost(t *testing.T) {
+	requests := make(chan *http.Request, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		io.WriteString(w, testSummaryJson)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"", strings.TrimPrefix(server.URL, "http://")},
+		{"api.nbp.pl", "api.nbp.pl"},
+	}
+
+	for _, tt := range tests {
+		cfg := newTestPoolConfig(1, server.URL)
+		f := httpFetcher(cfg)
+		cfg.Targets[0].request = testRequest(server.URL, requestOptions{Host: tt.host})
+		if _, err := f.fetchTarget(context.Background(), 0, cfg.Targets[0]); err != nil {
+			t.Fatalf("fetchTarget() failed: %s", err)
+		}
+
+		if got := (<-requests).Host; got != tt.want {
+			t.Errorf("request of -host %q was sent to host %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestHTTPFetcherSendsUserAgent(t *testing.T) {
+	requests := make(chan *http.Request, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		io.WriteString(w, testSummaryJson)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "spyrosoft-recruitment-task/" + Version},
+		{[]string{"-user-agent", "rates-monitor/2.1 (ops@example.com)"}, "rates-monitor/2.1 (ops@example.com)"},
+	}
+
+	for _, tt := range tests {
+		cfg := newTestPoolConfig(1, server.URL)
+		f := httpFetcher(cfg)
+		cfg.Targets[0].request = testRequest(server.URL, requestOptions{UserAgent: loadTestConfig(t, tt.args...).UserAgent})
+		if _, err := f.fetchTarget(context.Background(), 0, cfg.Targets[0]); err != nil {
+			t.Fatalf("fetchTarget() failed: %s", err)
+		}
+
+		if got := (<-requests).Header.Get("User-Agent"); got != tt.want {
+			t.Errorf("request of %q was sent with User-Agent %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestHTTPFetcherSendsAcceptOfFormat(t *testing.T) {
+	requests := make(chan *http.Request, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		io.WriteString(w, testSummaryJson)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{ResponseFormatJson, "application/json"},
+		{ResponseFormatXml, "application/xml"},
+	}
+
+	for _, tt := range tests {
+		cfg := newTestPoolConfig(1, server.URL)
+		f := httpFetcher(cfg)
+		cfg.Targets[0].request = testRequest(server.URL, requestOptions{Format: tt.format})
+		// only the request matters, the JSON body does not decode as XML
+		f.fetchTarget(context.Background(), 0, cfg.Targets[0])
+
+		if got := (<-requests).Header.Get("Accept"); got != tt.want {
+			t.Errorf("request of -format %s was sent with Accept %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestHTTPFetcherFetchesCountOfCurrency(t *testing.T) {
+	requested := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested <- r.URL.Path
+		io.WriteString(w, summaryJson("usd", 3.9, 4.0))
+	}))
+	defer server.Close()
+	cfg := newTestPoolConfig(1, server.URL)
+	f := httpFetcher(cfg)
+	f.ApiBaseUrl = server.URL + "/"
+
+	summary, err := f.Fetch(context.Background(), "USD", 2)
+	if err != nil {
+		t.Fatalf("Fetch() failed: %s", err)
+	}
+
+	if got, want := <-requested, "/a/usd/last/2/"; got != want {
+		t.Errorf("requested path = %q, want %q", got, want)
+	}
+	if summary.Code != "USD" || len(summary.Rates) != 2 {
+		t.Errorf("Fetch() = %+v, want 2 rates of USD", summary)
+	}
+}
+
+func TestFileFetcherReadsSavedResponse(t *testing.T) {
+	tests := []struct {
+		format string
+		path   string
+	}{
+		{ResponseFormatJson, "eur_a_2024-07-01_2024-07-05.json"},
+		{ResponseFormatXml, "eur_a_2024-07-01_2024-07-05.xml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			// -input-file replaces the API, rates are checked against bounds as if they were fetched
+			path := filepath.Join("testdata", tt.path)
+			cfg := newTestPoolConfig(2, testApiUrl)
+			cfg.Targets[0].Bounds = base.RateBounds{Min: 4.3, Max: 4.32}
+			cfg.Fetcher = &FileFetcher{Path: path, Format: tt.format, Table: cfg.Table, PriceField: cfg.PriceField}
+			captureLog(t, logger.LevelInfo)
+
+			allStats, err := runPool(context.Background(), cfg)
+			if err != nil {
+				t.Fatalf("runPool() failed: %s", err)
+			}
+
+			stats := allStats[0]
+			if stats.Fetches != 2 || stats.Rates != 10 || stats.Min != 4.2909 || stats.Max != 4.3179 {
+				t.Errorf("stats = %+v, want 2 fetches of 5 rates each within 4.2909-4.3179", stats)
+			}
+			// 2024-07-04 and 2024-07-05 are below 4.30
+			if stats.OutOfScope != 2 {
+				t.Errorf("out of scope = %d, want 2", stats.OutOfScope)
+			}
+		})
+	}
+}
+
+func TestFileFetcherOfMissingFile(t *testing.T) {
+	fetcher := &FileFetcher{Path: filepath.Join(t.TempDir(), "missing.json"), Format: ResponseFormatJson, Table: base.TableA}
+
+	_, err := fetcher.Fetch(context.Background(), DefaultCurrency, DefaultCount)
+	if !errors.Is(err, os.ErrNotExist) || !strings.Contains(err.Error(), "failed to open input file") {
+		t.Errorf("Fetch() error = %v, want failure to open the file", err)
+	}
+}
+
+func TestHTTPFetcherInStrictModeRejectsNonConformingBody(t *testing.T) {
+	// mid as a string is silently ignored by the decoder, schema validation catches it
+	body := `{"table":"A","currency":"euro","code":"EUR","rates":[{"no":"001/A/NBP/2024","effectiveDate":"2024-01-02","mid":"4.6"}]}`
+
+	tests := []struct {
+		body    string
+		wantErr bool
+	}{
+		{summaryJson("eur", 4.6), false},
+		{body, true},
+	}
+
+	for _, tt := range tests {
+		server := newEncodedNbpServer(t, "", tt.body)
+		cfg := newTestPoolConfig(1, server.URL)
+		f := httpFetcher(cfg)
+		f.Strict = true
+
+		_, err := f.fetchTarget(context.Background(), 0, cfg.Targets[0])
+
+		var schemaErr *base.SchemaError
+		if tt.wantErr != errors.As(err, &schemaErr) {
+			t.Errorf("fetchTarget() of %s error = %v, want schema error %t", tt.body, err, tt.wantErr)
+		}
+		if tt.wantErr && !strings.Contains(err.Error(), "/rates/0/mid: expected number, but got string") {
+			t.Errorf("fetchTarget() error = %v, want path of the violation", err)
+		}
+	}
+}
+
+func TestHTTPFetcherSendsConditionalRequests(t *testing.T) {
+	const lastModified = "Tue, 02 Jan 2024 12:15:00 GMT"
+
+	tests := []struct {
+		name string
+		// validator the server sends and the request header it expects back
+		header, value, conditional string
+	}{
+		{"etag", "ETag", `"v1"`, "If-None-Match"},
+		{"last modified", "Last-Modified", lastModified, "If-Modified-Since"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			body := summaryJson("eur", 4.55, 4.6)
+			var conditionals []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				conditional := r.Header.Get(tt.conditional)
+				conditionals = append(conditionals, conditional)
+				if conditional == tt.value {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Header().Set(tt.header, tt.value)
+				io.WriteString(w, body)
+			}))
+			t.Cleanup(server.Close)
+
+			cfg := newTestPoolConfig(1, server.URL)
+			f := httpFetcher(cfg)
+			target := cfg.Targets[0]
+			log := captureLog(t, logger.LevelInfo)
+
+			first, err := f.fetchTarget(context.Background(), 0, target)
+			if err != nil || first.statusCode != http.StatusOK {
+				t.Fatalf("first fetchTarget() = %+v, %v, want 200", first, err)
+			}
+
+			notModified, err := f.fetchTarget(context.Background(), 0, target)
+			if err != nil || notModified.statusCode != http.StatusNotModified {
+				t.Fatalf("second fetchTarget() = %+v, %v, want 304", notModified, err)
+			}
+			if !reflect.DeepEqual(notModified.summary, first.summary) {
+				t.Errorf("304 response summary = %+v, want the previous one %+v", notModified.summary, first.summary)
+			}
+			if !strings.Contains(log.String(), "Not modified, reusing previous response") {
+				t.Errorf("log is missing not modified line:\n%s", log.String())
+			}
+
+			// validator changes along with the content
+			mu.Lock()
+			tt.value = "changed"
+			body = summaryJson("eur", 4.55, 4.6, 4.7)
+			mu.Unlock()
+			changed, err := f.fetchTarget(context.Background(), 0, target)
+			if err != nil || changed.statusCode != http.StatusOK || len(changed.summary.Rates) != 3 {
+				t.Errorf("third fetchTarget() = %+v, %v, want 200 of 3 rates", changed, err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if conditionals[0] != "" {
+				t.Errorf("first request sent %s: %s, want an unconditional request", tt.conditional, conditionals[0])
+			}
+			if conditionals[1] == "" || conditionals[2] != conditionals[1] {
+				t.Errorf("%s of following requests = %q, want the validator of the first response", tt.conditional, conditionals[1:])
+			}
+		})
+	}
+}
+
+func TestHTTPFetcherOfTodayQuery(t *testing.T) {
+	published := newEncodedNbpServer(t, "", summaryJson("eur", 4.6))
+	notPublished := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "404 NotFound - Not Found - Brak danych", http.StatusNotFound)
+	}))
+	t.Cleanup(notPublished.Close)
+
+	tests := []struct {
+		name   string
+		server *httptest.Server
+		today  bool
+		// wantRates is the number of fetched rates when the fetch succeeds
+		wantRates int
+		wantErr   error
+	}{
+		{"published today", published, true, 1, nil},
+		{"not published today", notPublished, true, 0, ErrNoDataToday},
+		// 404 of the last rates is a genuine failure
+		{"not found without -today", notPublished, false, 0, &base.ErrBadStatus{Code: http.StatusNotFound}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestPoolConfig(1, tt.server.URL)
+			f := httpFetcher(cfg)
+			f.Today = tt.today
+
+			fetched, err := f.fetchTarget(context.Background(), 0, cfg.Targets[0])
+
+			switch want := tt.wantErr.(type) {
+			case nil:
+				if err != nil || len(fetched.summary.Rates) != tt.wantRates {
+					t.Errorf("fetchTarget() = %+v, %v, want %d rates", fetched, err, tt.wantRates)
+				}
+			case *base.ErrBadStatus:
+				var badStatus *base.ErrBadStatus
+				if !errors.As(err, &badStatus) || badStatus.Code != want.Code || errors.Is(err, ErrNoDataToday) {
+					t.Errorf("fetchTarget() error = %v, want status %d", err, want.Code)
+				}
+			default:
+				if !errors.Is(err, want) {
+					t.Errorf("fetchTarget() error = %v, want %v", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"spyrosoft-recruitment-task/base"
+	"spyrosoft-recruitment-task/logger"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/*.golden files with the current output")
+
+var (
+	// time prefix of text lines and request id of workers differ between runs, so both are replaced
+	timestampPattern = regexp.MustCompile(`(?m)^\[\d{2}-\d{2}-\d{4} \d{2}:\d{2}:\d{2}\] `)
+	requestIdPattern = regexp.MustCompile(`<worker-(\d+) [0-9a-f]{8}>`)
+)
+
+// goldenMids are rates of three consecutive days, the first below and the last above bounds of 4.5 - 4.7 PLN
+var goldenMids = []float64{4.3512, 4.6102, 4.7423}
+
+// goldenFetcher serves goldenMids of the currency with the same response details to every worker
+type goldenFetcher struct{}
+
+func (goldenFetcher) Fetch(ctx context.Context, currency string, count int) (base.ExchangeRatesSummary, error) {
+	return testSummary(currency, goldenMids...), nil
+}
+
+func (f goldenFetcher) fetchTarget(ctx context.Context, index int, t *target) (*fetchResult, error) {
+	summary, err := f.Fetch(ctx, t.Currency, DefaultCount)
+	if err != nil {
+		return nil, err
+	}
+	return &fetchResult{
+		summary:     summary,
+		elapsed:     132 * time.Millisecond,
+		statusCode:  http.StatusOK,
+		contentType: "application/json; charset=utf-8",
+		isJsonValid: true,
+	}, nil
+}
+
+func TestRunPoolOutputGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		bounds base.RateBounds
+		quiet  bool
+	}{
+		{"text", base.RateBounds{Min: 4.3, Max: 4.8}, false},
+		{"text_out_of_scope", base.RateBounds{Min: 4.5, Max: 4.7}, false},
+		// -quiet drops the banners only, summaries and out-of-scope dates are still logged
+		{"quiet", base.RateBounds{Min: 4.5, Max: 4.7}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := captureLog(t, logger.LevelDebug)
+			cfg := newTestPoolConfig(1, testApiUrl)
+			cfg.Targets[0].Bounds = tt.bounds
+			cfg.Quiet = tt.quiet
+			cfg.VolatilityPct = DefaultVolatilityPct
+			cfg.Fetcher = goldenFetcher{}
+
+			if _, err := runPool(context.Background(), cfg); err != nil {
+				t.Fatalf("runPool() failed: %s", err)
+			}
+
+			got := timestampPattern.ReplaceAllString(output.String(), "[TIME] ")
+			got = requestIdPattern.ReplaceAllString(got, "<worker-$1 ID>")
+			assertGolden(t, tt.name, []byte(got))
+		})
+	}
+}
+
+func TestWritePoolSummaryGolden(t *testing.T) {
+	var buffer bytes.Buffer
+	err := logger.WritePoolSummary(&buffer, base.PoolStats{Currency: "eur", Fetches: 3})
+	if err != nil {
+		t.Fatalf("WritePoolSummary() failed: %s", err)
+	}
+	assertGolden(t, "no_rates", buffer.Bytes())
+}
+
+// assertGolden compares got with testdata/<name>.golden, rewriting the file instead with -update
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		err := os.WriteFile(path, got, 0644)
+		if err != nil {
+			t.Fatalf("failed to update %s: %s", path, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s, run with -update to create it: %s", path, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s, run with -update if the change is intended\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
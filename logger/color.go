@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+type ColorMode string
+
+const (
+	ColorAuto   ColorMode = "auto"
+	ColorAlways ColorMode = "always"
+	ColorNever  ColorMode = "never"
+)
+
+const (
+	ansiReset = "\x1b[0m"
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+)
+
+// colorEnabled is resolved once by InitLogger from the requested mode
+var colorEnabled = false
+
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+func ParseColorMode(s string) (ColorMode, error) {
+	switch mode := ColorMode(strings.ToLower(s)); mode {
+	case ColorAuto, ColorAlways, ColorNever:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown color mode %q, expected %q, %q or %q", s, ColorAuto, ColorAlways, ColorNever)
+}
+
+// resolveColor enables color in auto mode only when stdout is a terminal and NO_COLOR is not set
+func resolveColor(mode ColorMode) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+func colorize(color string, s string) string {
+	if !colorEnabled || s == "" {
+		return s
+	}
+	return color + s + ansiReset
+}
+
+func colorizeStatus(statusCode int) string {
+	status := fmt.Sprint(statusCode)
+	if statusCode >= 200 && statusCode < 300 {
+		return colorize(ansiGreen, status)
+	}
+	return colorize(ansiRed, status)
+}
+
+// stripAnsiWriter removes color codes, so log file stays plain text while terminal output is colored
+type stripAnsiWriter struct {
+	w io.Writer
+}
+
+func (s stripAnsiWriter) Write(p []byte) (int, error) {
+	_, err := s.w.Write(ansiEscape.ReplaceAll(p, nil))
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
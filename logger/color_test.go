@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"bytes"
+	"spyrosoft-recruitment-task/base"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestColorOfRequestInfo(t *testing.T) {
+	info := ReqInfo{RequestId: "4f2a9c1e", StatusCode: 200, Scope: base.RateBounds{Min: 4.5, Max: 4.7},
+		OutOfScope: []base.OutOfScopeRate{{EffectiveDate: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}}}
+	failed := ReqInfo{RequestId: "7be03d52", StatusCode: 404}
+
+	tests := []struct {
+		mode ColorMode
+		want []string
+	}{
+		{ColorNever, nil},
+		// output is not a terminal, so auto leaves colors out as well
+		{ColorAuto, nil},
+		{ColorAlways, []string{ansiGreen + "200" + ansiReset, ansiRed + "404" + ansiReset, ansiRed + "2024-01-02" + ansiReset}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			buffer := initOutput(t, FormatText, LevelInfo)
+			colorEnabled = resolveColor(tt.mode)
+			t.Cleanup(func() {
+				colorEnabled = false
+			})
+
+			PrintReqInfo(info)
+			PrintReqInfo(failed)
+
+			output := buffer.String()
+			if tt.want == nil && strings.Contains(output, "\x1b[") {
+				t.Errorf("output of %s contains escape codes:\n%q", tt.mode, output)
+			}
+			for _, colored := range tt.want {
+				if !strings.Contains(output, colored) {
+					t.Errorf("output of %s is missing %q:\n%q", tt.mode, colored, output)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveColorRespectsNoColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+
+	if resolveColor(ColorAuto) {
+		t.Errorf("resolveColor(%s) = true with NO_COLOR set, want false", ColorAuto)
+	}
+	if !resolveColor(ColorAlways) {
+		t.Errorf("resolveColor(%s) = false, want true", ColorAlways)
+	}
+}
+
+func TestStripAnsiWriter(t *testing.T) {
+	var buffer bytes.Buffer
+	line := "HTTP Status Code: " + ansiGreen + "200" + ansiReset + "\n"
+
+	n, err := stripAnsiWriter{&buffer}.Write([]byte(line))
+	if err != nil || n != len(line) {
+		t.Fatalf("Write() = %d, %v, want %d, nil", n, err, len(line))
+	}
+	if got := buffer.String(); got != "HTTP Status Code: 200\n" {
+		t.Errorf("written %q, want plain text", got)
+	}
+}
+
+func TestParseColorMode(t *testing.T) {
+	for _, s := range []string{"auto", "ALWAYS", "Never"} {
+		if _, err := ParseColorMode(s); err != nil {
+			t.Errorf("ParseColorMode(%q) failed: %s", s, err)
+		}
+	}
+	if _, err := ParseColorMode("sometimes"); err == nil {
+		t.Errorf("ParseColorMode(%q) succeeded, want error", "sometimes")
+	}
+}
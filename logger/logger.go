@@ -1,31 +1,412 @@
 package logger
 
 import (
+	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"spyrosoft-recruitment-task/base"
 	"strings"
 	"time"
 )
 
-func InitLogger() {
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJson Format = "json"
+)
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+const DefaultDateLayout = "2006-01-02"
+
+// timestampLayout is the time prefix of every line of text output
+const timestampLayout = "[01-02-2006 15:04:05] "
+
+type Options struct {
+	Format Format
+	Level  Level
+	// path of size-rotated log file, log.txt in working directory is used when empty
+	File string
+	// time.Format layout of dates in output
+	DateLayout string
+	// colors of text output, only terminal output is colored
+	Color ColorMode
+	// destination of output replacing log file and stdout when set, e.g. a buffer capturing it
+	Output io.Writer
+	// time zone of timestamps in output, UTC when nil
+	Location *time.Location
+}
+
+var (
+	outputFormat = FormatText
+	minLevel     = LevelInfo
+	dateLayout   = DefaultDateLayout
+	location     = time.UTC
+	// source of current time, replaced by tests
+	clock = time.Now
+)
+
+// now returns current time in the zone of output
+func now() time.Time {
+	return clock().In(location)
+}
+
+type poolSummaryEntry struct {
+	Time       string  `json:"time"`
+	Currency   string  `json:"currency,omitempty"`
+	Fetches    int     `json:"successful_requests"`
+	Rates      int     `json:"rates"`
+	MinMid     float64 `json:"min_mid"`
+	MaxMid     float64 `json:"max_mid"`
+	AverageMid float64 `json:"average_mid"`
+	OutOfScope int     `json:"out_of_scope_dates"`
+	StdDev     float64 `json:"std_dev_mid"`
+	Volatile   int     `json:"volatile_days"`
+	P50Ms      int64   `json:"p50_ms"`
+	P95Ms      int64   `json:"p95_ms"`
+	P99Ms      int64   `json:"p99_ms"`
+}
+
+type rateChangeEntry struct {
+	Time          string  `json:"time"`
+	No            string  `json:"no"`
+	EffectiveDate string  `json:"effective_date"`
+	OldMid        float64 `json:"old_mid"`
+	NewMid        float64 `json:"new_mid"`
+	IsNew         bool    `json:"new"`
+}
+
+type messageEntry struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+type reqInfoEntry struct {
+	Time            string   `json:"time"`
+	WorkerIndex     int      `json:"worker_index"`
+	RequestId       string   `json:"request_id"`
+	ElapsedMs       int64    `json:"elapsed_ms"`
+	StatusCode      int      `json:"status_code"`
+	ContentType     string   `json:"content_type"`
+	JsonValid       bool     `json:"json_valid"`
+	OutOfScopeDates []string `json:"out_of_scope_dates"`
+}
+
+func ParseFormat(s string) (Format, error) {
+	switch Format(strings.ToLower(s)) {
+	case FormatText:
+		return FormatText, nil
+	case FormatJson:
+		return FormatJson, nil
+	}
+	return "", fmt.Errorf("unknown log format %q, expected %q or %q", s, FormatText, FormatJson)
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+func ParseLevel(s string) (Level, error) {
+	for level, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q, expected one of debug, info, warn, error", s)
+}
+
+func InitLogger(opts Options) {
+	outputFormat = opts.Format
+	minLevel = opts.Level
+	if opts.DateLayout != "" {
+		dateLayout = opts.DateLayout
+	}
+	if opts.Location != nil {
+		location = opts.Location
+	}
+
+	output = opts.Output
+	if output == nil {
+		colorEnabled = outputFormat == FormatText && resolveColor(opts.Color)
+		output = newDefaultOutput(opts.File)
+	} else {
+		// terminal is not known to be the destination, so colors are only used when forced
+		colorEnabled = outputFormat == FormatText && opts.Color == ColorAlways
+	}
+
+	// startup errors reported through the log package end up in the same output, stamped like the rest of it
 	log.SetFlags(0)
-	file, err := os.OpenFile("log.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	log.SetPrefix("")
+	log.SetOutput(timestampWriter{output})
+
+	startWriter()
+}
+
+// newDefaultOutput returns writer of both stdout and the log file, at path or log.txt in working directory
+func newDefaultOutput(path string) io.Writer {
+	var file io.Writer
+	var err error
+	if path != "" {
+		file, err = NewRotatingFile(path, DefaultMaxFileSize, DefaultMaxBackups)
+	} else {
+		file, err = os.OpenFile("log.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	}
 	if err != nil {
 		log.Fatalf("Failed to create log file: %s", err)
 	}
 
-	log.SetPrefix(time.Now().Format("[01-02-2006 15:04:05] "))
+	if colorEnabled {
+		file = stripAnsiWriter{file}
+	}
+
+	return io.MultiWriter(file, os.Stdout)
+}
+
+func Debug(format string, v ...interface{}) {
+	printLevel(LevelDebug, format, v...)
+}
+
+func Info(format string, v ...interface{}) {
+	printLevel(LevelInfo, format, v...)
+}
+
+func Warn(format string, v ...interface{}) {
+	printLevel(LevelWarn, format, v...)
+}
+
+func Error(format string, v ...interface{}) {
+	printLevel(LevelError, format, v...)
+}
+
+func Enabled(level Level) bool {
+	return level >= minLevel
+}
+
+func printLevel(level Level, format string, v ...interface{}) {
+	if !Enabled(level) {
+		return
+	}
+
+	if outputFormat == FormatJson {
+		writeJsonLine(messageEntry{
+			Time:    now().Format(time.RFC3339),
+			Level:   level.String(),
+			Message: fmt.Sprintf(format, v...),
+		})
+		return
+	}
+
+	lines := newTextLines()
+	lines.add(format, v...)
+	lines.send()
+}
+
+// ReqInfo describes a single successful API request of a worker
+type ReqInfo struct {
+	Index       int
+	RequestId   string
+	Elapsed     time.Duration
+	StatusCode  int
+	ContentType string
+	IsJsonValid bool
+	// bounds or baseline the rates were checked against
+	Scope      base.Scope
+	OutOfScope []base.OutOfScopeRate
+}
+
+func PrintReqInfo(info ReqInfo) {
+	if !Enabled(LevelInfo) {
+		return
+	}
+
+	if outputFormat == FormatJson {
+		printReqInfoJson(info)
+		return
+	}
+
+	lines := newTextLines()
+	addReqInfo(&lines, info)
+	lines.send()
+}
+
+// WriteReqInfo writes text output of info to w, lines are not prefixed with time
+func WriteReqInfo(w io.Writer, info ReqInfo) error {
+	var lines textLines
+	addReqInfo(&lines, info)
+	return lines.writeTo(w)
+}
+
+func addReqInfo(lines *textLines, info ReqInfo) {
+	tag := fmt.Sprintf("<worker-%d %s>", info.Index, info.RequestId)
+	lines.add("%s Request Time: %d ms", tag, info.Elapsed.Milliseconds())
+	lines.add("%s HTTP Status Code: %s", tag, colorizeStatus(info.StatusCode))
+	lines.add("%s HTTP Content Type: %s", tag, info.ContentType)
+	lines.add("%s Is Syntax Valid JSON: %t", tag, info.IsJsonValid)
+	dates := colorize(ansiRed, strings.Join(formatDates(info.OutOfScope), "; "))
+	lines.add("%s Mid Was Out Of Scope %s in: %s", tag, info.Scope, dates)
+}
+
+func printReqInfoJson(info ReqInfo) {
+	entry := reqInfoEntry{
+		Time:            now().Format(time.RFC3339),
+		WorkerIndex:     info.Index,
+		RequestId:       info.RequestId,
+		ElapsedMs:       info.Elapsed.Milliseconds(),
+		StatusCode:      info.StatusCode,
+		ContentType:     info.ContentType,
+		JsonValid:       info.IsJsonValid,
+		OutOfScopeDates: formatDates(info.OutOfScope),
+	}
+
+	writeJsonLine(entry)
+}
+
+func PrintPoolSummary(stats base.PoolStats) {
+	if !Enabled(LevelInfo) {
+		return
+	}
+
+	if outputFormat == FormatJson {
+		writeJsonLine(poolSummaryEntry{
+			Time:       now().Format(time.RFC3339),
+			Currency:   stats.Currency,
+			Fetches:    stats.Fetches,
+			Rates:      stats.Rates,
+			MinMid:     stats.Min,
+			MaxMid:     stats.Max,
+			AverageMid: stats.Average,
+			OutOfScope: stats.OutOfScope,
+			StdDev:     stats.StdDev,
+			Volatile:   len(stats.VolatileDays),
+			P50Ms:      stats.Latency.P50.Milliseconds(),
+			P95Ms:      stats.Latency.P95.Milliseconds(),
+			P99Ms:      stats.Latency.P99.Milliseconds(),
+		})
+		return
+	}
 
-	multi := io.MultiWriter(file, os.Stdout)
-	log.SetOutput(multi)
+	lines := newTextLines()
+	addPoolSummary(&lines, stats)
+	lines.send()
 }
 
-func PrintReqInfo(index int, elapsed time.Duration, statusCode int, contentType string, isJsonValid bool, rateOutOfScope []string) {
-	log.Printf("<worker-%d> Request Time: %d ms", index, elapsed.Milliseconds())
-	log.Printf("<worker-%d> HTTP Status Code: %d", index, statusCode)
-	log.Printf("<worker-%d> HTTP Content Type: %s", index, contentType)
-	log.Printf("<worker-%d> Is Syntax Valid JSON: %t", index, isJsonValid)
-	dates := strings.Join(rateOutOfScope, "; ")
-	log.Printf("<worker-%d> Mid Was Out Of Scope 4.50 - 4.70 PLN in: %s", index, dates)
+// WritePoolSummary writes text output of stats to w, lines are not prefixed with time
+func WritePoolSummary(w io.Writer, stats base.PoolStats) error {
+	var lines textLines
+	addPoolSummary(&lines, stats)
+	return lines.writeTo(w)
+}
+
+func addPoolSummary(lines *textLines, stats base.PoolStats) {
+	tag := PoolTag(stats.Currency)
+
+	if stats.Rates == 0 {
+		lines.add("%s No Rates Fetched In %d Successful Requests", tag, stats.Fetches)
+		return
+	}
+
+	lines.add("%s Successful Requests: %d", tag, stats.Fetches)
+	if stats.Latency.Samples > 0 {
+		lines.add("%s Request Time p50/p95/p99: %d/%d/%d ms", tag, stats.Latency.P50.Milliseconds(), stats.Latency.P95.Milliseconds(), stats.Latency.P99.Milliseconds())
+	}
+	lines.add("%s Min Mid: %.4f PLN", tag, stats.Min)
+	lines.add("%s Max Mid: %.4f PLN", tag, stats.Max)
+	lines.add("%s Average Mid: %.4f PLN", tag, stats.Average)
+	lines.add("%s Out Of Scope Dates: %d", tag, stats.OutOfScope)
+	lines.add("%s Std Dev Of Mid: %.4f PLN", tag, stats.StdDev)
+	lines.add("%s Volatile Days (> %.2f%%): %d", tag, stats.VolatilityPct, len(stats.VolatileDays))
+}
+
+// PoolTag prefixes pool log lines, currency is included when it is given
+func PoolTag(currency string) string {
+	if currency == "" {
+		return "<pool>"
+	}
+	return "<pool " + strings.ToUpper(currency) + ">"
+}
+
+// PrintRateChanges logs rates which changed since the previous pool
+func PrintRateChanges(changes []base.RateChange) {
+	if !Enabled(LevelInfo) {
+		return
+	}
+
+	if outputFormat == FormatJson {
+		for _, change := range changes {
+			writeJsonLine(rateChangeEntry{
+				Time:          now().Format(time.RFC3339),
+				No:            change.No,
+				EffectiveDate: change.EffectiveDate.Format(dateLayout),
+				OldMid:        change.Old,
+				NewMid:        change.New,
+				IsNew:         change.IsNew,
+			})
+		}
+		return
+	}
+
+	lines := newTextLines()
+	addRateChanges(&lines, changes)
+	lines.send()
+}
+
+// WriteRateChanges writes text output of changes to w, lines are not prefixed with time
+func WriteRateChanges(w io.Writer, changes []base.RateChange) error {
+	var lines textLines
+	addRateChanges(&lines, changes)
+	return lines.writeTo(w)
+}
+
+func addRateChanges(lines *textLines, changes []base.RateChange) {
+	if len(changes) == 0 {
+		lines.add("<diff> No Rates Changed")
+		return
+	}
+
+	for _, change := range changes {
+		date := change.EffectiveDate.Format(dateLayout)
+		if change.IsNew {
+			lines.add("<diff> New Rate %s (%s): %.4f PLN", change.No, date, change.New)
+		} else {
+			lines.add("<diff> Rate %s (%s) Changed: %.4f -> %.4f PLN", change.No, date, change.Old, change.New)
+		}
+	}
+}
+
+// formatDates never returns nil, so empty list is rendered as [] instead of null in JSON
+func formatDates(rates []base.OutOfScopeRate) []string {
+	dates := make([]string, 0, len(rates))
+	for _, rate := range rates {
+		dates = append(dates, rate.EffectiveDate.Format(dateLayout))
+	}
+	return dates
+}
+
+// writeJsonLine bypasses log prefix, so every line stays a valid JSON object
+func writeJsonLine(entry interface{}) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to marshal log entry: %s\n", err)
+		return
+	}
+
+	send(LogMessage{Text: append(line, '\n')})
 }
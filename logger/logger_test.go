@@ -0,0 +1,301 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"spyrosoft-recruitment-task/base"
+	"strings"
+	"testing"
+	"time"
+)
+
+// initOutput directs output of format at level to the returned buffer,
+// the writer is stopped, so messages are in the buffer as soon as they are logged
+func initOutput(t *testing.T, format Format, level Level) *bytes.Buffer {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	Close()
+	InitLogger(Options{Format: format, Level: level, Output: &buffer, Color: ColorNever})
+	Close()
+	t.Cleanup(func() {
+		Close()
+		InitLogger(Options{Format: FormatText, Level: LevelInfo, Output: &bytes.Buffer{}, Color: ColorNever})
+	})
+	return &buffer
+}
+
+// timestampPattern matches the time prefix of text lines, which is replaced as it differs between runs
+var timestampPattern = regexp.MustCompile(`(?m)^\[\d{2}-\d{2}-\d{4} \d{2}:\d{2}:\d{2}\] `)
+
+// testOutOfScope are rates below and above bounds of 4.5 - 4.7
+var testOutOfScope = []base.OutOfScopeRate{
+	{No: "001/A/NBP/2024", EffectiveDate: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Mid: 4.49, Direction: base.DirectionBelow},
+	{No: "005/A/NBP/2024", EffectiveDate: time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC), Mid: 4.71, Direction: base.DirectionAbove},
+}
+
+// jsonKeys returns sorted keys of JSON object line
+func jsonKeys(t *testing.T, line string) []string {
+	t.Helper()
+
+	var object map[string]interface{}
+	err := json.Unmarshal([]byte(line), &object)
+	if err != nil {
+		t.Fatalf("line %q is not a JSON object: %s", line, err)
+	}
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestJsonOutputWritesAnObjectPerLine(t *testing.T) {
+	buffer := initOutput(t, FormatJson, LevelInfo)
+	bounds := base.RateBounds{Min: 4.5, Max: 4.7}
+
+	PrintReqInfo(ReqInfo{Index: 1, RequestId: "0a1b2c3d", Elapsed: 132 * time.Millisecond, StatusCode: 200, ContentType: "application/json", IsJsonValid: true, Scope: bounds, OutOfScope: testOutOfScope})
+	PrintReqInfo(ReqInfo{Index: 2, RequestId: "4e5f6a7b", Elapsed: 98 * time.Millisecond, StatusCode: 200, ContentType: "application/json", IsJsonValid: true, Scope: bounds})
+
+	wantKeys := []string{"content_type", "elapsed_ms", "json_valid", "out_of_scope_dates", "request_id", "status_code", "time", "worker_index"}
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buffer)
+	}
+	for i, line := range lines {
+		if !json.Valid([]byte(line)) {
+			t.Errorf("line %q is not valid JSON", line)
+			continue
+		}
+		if keys := jsonKeys(t, line); !reflect.DeepEqual(keys, wantKeys) {
+			t.Errorf("line %d has keys %v, want %v", i, keys, wantKeys)
+		}
+	}
+
+	var reqInfo reqInfoEntry
+	json.Unmarshal([]byte(lines[0]), &reqInfo)
+	if reqInfo.WorkerIndex != 1 || reqInfo.ElapsedMs != 132 || !reflect.DeepEqual(reqInfo.OutOfScopeDates, []string{"2024-01-02", "2024-01-08"}) {
+		t.Errorf("request line = %+v, want worker 1 of 132 ms out of scope on 2024-01-02 and 2024-01-08", reqInfo)
+	}
+	if _, err := time.Parse(time.RFC3339, reqInfo.Time); err != nil {
+		t.Errorf("time %q is not RFC 3339: %s", reqInfo.Time, err)
+	}
+
+	// no dates are written as an empty list
+	if !strings.Contains(lines[1], `"out_of_scope_dates":[]`) {
+		t.Errorf("line %q has no empty list of out of scope dates", lines[1])
+	}
+}
+
+func TestTextOutputOfReqInfo(t *testing.T) {
+	buffer := initOutput(t, FormatText, LevelInfo)
+
+	PrintReqInfo(ReqInfo{Index: 1, RequestId: "0a1b2c3d", Elapsed: 132 * time.Millisecond, StatusCode: 200, ContentType: "application/json", IsJsonValid: true, Scope: base.RateBounds{Min: 4.5, Max: 4.7}, OutOfScope: testOutOfScope})
+
+	want := "<worker-1 0a1b2c3d> Request Time: 132 ms\n" +
+		"<worker-1 0a1b2c3d> HTTP Status Code: 200\n" +
+		"<worker-1 0a1b2c3d> HTTP Content Type: application/json\n" +
+		"<worker-1 0a1b2c3d> Is Syntax Valid JSON: true\n" +
+		"<worker-1 0a1b2c3d> Mid Was Out Of Scope 4.50 - 4.70 PLN in: 2024-01-02; 2024-01-08\n"
+	if got := timestampPattern.ReplaceAllString(buffer.String(), ""); got != want {
+		t.Errorf("text output =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    Format
+		wantErr bool
+	}{
+		{"text", FormatText, false},
+		{"JSON", FormatJson, false},
+		{"xml", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.s)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseFormat(%q) = %q, %v, want %q, error %t", tt.s, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestWarnLevelSuppressesInfoAndDebug(t *testing.T) {
+	for _, format := range []Format{FormatText, FormatJson} {
+		t.Run(string(format), func(t *testing.T) {
+			buffer := initOutput(t, format, LevelWarn)
+
+			Debug("debug line")
+			Info("info line")
+			PrintReqInfo(ReqInfo{Index: 7, RequestId: "0a1b2c3d", Elapsed: time.Millisecond, StatusCode: 200, ContentType: "application/json", IsJsonValid: true, Scope: base.RateBounds{Min: 4.5, Max: 4.7}})
+			Warn("warn line")
+			Error("error line")
+
+			output := buffer.String()
+			for _, suppressed := range []string{"debug line", "info line", "worker-7", "worker_index"} {
+				if strings.Contains(output, suppressed) {
+					t.Errorf("output contains %q below warn level:\n%s", suppressed, output)
+				}
+			}
+			for _, kept := range []string{"warn line", "error line"} {
+				if !strings.Contains(output, kept) {
+					t.Errorf("output is missing %q:\n%s", kept, output)
+				}
+			}
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    Level
+		wantErr bool
+	}{
+		{"debug", LevelDebug, false},
+		{"INFO", LevelInfo, false},
+		{"Warn", LevelWarn, false},
+		{"error", LevelError, false},
+		{"fatal", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.s)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v, error %t", tt.s, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestOutOfScopeDatesLayout(t *testing.T) {
+	tests := []struct {
+		layout string
+		want   string
+	}{
+		{DefaultDateLayout, "in: 2024-01-02; 2024-01-08"},
+		{"02.01.2006", "in: 02.01.2024; 08.01.2024"},
+	}
+
+	for _, tt := range tests {
+		buffer := initOutput(t, FormatText, LevelInfo)
+		dateLayout = tt.layout
+		PrintReqInfo(ReqInfo{Index: 1, RequestId: "0a1b2c3d", Elapsed: time.Millisecond, StatusCode: 200, ContentType: "application/json", IsJsonValid: true, Scope: base.RateBounds{Min: 4.5, Max: 4.7}, OutOfScope: testOutOfScope})
+		dateLayout = DefaultDateLayout
+
+		if !strings.Contains(buffer.String(), tt.want+"\n") {
+			t.Errorf("output of layout %q =\n%s\nwant dates %q", tt.layout, buffer.String(), tt.want)
+		}
+	}
+}
+
+func TestPrintPoolSummary(t *testing.T) {
+	stats := base.PoolStats{Fetches: 2, Rates: 4, Min: 4.4, Max: 4.6, Average: 4.5, OutOfScope: 1}
+
+	t.Run("text", func(t *testing.T) {
+		buffer := initOutput(t, FormatText, LevelInfo)
+		PrintPoolSummary(stats)
+		for _, want := range []string{"Successful Requests: 2", "Min Mid: 4.4000 PLN", "Max Mid: 4.6000 PLN", "Average Mid: 4.5000 PLN", "Out Of Scope Dates: 1"} {
+			if !strings.Contains(buffer.String(), want) {
+				t.Errorf("summary does not contain %q:\n%s", want, buffer)
+			}
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		buffer := initOutput(t, FormatJson, LevelInfo)
+		PrintPoolSummary(stats)
+		var entry poolSummaryEntry
+		if err := json.Unmarshal(buffer.Bytes(), &entry); err != nil {
+			t.Fatalf("summary %q is not a JSON object: %s", buffer, err)
+		}
+		if entry.Fetches != 2 || entry.Rates != 4 || entry.AverageMid != 4.5 || entry.OutOfScope != 1 {
+			t.Errorf("summary = %+v, want 2 requests of 4 rates averaging 4.5 with 1 out-of-scope date", entry)
+		}
+	})
+
+	t.Run("warn level", func(t *testing.T) {
+		buffer := initOutput(t, FormatText, LevelWarn)
+		PrintPoolSummary(stats)
+		if buffer.Len() != 0 {
+			t.Errorf("summary is printed at warn level:\n%s", buffer)
+		}
+	})
+}
+
+func TestPrintRateChanges(t *testing.T) {
+	changes := []base.RateChange{
+		{No: "003/A/NBP/2024", EffectiveDate: time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC), Old: 4.6, New: 4.7},
+		{No: "004/A/NBP/2024", EffectiveDate: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), New: 4.65, IsNew: true},
+	}
+
+	buffer := initOutput(t, FormatText, LevelInfo)
+	PrintRateChanges(changes)
+	PrintRateChanges(nil)
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	want := []string{
+		"<diff> Rate 003/A/NBP/2024 (2024-01-04) Changed: 4.6000 -> 4.7000 PLN",
+		"<diff> New Rate 004/A/NBP/2024 (2024-01-05): 4.6500 PLN",
+		"<diff> No Rates Changed",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(want), buffer)
+	}
+	for i, line := range lines {
+		if !strings.HasSuffix(line, want[i]) {
+			t.Errorf("line %d = %q, want %q", i, line, want[i])
+		}
+	}
+}
+
+func TestReqInfoRendersPositionalFormat(t *testing.T) {
+	info := ReqInfo{Index: 2, RequestId: "4f2a9c1e", Elapsed: 132 * time.Millisecond, StatusCode: 200,
+		ContentType: "application/json; charset=utf-8", IsJsonValid: true, Scope: base.RateBounds{Min: 4.5, Max: 4.7},
+		OutOfScope: []base.OutOfScopeRate{
+			{EffectiveDate: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+			{EffectiveDate: time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC)},
+		}}
+
+	// lines as the positional PrintReqInfo(index, elapsed, statusCode, contentType, isJsonValid, rateOutOfScope) logged them
+	var want strings.Builder
+	tag := "<worker-2 4f2a9c1e>"
+	fmt.Fprintf(&want, "%s Request Time: %d ms\n", tag, 132)
+	fmt.Fprintf(&want, "%s HTTP Status Code: %d\n", tag, 200)
+	fmt.Fprintf(&want, "%s HTTP Content Type: %s\n", tag, "application/json; charset=utf-8")
+	fmt.Fprintf(&want, "%s Is Syntax Valid JSON: %t\n", tag, true)
+	fmt.Fprintf(&want, "%s Mid Was Out Of Scope 4.50 - 4.70 PLN in: %s\n", tag, strings.Join([]string{"2024-01-02", "2024-01-04"}, "; "))
+
+	buffer := initOutput(t, FormatText, LevelInfo)
+	PrintReqInfo(info)
+
+	if got := timestampPattern.ReplaceAllString(buffer.String(), ""); got != want.String() {
+		t.Errorf("PrintReqInfo() logged\n%s\nwant\n%s", got, want.String())
+	}
+}
+
+func TestOutputIsWrittenToGivenWriter(t *testing.T) {
+	buffer := initOutput(t, FormatText, LevelInfo)
+
+	Info("pool of %d workers", 2)
+	PrintReqInfo(ReqInfo{Index: 1, RequestId: "4f2a9c1e", Elapsed: 132 * time.Millisecond, StatusCode: 200,
+		ContentType: "application/json", IsJsonValid: true, Scope: base.RateBounds{Min: 4.5, Max: 4.7}})
+	Error("<worker-0 7be03d52> Fetch failed: %s", "unexpected HTTP status 503")
+	Close()
+
+	want := "[TIME] pool of 2 workers\n" +
+		"[TIME] <worker-1 4f2a9c1e> Request Time: 132 ms\n" +
+		"[TIME] <worker-1 4f2a9c1e> HTTP Status Code: 200\n" +
+		"[TIME] <worker-1 4f2a9c1e> HTTP Content Type: application/json\n" +
+		"[TIME] <worker-1 4f2a9c1e> Is Syntax Valid JSON: true\n" +
+		"[TIME] <worker-1 4f2a9c1e> Mid Was Out Of Scope 4.50 - 4.70 PLN in: \n" +
+		"[TIME] <worker-0 7be03d52> Fetch failed: unexpected HTTP status 503\n"
+	if got := timestampPattern.ReplaceAllString(buffer.String(), "[TIME] "); got != want {
+		t.Errorf("output =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,45 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFileRollsOverOnceFull(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+	rf, err := NewRotatingFile(path, 100, 2)
+	if err != nil {
+		t.Fatalf("NewRotatingFile() failed: %s", err)
+	}
+	defer rf.Close()
+
+	// 40 bytes per line, so every third line starts a new file
+	line := strings.Repeat("x", 39) + "\n"
+	for i := 0; i < 7; i++ {
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatalf("Write() failed: %s", err)
+		}
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			t.Errorf("log file %s is missing: %s", name, err)
+			continue
+		}
+		if len(content) > 100 {
+			t.Errorf("log file %s has %d bytes, above max size of 100", name, len(content))
+		}
+	}
+	// only -max-backups of 2 are kept
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("backup beyond max backups exists: %v", err)
+	}
+
+	current, _ := os.ReadFile(path)
+	if string(current) != line {
+		t.Errorf("current log file = %q, want the last line only", current)
+	}
+}
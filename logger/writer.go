@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// queueSize is how many messages may wait for the writer before callers block
+const queueSize = 1024
+
+// LogMessage is a complete piece of output written at once, lines of one message are never interleaved with others
+type LogMessage struct {
+	Text []byte
+}
+
+var (
+	// destination of all messages, set by InitLogger
+	output io.Writer = os.Stderr
+
+	// guards queue against being closed while messages are sent
+	queueMu   sync.RWMutex
+	queue     chan LogMessage
+	queueDone chan struct{}
+)
+
+// startWriter starts the goroutine writing queued messages serially to the log output
+func startWriter() {
+	queueMu.Lock()
+	defer queueMu.Unlock()
+
+	if queue != nil {
+		return
+	}
+
+	queue = make(chan LogMessage, queueSize)
+	queueDone = make(chan struct{})
+
+	go func(queue <-chan LogMessage, done chan<- struct{}) {
+		defer close(done)
+		for message := range queue {
+			write(message)
+		}
+	}(queue, queueDone)
+}
+
+// Close writes out all queued messages and stops the writer, later messages are written directly
+func Close() {
+	queueMu.Lock()
+	if queue == nil {
+		queueMu.Unlock()
+		return
+	}
+	close(queue)
+	done := queueDone
+	queue = nil
+	queueMu.Unlock()
+
+	<-done
+}
+
+func send(message LogMessage) {
+	queueMu.RLock()
+	defer queueMu.RUnlock()
+
+	if queue == nil {
+		write(message)
+		return
+	}
+	queue <- message
+}
+
+func write(message LogMessage) {
+	_, err := output.Write(message.Text)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write log entry: %s\n", err)
+	}
+}
+
+// textLines collects prefixed lines of a single text message
+type textLines struct {
+	prefix string
+	b      strings.Builder
+}
+
+// newTextLines returns lines prefixed with the time of the message, which all of its lines share
+func newTextLines() textLines {
+	return textLines{prefix: now().Format(timestampLayout)}
+}
+
+func (l *textLines) add(format string, v ...interface{}) {
+	l.b.WriteString(l.prefix)
+	fmt.Fprintf(&l.b, format, v...)
+	l.b.WriteByte('\n')
+}
+
+func (l *textLines) send() {
+	if l.b.Len() > 0 {
+		send(LogMessage{Text: []byte(l.b.String())})
+	}
+}
+
+func (l *textLines) writeTo(w io.Writer) error {
+	_, err := io.WriteString(w, l.b.String())
+	return err
+}
+
+// timestampWriter prefixes output of the log package with the time it is written, every write is a single message
+type timestampWriter struct {
+	w io.Writer
+}
+
+func (t timestampWriter) Write(p []byte) (int, error) {
+	line := append([]byte(now().Format(timestampLayout)), p...)
+	_, err := t.w.Write(line)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"regexp"
+	"spyrosoft-recruitment-task/base"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// initWriter directs text output to the returned buffer through the queued writer, lines prefixed with a fixed time
+func initWriter(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	return initWithClock(t, time.UTC, func() time.Time {
+		return time.Date(2024, 1, 2, 11, 0, 0, 0, time.UTC)
+	})
+}
+
+// reqInfoLinePattern matches a complete line of request info, capturing its worker tag
+var reqInfoLinePattern = regexp.MustCompile(`^\[01-02-2024 11:00:00\] (<worker-\d+ [0-9a-f]{8}>) (Request Time: \d+ ms|HTTP Status Code: 200|HTTP Content Type: application/json|Is Syntax Valid JSON: true|Mid Was Out Of Scope 4.50 - 4.70 PLN in: )$`)
+
+func TestConcurrentWorkersDoNotInterleaveLines(t *testing.T) {
+	buffer := initWriter(t)
+
+	// more messages than the queue holds, so senders also block on a full queue
+	const workers = 64
+	const messagesPerWorker = 20
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			for j := 0; j < messagesPerWorker; j++ {
+				PrintReqInfo(ReqInfo{Index: index, RequestId: fmt.Sprintf("%08x", index), Elapsed: time.Duration(j) * time.Millisecond,
+					StatusCode: 200, ContentType: "application/json", IsJsonValid: true, Scope: base.RateBounds{Min: 4.5, Max: 4.7}})
+			}
+		}(i)
+	}
+	wg.Wait()
+	// every queued message is written out once the writer is closed
+	Close()
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	const linesPerMessage = 5
+	if len(lines) != workers*messagesPerWorker*linesPerMessage {
+		t.Fatalf("got %d lines, want %d", len(lines), workers*messagesPerWorker*linesPerMessage)
+	}
+	for start := 0; start < len(lines); start += linesPerMessage {
+		var tag string
+		for _, line := range lines[start : start+linesPerMessage] {
+			match := reqInfoLinePattern.FindStringSubmatch(line)
+			if match == nil {
+				t.Fatalf("line %d is garbled: %q", start, line)
+			}
+			if tag == "" {
+				tag = match[1]
+			}
+			if match[1] != tag {
+				t.Fatalf("lines of %s are interleaved with %s at line %d", tag, match[1], start)
+			}
+		}
+	}
+}
+
+// initWithClock directs text output of every level to the returned buffer, stamped in location with times returned by now
+func initWithClock(t *testing.T, location *time.Location, now func() time.Time) *bytes.Buffer {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	Close()
+	clock = now
+	InitLogger(Options{Format: FormatText, Level: LevelDebug, Output: &buffer, Color: ColorNever, Location: location})
+	t.Cleanup(func() {
+		Close()
+		clock = time.Now
+		InitLogger(Options{Format: FormatText, Level: LevelInfo, Output: &bytes.Buffer{}, Color: ColorNever, Location: time.UTC})
+	})
+	return &buffer
+}
+
+func TestTextOutputIsStampedWithTimeOfEachMessage(t *testing.T) {
+	current := time.Date(2024, 1, 2, 11, 0, 0, 0, time.UTC)
+	buffer := initWithClock(t, time.UTC, func() time.Time {
+		return current
+	})
+
+	Info("first")
+	Close()
+	current = current.Add(90 * time.Second)
+	PrintPoolSummary(base.PoolStats{Fetches: 1})
+	log.Printf("through log package")
+	Close()
+
+	want := "[01-02-2024 11:00:00] first\n" +
+		"[01-02-2024 11:01:30] <pool> No Rates Fetched In 1 Successful Requests\n" +
+		"[01-02-2024 11:01:30] through log package\n"
+	if got := buffer.String(); got != want {
+		t.Errorf("output =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTextOutputIsStampedInLocation(t *testing.T) {
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	if err != nil {
+		t.Skipf("zone database is not available: %s", err)
+	}
+	buffer := initWithClock(t, warsaw, func() time.Time {
+		return time.Date(2024, 7, 1, 22, 30, 0, 0, time.UTC)
+	})
+
+	Warn("past midnight in Warsaw")
+	PrintReqInfo(ReqInfo{Index: 0, RequestId: "4f2a9c1e", StatusCode: 200, Scope: base.RateBounds{Min: 4.5, Max: 4.7},
+		OutOfScope: []base.OutOfScopeRate{{EffectiveDate: time.Date(2024, 7, 1, 0, 0, 0, 0, warsaw)}}})
+	Close()
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "[07-02-2024 00:30:00] ") {
+			t.Errorf("line %q is not stamped with time in Europe/Warsaw", line)
+		}
+	}
+	if !strings.HasSuffix(lines[len(lines)-1], "in: 2024-07-01") {
+		t.Errorf("out-of-scope date is not rendered in its zone: %q", lines[len(lines)-1])
+	}
+}
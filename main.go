@@ -1,172 +1,477 @@
 package main
 
 import (
-	"bytes"
-	"compress/gzip"
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
+	"context"
+	"errors"
+	"flag"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"spyrosoft-recruitment-task/api"
 	"spyrosoft-recruitment-task/base"
+	"spyrosoft-recruitment-task/export"
 	"spyrosoft-recruitment-task/logger"
-	"sync"
+	"spyrosoft-recruitment-task/marshal"
+	"spyrosoft-recruitment-task/metrics"
+	"spyrosoft-recruitment-task/notify"
+	"spyrosoft-recruitment-task/report"
+	"spyrosoft-recruitment-task/storage"
+	"spyrosoft-recruitment-task/telemetry"
+	"strings"
+	"syscall"
 	"time"
+
+	// zone database for -timezone, the runtime image has none
+	_ "time/tzdata"
 )
 
+const ServerShutdownTimeout = 5 * time.Second
+
+// exit codes of -once runs, so the program can serve as a monitoring check
 const (
-	ApiUrl        = "http://api.nbp.pl/api/exchangerates/rates/a/eur/last/100/"
-	FetchInterval = 5
-	FetchesAmount = 10
+	ExitOk = 0
+	// some rate is out of bounds, only with -fail-on-out-of-scope
+	ExitOutOfScope = 1
+	// some worker of the pool failed, takes precedence over ExitOutOfScope
+	ExitFetchFailed = 2
+	// configuration is invalid or a file or service it names cannot be set up, nothing is fetched
+	ExitInvalidConfig = 3
 )
 
-type IntervalHandler struct {
-	wg     sync.WaitGroup
-	waitCh chan int
+func main() {
+	os.Exit(run(os.Args[1:]))
 }
 
-func main() {
-	logger.InitLogger()
-	var mu sync.Mutex
+// run parses args and runs the program, returning its exit code
+func run(args []string) int {
+	cmd, args := parseCommand(args)
+	cfg, err := cmd.loadConfig(args, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return ExitOk
+	}
+	if err != nil {
+		log.Printf("Failed to load configuration: %s", err)
+		return ExitInvalidConfig
+	}
 
-	for {
-		intervalHandler := &IntervalHandler{sync.WaitGroup{}, make(chan int)}
+	format, err := logger.ParseFormat(cfg.LogFormat)
+	if err != nil {
+		log.Printf("Invalid -log-format: %s", err)
+		return ExitInvalidConfig
+	}
+
+	level, err := logger.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		log.Printf("Invalid -log-level: %s", err)
+		return ExitInvalidConfig
+	}
+
+	color, err := logger.ParseColorMode(cfg.Color)
+	if err != nil {
+		log.Printf("Invalid -color: %s", err)
+		return ExitInvalidConfig
+	}
+
+	location, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		log.Printf("Invalid -timezone: %s", err)
+		return ExitInvalidConfig
+	}
+	marshal.SetLocation(location)
+
+	logger.InitLogger(logger.Options{
+		Format:     format,
+		Level:      level,
+		File:       cfg.LogFile,
+		DateLayout: cfg.DateFormat,
+		Color:      color,
+		Location:   location,
+	})
+	// write out messages still queued for the log writer
+	defer logger.Close()
+
+	err = cfg.validate()
+	if err != nil {
+		log.Printf("Invalid configuration: %s", err)
+		return ExitInvalidConfig
+	}
+
+	targets, err := buildTargets(cfg)
+	if err != nil {
+		log.Printf("Invalid configuration: %s", err)
+		return ExitInvalidConfig
+	}
+
+	if cfg.DryRun {
+		logDryRun(targets)
+		return ExitOk
+	}
+
+	proxyUrl, err := parseProxyUrl(cfg.Proxy)
+	if err != nil {
+		log.Printf("Invalid configuration: %s", err)
+		return ExitInvalidConfig
+	}
+
+	tlsConfig, err := newTlsConfig(cfg.CaFile, cfg.TlsSkipVerify)
+	if err != nil {
+		log.Printf("Invalid configuration: %s", err)
+		return ExitInvalidConfig
+	}
+	if cfg.TlsSkipVerify {
+		logger.Warn("!!! TLS certificate verification is DISABLED by -insecure-skip-verify, use it for testing only !!!")
+	}
+
+	poolCfg := &PoolConfig{
+		Config:  cfg,
+		Targets: targets,
+		Fetcher: &HTTPFetcher{
+			Config:  cfg,
+			Client:  newHttpClient(cfg.Workers, proxyUrl, tlsConfig),
+			Limiter: newRateLimiter(cfg.RateLimit, cfg.Burst),
+			Clock:   realClock{},
+		},
+		AllOutOfScope: api.NewOutOfScopeSet(),
+		Clock:         realClock{location: location},
+	}
 
-		intervalHandler.wg.Add(FetchesAmount)
+	if cfg.InputFile != "" {
+		poolCfg.Fetcher = &FileFetcher{
+			Path:       cfg.InputFile,
+			Format:     cfg.ResponseFormat,
+			Table:      cfg.Table,
+			PriceField: cfg.PriceField,
+		}
+		logger.Info("Reading rates from %s instead of querying the API", cfg.InputFile)
+	}
 
-		//locking mutex to avoid mixing logs from different goroutines
-		mu.Lock()
-		log.Println(" ======== BEGIN REQUESTS POOL ======== ")
-		mu.Unlock()
+	if cfg.Healthcheck {
+		return runHealthcheck(context.Background(), poolCfg)
+	}
 
-		start := time.Now()
-		for i := 0; i < FetchesAmount; i++ {
-			go apiQueryWorker(i, &mu, &intervalHandler.wg)
+	if cfg.BandsFile != "" {
+		poolCfg.Bands, err = loadBandsFile(cfg.BandsFile, cfg.Table)
+		if err != nil {
+			log.Printf("Invalid configuration: %s", err)
+			return ExitInvalidConfig
 		}
+	}
 
-		go func() {
-			// wait until all requests are processed
-			intervalHandler.wg.Wait()
+	if cfg.TripThreshold > 0 {
+		poolCfg.Breaker = newCircuitBreaker(cfg.TripThreshold, cfg.TripCooldown, poolCfg.Clock)
+	}
 
-			//notify end of requests processing
-			close(intervalHandler.waitCh)
-		}()
+	if cfg.CacheTtl == 0 {
+		poolCfg.Cache = newResponseCache(cfg.Interval, poolCfg.Clock)
+	} else if cfg.CacheTtl > 0 {
+		poolCfg.Cache = newResponseCache(cfg.CacheTtl, poolCfg.Clock)
+	}
 
-		select {
-		case <-intervalHandler.waitCh:
-			// sleep until interval makes cycle
-			elapsed := time.Since(start)
-			time.Sleep(FetchInterval*time.Second - elapsed)
-		case <-time.After(FetchInterval * time.Second):
-			log.Println("Timeout, performing next requests group...")
+	// workers run under runCtx, which is only cancelled once -max-runtime passes
+	runCtx := context.Background()
+	if cfg.MaxRuntime > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(runCtx, cfg.MaxRuntime)
+		defer cancel()
+	}
+
+	if cfg.OtelEndpoint != "" {
+		tel, err := telemetry.Start(runCtx, cfg.OtelEndpoint, Version)
+		if err != nil {
+			log.Printf("Failed to start OpenTelemetry export: %s", err)
+			return ExitInvalidConfig
+		}
+		defer shutdownTelemetry(tel)
+		runCtx = telemetry.WithTelemetry(runCtx, tel)
+	}
+
+	// stop scheduling new pools on SIGINT/SIGTERM, in-flight pool is allowed to finish
+	ctx, stop := signal.NotifyContext(runCtx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if poolCfg.Bands != nil {
+		err = poolCfg.Bands.watch(ctx)
+		if err != nil {
+			log.Printf("Failed to watch bands file: %s", err)
+			return ExitInvalidConfig
 		}
+	}
 
-		mu.Lock()
-		log.Println(" ======== END OF REQUESTS POOL ======== ")
-		mu.Unlock()
+	if cfg.OutputCsv != "" {
+		poolCfg.CsvWriter, err = export.NewCsvWriter(cfg.OutputCsv)
+		if err != nil {
+			log.Printf("Failed to create CSV output: %s", err)
+			return ExitInvalidConfig
+		}
 	}
 
-}
+	if cfg.ReportFile != "" {
+		poolCfg.Report = report.NewMarkdownWriter(cfg.ReportFile, cfg.ReportAppend)
+	}
 
-func apiQueryWorker(index int, mu *sync.Mutex, wg *sync.WaitGroup) {
-	defer wg.Done()
-	req, err := prepareHttpRequest()
-	if err != nil {
-		log.Fatalf("Failed to prepare GET request: %s", err)
-		return
+	if cfg.DbPath != "" {
+		poolCfg.Store, err = storage.NewSqliteStore(cfg.DbPath)
+		if err != nil {
+			log.Printf("Failed to open SQLite database: %s", err)
+			return ExitInvalidConfig
+		}
 	}
 
-	client := &http.Client{}
+	poolCfg.Notifier = newNotifier(cfg)
+	if poolCfg.Notifier != nil {
+		poolCfg.Cooldown = newAlertCooldown(cfg.AlertCooldown, poolCfg.Clock)
+	}
 
-	startTime := time.Now()
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Fatalf("Failed to perform GET request: %s", err)
-		return
+	var metricsServer *http.Server
+	if cfg.MetricsAddr != "" {
+		metricsServer = metrics.StartServer(cfg.MetricsAddr)
+	}
+
+	var apiServer *http.Server
+	if cfg.HttpAddr != "" {
+		poolCfg.RatesState = &api.State{History: api.NewHistory(cfg.HistorySize), AllOutOfScope: poolCfg.AllOutOfScope}
+		apiServer = api.StartServer(cfg.HttpAddr, poolCfg.RatesState)
+	}
+
+	exitCode := ExitOk
+	if cfg.Once {
+		var allStats []base.PoolStats
+		allStats, err = runPool(runCtx, poolCfg)
+		if err != nil {
+			logger.Error("Requests pool failed: %s", err)
+			exitCode = ExitFetchFailed
+		} else if cfg.FailOutOfScope && countOutOfScope(allStats) > 0 {
+			logger.Warn("Out of scope rates found, exiting with code %d", ExitOutOfScope)
+			exitCode = ExitOutOfScope
+		}
+
+		// nothing scrapes a single pool run, so its metrics are pushed instead
+		if cfg.PushgatewayUrl != "" {
+			err = metrics.Push(cfg.PushgatewayUrl, cfg.PushgatewayJob)
+			if err != nil {
+				logger.Error("Failed to push metrics to Pushgateway: %s", err)
+			}
+		}
+	} else {
+		runLoop(ctx, runCtx, poolCfg)
 	}
 
-	elapsed := time.Since(startTime)
+	// wait for workers of a timed out pool so none of them outlives main
+	poolCfg.pending.Wait()
+
+	logAllOutOfScope(poolCfg.AllOutOfScope.All(), len(targets) > 1)
 
-	defer func() {
-		err := resp.Body.Close()
+	if metricsServer != nil {
+		err = metrics.Shutdown(metricsServer, ServerShutdownTimeout)
 		if err != nil {
-			log.Fatalf("Failed to close response body: %s", err)
-			return
+			logger.Error("Failed to shut down metrics server: %s", err)
 		}
-	}()
+	}
 
-	statusCode := resp.StatusCode
-	contentType := resp.Header.Get("Content-Type")
+	if apiServer != nil {
+		err = api.Shutdown(apiServer, ServerShutdownTimeout)
+		if err != nil {
+			logger.Error("Failed to shut down rates API server: %s", err)
+		}
+	}
 
-	// read gzip byte stream and decompress it into readable JSON
-	content, err := decompressGzippedResponse(resp)
-	if err != nil {
-		log.Fatalf("Failed to read compressed body content: %s", err)
-		return
+	if poolCfg.CsvWriter != nil {
+		err = poolCfg.CsvWriter.Close()
+		if err != nil {
+			logger.Error("Failed to close CSV output: %s", err)
+		}
+	}
+
+	if poolCfg.Store != nil {
+		err = poolCfg.Store.Close()
+		if err != nil {
+			logger.Error("Failed to close SQLite database: %s", err)
+		}
 	}
 
-	isJsonValid := json.Valid(content)
+	return exitCode
+}
 
-	var summary base.ExchangeRatesSummary
+// shutdownTelemetry flushes spans and metrics not exported yet, giving the collector ServerShutdownTimeout to take them
+func shutdownTelemetry(tel *telemetry.Telemetry) {
+	ctx, cancel := context.WithTimeout(context.Background(), ServerShutdownTimeout)
+	defer cancel()
 
-	err = json.Unmarshal(content, &summary)
+	err := tel.Shutdown(ctx)
 	if err != nil {
-		log.Fatalf("Failed to unmarshall request content: %s", err)
-		return
+		logger.Error("Failed to shut down OpenTelemetry export: %s", err)
 	}
+}
 
-	var rateOutOfScope []string
+// runHealthcheck fetches every target once and logs OK or FAIL of each,
+// exit code is ExitFetchFailed when any fetch failed
+func runHealthcheck(ctx context.Context, cfg *PoolConfig) int {
+	exitCode := ExitOk
+	for _, t := range cfg.Targets {
+		fetchCtx, cancel := context.WithTimeout(ctx, cfg.RequestTimeout)
+		fetched, err := fetchTarget(fetchCtx, 0, cfg, t)
+		cancel()
+		if errors.Is(err, ErrNoDataToday) {
+			logger.Info("Healthcheck OK %s: no rates published today", t.ApiUrl)
+			continue
+		}
+		if err != nil {
+			logger.Error("Healthcheck FAIL %s: %s", t.ApiUrl, err)
+			exitCode = ExitFetchFailed
+			continue
+		}
 
-	for _, item := range summary.Rates {
-		if item.Mid < 4.5 || item.Mid > 4.7 {
-			day, month, year := item.EffectiveDate.Day(), item.EffectiveDate.Month(), item.EffectiveDate.Year()
-			date := fmt.Sprintf("%d/%d/%d", day, month, year)
-			rateOutOfScope = append(rateOutOfScope, date)
+		newest := "unknown"
+		if date, ok := fetched.summary.Newest(); ok {
+			newest = date.Format("2006-01-02")
 		}
+		logger.Info("Healthcheck OK %s: HTTP %d in %d ms, %d rates, newest from %s", t.ApiUrl, fetched.statusCode, fetched.elapsed.Milliseconds(), len(fetched.summary.Rates), newest)
+	}
+	return exitCode
+}
+
+// newNotifier returns notifier selected by -notifier, or nil when notifications are disabled,
+// for compatibility -webhook-url alone enables the webhook one
+func newNotifier(cfg Config) notify.Notifier {
+	kind := cfg.Notifier
+	if kind == "" && cfg.WebhookUrl != "" {
+		kind = notify.KindWebhook
 	}
 
-	//locking mutex to avoid mixing logs from different goroutines
-	mu.Lock()
-	logger.PrintReqInfo(index, elapsed, statusCode, contentType, isJsonValid, rateOutOfScope)
-	mu.Unlock()
+	switch kind {
+	case notify.KindWebhook:
+		return notify.NewWebhook(cfg.WebhookUrl, notify.DefaultTimeout)
+	case notify.KindSlack:
+		return notify.NewSlack(cfg.WebhookUrl, notify.DefaultTimeout)
+	case notify.KindEmail:
+		return notify.NewEmail(cfg.SmtpAddr, cfg.SmtpFrom, parseAddresses(cfg.SmtpTo), cfg.SmtpUsername, cfg.SmtpPassword, notify.DefaultTimeout)
+	default:
+		return nil
+	}
 }
 
-func prepareHttpRequest() (*http.Request, error) {
-	req, err := http.NewRequest("GET", ApiUrl, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to prepare HTTP GET request: %s", err)
+// parseAddresses splits comma separated email addresses, empty entries are skipped
+func parseAddresses(value string) []string {
+	var addresses []string
+	for _, address := range strings.Split(value, ",") {
+		address = strings.TrimSpace(address)
+		if address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
+// logDryRun logs the requests workers would send for every target, without sending them
+func logDryRun(targets []*target) {
+	for _, t := range targets {
+		req := t.request
+
+		host := req.Host
+		if host == "" {
+			host = req.URL.Host
+		}
+
+		logger.Info("Dry run, request is not sent: %s %s", req.Method, req.URL)
+		logger.Info("Host: %s", host)
+		logger.Info("Headers:%s", formatHeaders(req.Header))
 	}
+}
+
+// runLoop starts a requests pool every interval until ctx is cancelled,
+// pools run under runCtx, so cancelling only ctx lets in-flight requests finish
+func runLoop(ctx context.Context, runCtx context.Context, cfg *PoolConfig) {
+	scheduler := newAdaptiveInterval(cfg.Interval, cfg.MaxInterval)
+
+	var pools, failedPools int
+	for {
+		start := cfg.Clock.Now()
+
+		// failures are already logged by the workers, loop just goes on with the next pool
+		allStats, err := runPool(runCtx, cfg)
+		pools++
+		if err != nil {
+			failedPools++
+		}
+
+		elapsed := cfg.Clock.Now().Sub(start)
+		logger.Debug("Goroutines after pool: %d", runtime.NumGoroutine())
+		newest := newestOf(allStats)
+		interval := scheduler.next(newest)
+		if interval != cfg.Interval {
+			logger.Debug("Newest rate unchanged since %s, next pool in %s", newest.Format("2006-01-02"), interval)
+		}
 
-	addHeaders(req)
+		sleep, overrun := scheduleNext(interval, elapsed)
+		if overrun {
+			metrics.IncPoolOverruns()
 
-	return req, nil
+			// timed out pool has already reported itself
+			if !errors.Is(err, ErrPoolTimeout) {
+				logger.Warn("Requests pool overran interval by %s, starting next pool immediately", elapsed-interval)
+			}
+		}
+
+		// sleep until interval makes cycle or shutdown is requested
+		select {
+		case <-ctx.Done():
+			if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+				logger.Info("Max runtime of %s reached, shutting down...", cfg.MaxRuntime)
+				logger.Info("Ran %d requests pools, %d of them failed", pools, failedPools)
+			} else {
+				logger.Info("Shutdown signal received, shutting down...")
+			}
+			return
+		case <-cfg.Clock.After(sleep):
+		}
+	}
 }
 
-func addHeaders(req *http.Request) {
-	req.Header.Set("Host", "api.nbp.pl")
-	req.Header.Set("User-Agent", "Golang Program")
-	req.Header.Set("Accept-Language", "pl-PL,pl;q=0.9,en-US;q=0.8,en;q=0.7")
+// logAllOutOfScope logs distinct out-of-scope dates since startup, currencies are only told when there are more
+func logAllOutOfScope(all []api.OutOfScopeDate, withCurrency bool) {
+	if len(all) == 0 {
+		return
+	}
 
-	//gzip encoding results in a much smaller response body
-	req.Header.Set("Accept-Encoding", "deflate, gzip")
+	dates := make([]string, 0, len(all))
+	for _, date := range all {
+		if withCurrency {
+			dates = append(dates, date.Date+" "+strings.ToUpper(date.Currency))
+		} else {
+			dates = append(dates, date.Date)
+		}
+	}
+	logger.Info("Out of scope dates since startup: %s", strings.Join(dates, "; "))
 }
 
-func decompressGzippedResponse(response *http.Response) ([]byte, error) {
-	gzipBytes, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read compressed body content: %s", err)
+// countOutOfScope returns number of out-of-scope dates of all targets
+func countOutOfScope(allStats []base.PoolStats) int {
+	var count int
+	for _, stats := range allStats {
+		count += stats.OutOfScope
 	}
+	return count
+}
 
-	bytesReader := bytes.NewReader(gzipBytes)
-	gzipReader, err := gzip.NewReader(bytesReader)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create gzip reader: %s", err)
+// newestOf returns the latest effective date among stats of all targets, zero when none is known
+func newestOf(allStats []base.PoolStats) time.Time {
+	var newest time.Time
+	for _, stats := range allStats {
+		if stats.Newest.After(newest) {
+			newest = stats.Newest
+		}
 	}
+	return newest
+}
 
-	content, err := ioutil.ReadAll(gzipReader)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read compressed body content: %s", err)
+// scheduleNext returns how long to wait before starting the next pool,
+// a pool which took longer than the interval overran it and the next one starts immediately
+func scheduleNext(interval, elapsed time.Duration) (time.Duration, bool) {
+	if elapsed >= interval {
+		return 0, elapsed > interval
 	}
 
-	return content, nil
+	return interval - elapsed, false
 }
@@ -0,0 +1,627 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"spyrosoft-recruitment-task/api"
+	"spyrosoft-recruitment-task/base"
+	"spyrosoft-recruitment-task/logger"
+	"spyrosoft-recruitment-task/notify"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testSummaryJson = `{"table":"A","currency":"euro","code":"EUR","rates":[` +
+	`{"no":"001/A/NBP/2024","effectiveDate":"2024-01-02","mid":4.6}]}`
+
+var testBounds = base.RateBounds{Min: DefaultRateMin, Max: DefaultRateMax}
+
+// testRateDate is the effective date of the first rate of summaryJson bodies
+var testRateDate = time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+// summaryJson returns NBP table A response of currency code with rates of mids effective on consecutive days from testRateDate
+func summaryJson(code string, mids ...float64) string {
+	type rate struct {
+		No            string  `json:"no"`
+		EffectiveDate string  `json:"effectiveDate"`
+		Mid           float64 `json:"mid"`
+	}
+
+	rates := make([]rate, 0, len(mids))
+	for i, mid := range mids {
+		rates = append(rates, rate{
+			No:            fmt.Sprintf("%03d/A/NBP/2024", i+1),
+			EffectiveDate: testRateDate.AddDate(0, 0, i).Format(DateLayout),
+			Mid:           mid,
+		})
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"table":    "A",
+		"currency": strings.ToLower(code),
+		"code":     strings.ToUpper(code),
+		"rates":    rates,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return string(body)
+}
+
+// testSummary returns summaryJson decoded like a response
+func testSummary(code string, mids ...float64) base.ExchangeRatesSummary {
+	summary, err := base.ParseSummary(strings.NewReader(summaryJson(code, mids...)))
+	if err != nil {
+		panic(err)
+	}
+	return summary
+}
+
+// fetcherFunc is Fetcher calling a function, e.g. one serving rates from memory
+type fetcherFunc func(ctx context.Context, currency string, count int) (base.ExchangeRatesSummary, error)
+
+func (f fetcherFunc) Fetch(ctx context.Context, currency string, count int) (base.ExchangeRatesSummary, error) {
+	return f(ctx, currency, count)
+}
+
+// workerFetcherFunc is a fake fetcher answering each worker by its index, which Fetch alone does not get
+type workerFetcherFunc func(ctx context.Context, index int, t *target) (*fetchResult, error)
+
+func (f workerFetcherFunc) Fetch(ctx context.Context, currency string, count int) (base.ExchangeRatesSummary, error) {
+	result, err := f(ctx, 0, &target{Currency: currency})
+	if err != nil {
+		return base.ExchangeRatesSummary{}, err
+	}
+	return result.summary, nil
+}
+
+func (f workerFetcherFunc) fetchTarget(ctx context.Context, index int, t *target) (*fetchResult, error) {
+	return f(ctx, index, t)
+}
+
+// roundTripperFunc is http.RoundTripper calling a function, e.g. one failing requests without any server
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// gzipResponse returns response of gzip compressed body, the way NBP answers
+func gzipResponse(body string) *http.Response {
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	io.WriteString(w, body)
+	w.Close()
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json; charset=utf-8"}, "Content-Encoding": {"gzip"}},
+		Body:       io.NopCloser(&compressed),
+	}
+}
+
+// setDefaultTransport makes clients without a transport of their own send requests through rt until the test ends
+func setDefaultTransport(t *testing.T, rt http.RoundTripper) {
+	t.Helper()
+
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = rt
+	t.Cleanup(func() {
+		http.DefaultTransport = defaultTransport
+	})
+}
+
+// testApiUrl is URL of EUR rates, requests never reach it as tests replace the transport
+var testApiUrl, _ = buildApiUrl(Config{ApiBaseUrl: DefaultApiBaseUrl, Table: base.TableA, Currency: DefaultCurrency, Count: DefaultCount})
+
+// newTestPoolConfig returns configuration of pools of given number of workers fetching a single currency from apiUrl,
+// failed requests are not retried
+func newTestPoolConfig(workers int, apiUrl string) *PoolConfig {
+	cfg := Config{
+		Table:          base.TableA,
+		PriceField:     base.PriceBid,
+		Currency:       DefaultCurrency,
+		Count:          DefaultCount,
+		Workers:        workers,
+		RequestTimeout: DefaultRequestTimeout,
+		MaxBodyBytes:   DefaultMaxBodyBytes,
+		Bounds:         testBounds,
+		Interval:       DefaultInterval,
+	}
+
+	return &PoolConfig{
+		Config:        cfg,
+		Targets:       []*target{{Currency: DefaultCurrency, ApiUrl: apiUrl, Bounds: testBounds, request: testRequest(apiUrl, requestOptions{})}},
+		Fetcher:       &HTTPFetcher{Config: cfg, Client: &http.Client{}, Limiter: newRateLimiter(0, 1), Clock: realClock{}},
+		AllOutOfScope: api.NewOutOfScopeSet(),
+		Clock:         realClock{},
+	}
+}
+
+// testRequest returns request of apiUrl as buildTargets prepares it, URLs of tests are always valid
+func testRequest(apiUrl string, opts requestOptions) *http.Request {
+	req, err := prepareHttpRequest(context.Background(), apiUrl, opts)
+	if err != nil {
+		panic(err)
+	}
+	return req
+}
+
+// httpFetcher returns the fetcher of a pool config made by newTestPoolConfig
+func httpFetcher(cfg *PoolConfig) *HTTPFetcher {
+	return cfg.Fetcher.(*HTTPFetcher)
+}
+
+// summaryFetch returns fetch of a single worker of the only target of cfg performing its own API request
+func summaryFetch(cfg *PoolConfig) fetchFunc {
+	return func(ctx context.Context, index int) (*fetchResult, error) {
+		return httpFetcher(cfg).fetchTarget(ctx, index, cfg.Targets[0])
+	}
+}
+
+// runWorkers runs a pool of given number of workers checking rates against bounds and waits for all of them
+func runWorkers(workers int, bounds base.RateBounds) {
+	cfg := newTestPoolConfig(workers, testApiUrl)
+	cfg.Targets[0].Bounds = bounds
+	runPool(context.Background(), cfg)
+}
+
+// captureLog directs log output of level and above to the returned buffer until the test ends
+func captureLog(t *testing.T, level logger.Level) *logBuffer {
+	t.Helper()
+
+	buffer := &logBuffer{}
+	initTestLogger(level, buffer)
+	t.Cleanup(func() {
+		initTestLogger(logger.LevelError, io.Discard)
+	})
+	return buffer
+}
+
+// initTestLogger restarts the logger writing text output of level and above to w
+func initTestLogger(level logger.Level, w io.Writer) {
+	logger.Close()
+	logger.InitLogger(logger.Options{Format: logger.FormatText, Level: level, Output: w, Color: logger.ColorNever})
+}
+
+// logBuffer is log output captured by captureLog, safe for the writer and direct writes alike
+type logBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (l *logBuffer) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.b.Write(p)
+}
+
+// String flushes messages queued for the log writer and returns everything logged so far
+func (l *logBuffer) String() string {
+	logger.Close()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.b.String()
+}
+
+// compressBody returns body compressed according to Content-Encoding, "raw-deflate" is deflate without zlib wrapper
+func compressBody(encoding string, body string) []byte {
+	var compressed bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&compressed)
+	case "deflate":
+		w = zlib.NewWriter(&compressed)
+	case "raw-deflate":
+		w, _ = flate.NewWriter(&compressed, flate.DefaultCompression)
+	default:
+		return []byte(body)
+	}
+
+	io.WriteString(w, body)
+	w.Close()
+	return compressed.Bytes()
+}
+
+// newEncodedNbpServer returns server answering every request with JSON body compressed according to encoding,
+// closed once the test ends
+func newEncodedNbpServer(t *testing.T, encoding string, body string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if encoding != "" {
+			w.Header().Set("Content-Encoding", strings.TrimPrefix(encoding, "raw-"))
+		}
+		w.Write(compressBody(encoding, body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRunLoopStopsOnShutdownSignal(t *testing.T) {
+	requests := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- struct{}{}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		io.WriteString(w, testSummaryJson)
+	}))
+	defer server.Close()
+	output := captureLog(t, logger.LevelInfo)
+
+	// ctx stands for the context of signal.NotifyContext, cancelled by SIGINT or SIGTERM
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runLoop(ctx, context.Background(), newTestPoolConfig(1, server.URL))
+	}()
+
+	<-requests
+	cancel()
+
+	// the loop returns without waiting for the rest of the interval
+	select {
+	case <-done:
+	case <-time.After(DefaultInterval / 2):
+		t.Fatal("runLoop() did not return once its context was cancelled")
+	}
+	if len(requests) != 0 {
+		t.Errorf("%d requests after shutdown, want none", len(requests))
+	}
+	if !strings.Contains(output.String(), "Shutdown signal received") {
+		t.Errorf("shutdown is not logged:\n%s", output.String())
+	}
+}
+
+func TestRunLoopStartsPoolEveryInterval(t *testing.T) {
+	requests := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- struct{}{}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		io.WriteString(w, testSummaryJson)
+	}))
+	defer server.Close()
+	captureLog(t, logger.LevelInfo)
+
+	cfg := newTestPoolConfig(1, server.URL)
+	cfg.Interval = 10 * time.Second
+	clock := newFakeClock(time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC))
+	cfg.Clock = clock
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runLoop(ctx, context.Background(), cfg)
+	}()
+
+	<-requests
+	// timeout of the first pool and the wait for the next one
+	clock.BlockUntil(2)
+	if deadlines := clock.Deadlines(); deadlines[1] != 10*time.Second {
+		t.Fatalf("next pool waits for %s, want the full -interval of 10s as the pool took no time", deadlines[1])
+	}
+
+	clock.Advance(10*time.Second - time.Nanosecond)
+	select {
+	case <-requests:
+		t.Fatal("second pool started before -interval passed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	clock.Advance(time.Nanosecond)
+	<-requests
+
+	cancel()
+	<-done
+}
+
+func TestRunLoopLetsInFlightPoolFinishOnShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		io.WriteString(w, testSummaryJson)
+	}))
+	defer server.Close()
+	output := captureLog(t, logger.LevelInfo)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runLoop(ctx, context.Background(), newTestPoolConfig(1, server.URL))
+	}()
+
+	<-started
+	cancel()
+	select {
+	case <-done:
+		t.Fatal("runLoop() returned before its in-flight pool finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(DefaultInterval / 2):
+		t.Fatal("runLoop() did not return once its in-flight pool finished")
+	}
+	// request of the in-flight pool is not cancelled by shutdown
+	if content := output.String(); strings.Contains(content, "Fetch failed") || !strings.Contains(content, "HTTP Status Code: 200") {
+		t.Errorf("in-flight request did not complete:\n%s", content)
+	}
+}
+
+func TestRunLoopGoesOnAfterSlowPool(t *testing.T) {
+	requests := make(chan struct{}, 10)
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- struct{}{}
+		// the first request outlasts the interval, so its pool times out
+		if atomic.AddInt32(&calls, 1) == 1 {
+			time.Sleep(300 * time.Millisecond)
+		}
+		io.WriteString(w, testSummaryJson)
+	}))
+	defer server.Close()
+	output := captureLog(t, logger.LevelInfo)
+
+	cfg := newTestPoolConfig(1, server.URL)
+	cfg.Interval = 100 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runLoop(ctx, context.Background(), cfg)
+	}()
+
+	<-requests
+	// pool took the whole interval, so the next one starts without waiting for another tick
+	select {
+	case <-requests:
+	case <-time.After(5 * time.Second):
+		t.Fatal("next pool did not start after the slow one")
+	}
+
+	cancel()
+	<-done
+	cfg.pending.Wait()
+	if !strings.Contains(output.String(), "Timeout, performing next requests group...") {
+		t.Errorf("slow pool is not warned about:\n%s", output.String())
+	}
+}
+
+func TestRunLoopStopsInFlightPoolAtMaxRuntime(t *testing.T) {
+	// the only request is answered once it is cancelled, long after -max-runtime
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	output := captureLog(t, logger.LevelInfo)
+
+	cfg := newTestPoolConfig(1, server.URL)
+	cfg.Interval = time.Hour
+	cfg.MaxRuntime = 200 * time.Millisecond
+	// as run does, the shutdown context is derived from the one cancelled at -max-runtime
+	runCtx, cancel := context.WithTimeout(context.Background(), cfg.MaxRuntime)
+	defer cancel()
+
+	start := time.Now()
+	runLoop(runCtx, runCtx, cfg)
+	elapsed := time.Since(start)
+
+	if elapsed < cfg.MaxRuntime || elapsed > cfg.MaxRuntime+2*time.Second {
+		t.Errorf("runLoop() returned after %s, want shortly after -max-runtime of %s", elapsed, cfg.MaxRuntime)
+	}
+	for _, want := range []string{"Max runtime of 200ms reached", "Ran 1 requests pools, 1 of them failed"} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("log is missing final summary %q:\n%s", want, output.String())
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	tests := []struct {
+		name        string
+		elapsed     time.Duration
+		wantSleep   time.Duration
+		wantOverrun bool
+	}{
+		{"fast pool", 2 * time.Second, 8 * time.Second, false},
+		{"pool of exactly the interval", 10 * time.Second, 0, false},
+		{"pool overrunning the interval", 13 * time.Second, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sleep, overrun := scheduleNext(10*time.Second, tt.elapsed)
+			if sleep != tt.wantSleep || overrun != tt.wantOverrun {
+				t.Errorf("scheduleNext(10s, %s) = %s, %t, want %s, %t", tt.elapsed, sleep, overrun, tt.wantSleep, tt.wantOverrun)
+			}
+		})
+	}
+}
+
+func TestLogDryRunLogsRequestWithoutSendingIt(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	}))
+	defer server.Close()
+	targets, err := buildTargets(Config{ApiBaseUrl: server.URL + "/", Table: base.TableA, Currencies: "usd,chf", Count: DefaultCount, ResponseFormat: ResponseFormatXml})
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := captureLog(t, logger.LevelInfo)
+
+	logDryRun(targets)
+
+	if got := atomic.LoadInt32(&requests); got != 0 {
+		t.Errorf("dry run sent %d requests, want none", got)
+	}
+	for _, want := range []string{
+		// a request of every currency
+		"Dry run, request is not sent: GET " + targets[0].ApiUrl,
+		"Dry run, request is not sent: GET " + targets[1].ApiUrl,
+		"Host: " + server.Listener.Addr().String(),
+		"  Accept: application/xml",
+	} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("log is missing %q:\n%s", want, output.String())
+		}
+	}
+}
+
+func TestRunExitCodes(t *testing.T) {
+	inBand := newEncodedNbpServer(t, "", summaryJson("eur", 4.55, 4.6))
+	outOfScope := newEncodedNbpServer(t, "", summaryJson("eur", 4.55, 4.8))
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(failing.Close)
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"all rates within bounds", []string{"-api-base-url", inBand.URL}, ExitOk},
+		{"out-of-scope rate", []string{"-api-base-url", outOfScope.URL}, ExitOutOfScope},
+		{"failed fetch", []string{"-api-base-url", failing.URL, "-max-retries", "0"}, ExitFetchFailed},
+		{"invalid configuration", []string{"-api-base-url", inBand.URL, "-workers", "0"}, ExitInvalidConfig},
+		{"unknown flag", []string{"-no-such-flag"}, ExitInvalidConfig},
+		{"dry run", []string{"-api-base-url", inBand.URL, "-dry-run"}, ExitOk},
+		{"unknown time zone", []string{"-api-base-url", inBand.URL, "-timezone", "Europe/Atlantis"}, ExitInvalidConfig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"-once", "-workers", "1", "-rate-min", "4.5", "-rate-max", "4.7", "-fail-on-out-of-scope",
+				"-log-level", "error", "-color", "never", "-log-file", t.TempDir() + "/log.txt"}, tt.args...)
+
+			// run initializes logger on its own, test one is restored once it is done
+			logger.Close()
+			got := run(args)
+			initTestLogger(logger.LevelError, io.Discard)
+
+			if got != tt.want {
+				t.Errorf("run(%q) = %d, want %d", args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunHealthcheckExitCode(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}))
+	t.Cleanup(failing.Close)
+
+	tests := []struct {
+		name   string
+		server *httptest.Server
+		want   int
+		// line expected in log of the healthcheck
+		wantLog string
+	}{
+		{"good response", newEncodedNbpServer(t, "", summaryJson("eur", 4.55, 4.6)), ExitOk, "Healthcheck OK"},
+		{"status 500", failing, ExitFetchFailed, "Healthcheck FAIL"},
+		{"response of wrong shape", newEncodedNbpServer(t, "", `{"table":"A","rates":"none"}`), ExitFetchFailed, "Healthcheck FAIL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logFile := filepath.Join(t.TempDir(), "log.txt")
+			args := []string{"-healthcheck", "-max-retries", "0", "-api-base-url", tt.server.URL, "-color", "never", "-log-file", logFile}
+
+			logger.Close()
+			code := run(args)
+			initTestLogger(logger.LevelError, io.Discard)
+
+			if code != tt.want {
+				t.Errorf("run(%q) = %d, want %d", args, code, tt.want)
+			}
+			content, err := os.ReadFile(logFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(content), tt.wantLog) {
+				t.Errorf("log of %q has no %q:\n%s", args, tt.wantLog, content)
+			}
+		})
+	}
+}
+
+func TestRunFailsStartupOfRequestWhichCouldNeverBeSent(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	}))
+	t.Cleanup(server.Close)
+	logFile := filepath.Join(t.TempDir(), "log.txt")
+	args := []string{"-api-base-url", server.URL, "-user-agent", "rates\x7f", "-interval", "10ms",
+		"-color", "never", "-log-file", logFile}
+
+	logger.Close()
+	code := run(args)
+	initTestLogger(logger.LevelError, io.Discard)
+
+	if code != ExitInvalidConfig {
+		t.Fatalf("run(%q) = %d, want %d", args, code, ExitInvalidConfig)
+	}
+	if got := atomic.LoadInt32(&requests); got != 0 {
+		t.Errorf("%d requests were sent, want startup to fail before any pool", got)
+	}
+	content, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "Invalid configuration:") || !strings.Contains(string(content), "-user-agent") {
+		t.Errorf("log =\n%s\nwant invalid -user-agent reported", content)
+	}
+}
+
+func TestNewNotifier(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want notify.Notifier
+	}{
+		{"disabled", nil, nil},
+		{"webhook url alone", []string{"-webhook-url", "http://example.com/hook"}, notify.NewWebhook("http://example.com/hook", notify.DefaultTimeout)},
+		{"webhook", []string{"-notifier", "webhook", "-webhook-url", "http://example.com/hook"}, notify.NewWebhook("http://example.com/hook", notify.DefaultTimeout)},
+		{"slack", []string{"-notifier", "slack", "-webhook-url", "http://example.com/hook"}, notify.NewSlack("http://example.com/hook", notify.DefaultTimeout)},
+		{"email", []string{"-notifier", "email", "-smtp-addr", "mail.example.com:25", "-smtp-from", "rates@example.com", "-smtp-to", "ops@example.com, ,fx@example.com"},
+			notify.NewEmail("mail.example.com:25", "rates@example.com", []string{"ops@example.com", "fx@example.com"}, "", "", notify.DefaultTimeout)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newNotifier(loadTestConfig(t, tt.args...))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newNotifier() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
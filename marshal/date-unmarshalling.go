@@ -1,10 +1,21 @@
 package marshal
 
 import (
+	"encoding/xml"
 	"strings"
 	"time"
 )
 
+// location effective dates are midnights of, NBP publishes dates of Warsaw, but they are taken as ones of -timezone
+var location = time.UTC
+
+// SetLocation sets zone of parsed dates, it must be called before any date is parsed
+func SetLocation(loc *time.Location) {
+	location = loc
+}
+
+// CustomTime is an NBP effective date, sent as date-only "2006-01-02" in both JSON and XML,
+// which the default time.Time unmarshalling would reject as not RFC 3339
 type CustomTime struct {
 	time.Time
 }
@@ -15,6 +26,16 @@ func (ct *CustomTime) UnmarshalJSON(b []byte) (err error) {
 		ct.Time = time.Time{}
 		return
 	}
-	ct.Time, err = time.Parse("2006-01-02", s)
+	ct.Time, err = time.ParseInLocation("2006-01-02", s, location)
+	return
+}
+
+func (ct *CustomTime) UnmarshalXML(d *xml.Decoder, start xml.StartElement) (err error) {
+	var s string
+	err = d.DecodeElement(&s, &start)
+	if err != nil {
+		return
+	}
+	ct.Time, err = time.ParseInLocation("2006-01-02", strings.TrimSpace(s), location)
 	return
 }
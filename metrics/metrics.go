@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"spyrosoft-recruitment-task/logger"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// DefaultPushJob is the job label of metrics pushed to Pushgateway
+const DefaultPushJob = "nbp_api_query_worker"
+
+var (
+	fetchesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "nbp_fetches_total",
+		Help: "Total number of performed NBP API fetches.",
+	})
+
+	fetchFailuresTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "nbp_fetch_failures_total",
+		Help: "Total number of failed NBP API fetches.",
+	})
+
+	invalidJsonTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "nbp_invalid_json_total",
+		Help: "Total number of NBP API responses which were not valid JSON.",
+	})
+
+	outOfScopeRatesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "nbp_out_of_scope_rates_total",
+		Help: "Total number of fetched rates with mid out of the configured bounds.",
+	})
+
+	slowResponsesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "nbp_slow_responses_total",
+		Help: "Total number of NBP API responses slower than the latency SLA.",
+	})
+
+	poolOverrunsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "nbp_pool_overruns_total",
+		Help: "Total number of requests pools which took longer than the fetch interval.",
+	})
+
+	requestDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "nbp_request_duration_seconds",
+		Help:    "Latency of NBP API requests.",
+		Buckets: prometheus.DefBuckets,
+	})
+)
+
+func collectors() []prometheus.Collector {
+	return []prometheus.Collector{fetchesTotal, fetchFailuresTotal, invalidJsonTotal, outOfScopeRatesTotal, slowResponsesTotal, poolOverrunsTotal, requestDuration}
+}
+
+func init() {
+	prometheus.MustRegister(collectors()...)
+}
+
+func IncFetches() {
+	fetchesTotal.Inc()
+}
+
+func IncFetchFailures() {
+	fetchFailuresTotal.Inc()
+}
+
+func IncInvalidJson() {
+	invalidJsonTotal.Inc()
+}
+
+func AddOutOfScopeRates(count int) {
+	outOfScopeRatesTotal.Add(float64(count))
+}
+
+func IncSlowResponses() {
+	slowResponsesTotal.Inc()
+}
+
+func IncPoolOverruns() {
+	poolOverrunsTotal.Inc()
+}
+
+func ObserveRequestDuration(elapsed time.Duration) {
+	requestDuration.Observe(elapsed.Seconds())
+}
+
+// NewHandler serves all metrics at /metrics
+func NewHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return mux
+}
+
+// StartServer exposes /metrics on given address in background, returned server should be shut down with Shutdown
+func StartServer(addr string) *http.Server {
+	server := &http.Server{Addr: addr, Handler: NewHandler()}
+
+	go func() {
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Metrics server failed: %s", err)
+		}
+	}()
+
+	return server
+}
+
+func Shutdown(server *http.Server, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return server.Shutdown(ctx)
+}
+
+// Push sends current values of all metrics to Pushgateway at url under given job label, replacing ones pushed before
+func Push(url string, job string) error {
+	pusher := push.New(url, job)
+	for _, collector := range collectors() {
+		pusher = pusher.Collector(collector)
+	}
+	return pusher.Push()
+}
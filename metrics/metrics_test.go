@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandlerExposesAllMetrics(t *testing.T) {
+	IncFetches()
+	IncFetchFailures()
+	AddOutOfScopeRates(2)
+	ObserveRequestDuration(120 * time.Millisecond)
+
+	server := httptest.NewServer(NewHandler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /metrics returned %d, want 200", resp.StatusCode)
+	}
+	for _, name := range []string{
+		"nbp_fetches_total",
+		"nbp_fetch_failures_total",
+		"nbp_out_of_scope_rates_total 2",
+		"nbp_request_duration_seconds_count",
+	} {
+		if !strings.Contains(string(body), name) {
+			t.Errorf("/metrics is missing %s", name)
+		}
+	}
+}
+
+func TestPushSendsMetricsUnderJob(t *testing.T) {
+	type push struct {
+		method string
+		path   string
+		body   string
+	}
+	pushes := make(chan push, 1)
+	pushgateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		pushes <- push{r.Method, r.URL.Path, string(body)}
+	}))
+	defer pushgateway.Close()
+	IncFetches()
+	ObserveRequestDuration(120 * time.Millisecond)
+
+	if err := Push(pushgateway.URL, "nbp_batch"); err != nil {
+		t.Fatalf("Push() failed: %s", err)
+	}
+
+	select {
+	case got := <-pushes:
+		if got.method != http.MethodPut || got.path != "/metrics/job/nbp_batch" {
+			t.Errorf("push request = %s %s, want PUT /metrics/job/nbp_batch", got.method, got.path)
+		}
+		// metrics of the scrape endpoint are pushed
+		for _, name := range []string{"nbp_fetches_total", "nbp_request_duration_seconds"} {
+			if !strings.Contains(got.body, name) {
+				t.Errorf("pushed metrics are missing %s", name)
+			}
+		}
+	default:
+		t.Fatal("no metrics were pushed to Pushgateway")
+	}
+}
@@ -0,0 +1,115 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Email sends out-of-scope rates as a plain-text email through an SMTP server,
+// STARTTLS is used whenever the server offers it
+type Email struct {
+	// host:port of the SMTP server
+	addr    string
+	from    string
+	to      []string
+	auth    smtp.Auth
+	timeout time.Duration
+}
+
+// NewEmail returns email notifier, PLAIN authentication is used only when username is not empty
+func NewEmail(addr string, from string, to []string, username string, password string, timeout time.Duration) *Email {
+	e := &Email{addr: addr, from: from, to: to, timeout: timeout}
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		e.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return e
+}
+
+// Notify sends a single email listing rates ordered by effective date, nothing is sent when there are none
+func (n *Email) Notify(ctx context.Context, event OutOfScopeEvent) error {
+	if len(event.Rates) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %s", err)
+	}
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	host, _, _ := net.SplitHostPort(n.addr)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to greet SMTP server: %s", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: host})
+		if err != nil {
+			return fmt.Errorf("failed to start TLS with SMTP server: %s", err)
+		}
+	}
+
+	if n.auth != nil {
+		err = client.Auth(n.auth)
+		if err != nil {
+			return fmt.Errorf("failed to authenticate to SMTP server: %s", err)
+		}
+	}
+
+	err = client.Mail(n.from)
+	if err != nil {
+		return fmt.Errorf("SMTP server rejected sender %s: %s", n.from, err)
+	}
+	for _, to := range n.to {
+		err = client.Rcpt(to)
+		if err != nil {
+			return fmt.Errorf("SMTP server rejected recipient %s: %s", to, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start email data: %s", err)
+	}
+	_, err = w.Write(n.message(event))
+	if err != nil {
+		return fmt.Errorf("failed to write email: %s", err)
+	}
+	err = w.Close()
+	if err != nil {
+		return fmt.Errorf("SMTP server rejected the email: %s", err)
+	}
+
+	return client.Quit()
+}
+
+// message returns the email with headers, lines end with CRLF as SMTP requires
+func (n *Email) message(event OutOfScopeEvent) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", n.from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(n.to, ", "))
+	// baseline scope has a non-ASCII "±" in it
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", event.summary()))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(event.text(), "\n", "\r\n"))
+	return b.Bytes()
+}
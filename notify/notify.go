@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"spyrosoft-recruitment-task/base"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout keeps a slow notification receiver from delaying the requests pool
+const DefaultTimeout = 3 * time.Second
+
+// kinds of notifiers selectable with -notifier
+const (
+	KindWebhook = "webhook"
+	KindSlack   = "slack"
+	KindEmail   = "email"
+)
+
+// OutOfScopeEvent tells about rates of a currency found out of scope by a requests pool
+type OutOfScopeEvent struct {
+	// currency code as returned by NBP, e.g. "EUR"
+	Currency string
+	// static bounds of the currency, reported even when rates were checked against a baseline
+	Bounds base.RateBounds
+	// what the rates were checked against
+	Scope base.Scope
+	Rates []base.OutOfScopeRate
+}
+
+// Notifier delivers out-of-scope events, e.g. to a webhook, Slack or email,
+// a failed delivery is returned to the caller and not retried
+type Notifier interface {
+	Notify(ctx context.Context, event OutOfScopeEvent) error
+}
+
+// sortedRates returns rates of the event ordered by effective date
+func (e OutOfScopeEvent) sortedRates() []base.OutOfScopeRate {
+	sorted := append([]base.OutOfScopeRate(nil), e.Rates...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].EffectiveDate.Before(sorted[j].EffectiveDate)
+	})
+	return sorted
+}
+
+// summary is a one-line description of the event, used as Slack title and email subject
+func (e OutOfScopeEvent) summary() string {
+	return fmt.Sprintf("%s rates out of scope %s", strings.ToUpper(e.Currency), e.Scope)
+}
+
+// text lists every rate of the event in a line of date, mid and direction
+func (e OutOfScopeEvent) text() string {
+	var b strings.Builder
+	for _, rate := range e.sortedRates() {
+		fmt.Fprintf(&b, "%s %.4f %s\n", rate.EffectiveDate.Format("2006-01-02"), rate.Mid, rate.Direction)
+	}
+	return b.String()
+}
@@ -0,0 +1,194 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"spyrosoft-recruitment-task/base"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testEvent has rates out of order, notifiers list them by effective date
+var testEvent = OutOfScopeEvent{
+	Currency: "EUR",
+	Bounds:   base.RateBounds{Min: 4.5, Max: 4.7},
+	Scope:    base.RateBounds{Min: 4.5, Max: 4.7},
+	Rates: []base.OutOfScopeRate{
+		{No: "003/A/NBP/2024", EffectiveDate: time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC), Mid: 4.8, Direction: base.DirectionAbove},
+		{No: "001/A/NBP/2024", EffectiveDate: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Mid: 4.4, Direction: base.DirectionBelow},
+	},
+}
+
+// newReceiver returns server sending every posted JSON body to bodies
+func newReceiver(t *testing.T, bodies chan<- []byte) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got %s of %s, want POST of JSON", r.Method, r.Header.Get("Content-Type"))
+		}
+		var body json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("posted body is not JSON: %s", err)
+		}
+		bodies <- body
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestWebhookPostsEvent(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	server := newReceiver(t, bodies)
+
+	err := NewWebhook(server.URL, DefaultTimeout).Notify(context.Background(), testEvent)
+	if err != nil {
+		t.Fatalf("Notify() failed: %s", err)
+	}
+
+	want := `{"currency":"EUR","bounds":{"min":4.5,"max":4.7},"rates":[` +
+		`{"no":"001/A/NBP/2024","effective_date":"2024-01-02","mid":4.4,"direction":"below"},` +
+		`{"no":"003/A/NBP/2024","effective_date":"2024-01-04","mid":4.8,"direction":"above"}]}`
+	if got := string(<-bodies); got != want {
+		t.Errorf("posted payload =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSlackPostsAttachment(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	server := newReceiver(t, bodies)
+
+	err := NewSlack(server.URL, DefaultTimeout).Notify(context.Background(), testEvent)
+	if err != nil {
+		t.Fatalf("Notify() failed: %s", err)
+	}
+
+	var got slackPayload
+	if err := json.Unmarshal(<-bodies, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := slackPayload{Attachments: []slackAttachment{{
+		Fallback: "EUR rates out of scope 4.50 - 4.70 PLN",
+		Color:    "danger",
+		Title:    "EUR rates out of scope 4.50 - 4.70 PLN",
+		Text:     "```2024-01-02 4.4000 below\n2024-01-04 4.8000 above\n```",
+		MrkdwnIn: []string{"text"},
+	}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("posted payload = %+v, want %+v", got, want)
+	}
+}
+
+func TestNotifiersSkipEventWithoutRates(t *testing.T) {
+	// nothing listens at the addresses, so any attempt to deliver fails
+	notifiers := map[string]Notifier{
+		KindWebhook: NewWebhook("http://127.0.0.1:1", DefaultTimeout),
+		KindSlack:   NewSlack("http://127.0.0.1:1", DefaultTimeout),
+		KindEmail:   NewEmail("127.0.0.1:1", "rates@example.com", []string{"ops@example.com"}, "", "", DefaultTimeout),
+	}
+
+	for kind, notifier := range notifiers {
+		if err := notifier.Notify(context.Background(), OutOfScopeEvent{Currency: "EUR"}); err != nil {
+			t.Errorf("%s Notify() of no rates = %v, want nothing delivered", kind, err)
+		}
+	}
+}
+
+func TestWebhookReportsRejectedPayload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	t.Cleanup(server.Close)
+
+	err := NewWebhook(server.URL, DefaultTimeout).Notify(context.Background(), testEvent)
+
+	var badStatus *base.ErrBadStatus
+	if !errors.As(err, &badStatus) || badStatus.Code != http.StatusBadRequest {
+		t.Errorf("Notify() error = %v, want status 400 of the webhook", err)
+	}
+}
+
+// serveSmtp answers a single SMTP session without extensions, sending the received message to messages
+func serveSmtp(t *testing.T, listener net.Listener, messages chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) {
+		conn.Write([]byte(line + "\r\n"))
+	}
+	reply("220 localhost ESMTP")
+
+	var envelope []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch command := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); command {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL", "RCPT":
+			envelope = append(envelope, line)
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil || line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			messages <- strings.Join(envelope, "\n") + "\n\n" + data.String()
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestEmailSendsEvent(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	messages := make(chan string, 1)
+	go serveSmtp(t, listener, messages)
+
+	email := NewEmail(listener.Addr().String(), "rates@example.com", []string{"ops@example.com", "fx@example.com"}, "", "", DefaultTimeout)
+	err = email.Notify(context.Background(), testEvent)
+	if err != nil {
+		t.Fatalf("Notify() failed: %s", err)
+	}
+
+	message := <-messages
+	for _, want := range []string{
+		"MAIL FROM:<rates@example.com>",
+		"RCPT TO:<ops@example.com>\nRCPT TO:<fx@example.com>",
+		"To: ops@example.com, fx@example.com\r\n",
+		"Subject: EUR rates out of scope 4.50 - 4.70 PLN\r\n",
+		"\r\n\r\n2024-01-02 4.4000 below\r\n2024-01-04 4.8000 above\r\n",
+	} {
+		if !strings.Contains(message, want) {
+			t.Errorf("sent email is missing %q:\n%s", want, message)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type slackAttachment struct {
+	Fallback string `json:"fallback"`
+	Color    string `json:"color"`
+	Title    string `json:"title"`
+	// rendered as preformatted block, so columns of rates stay aligned
+	Text     string   `json:"text"`
+	MrkdwnIn []string `json:"mrkdwn_in"`
+}
+
+type slackPayload struct {
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+// Slack posts out-of-scope rates to a Slack incoming webhook as a red attachment
+type Slack struct {
+	url    string
+	client *http.Client
+}
+
+func NewSlack(url string, timeout time.Duration) *Slack {
+	return &Slack{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Notify posts a single attachment listing rates ordered by effective date, nothing is posted when there are none
+func (n *Slack) Notify(ctx context.Context, event OutOfScopeEvent) error {
+	if len(event.Rates) == 0 {
+		return nil
+	}
+
+	summary := event.summary()
+	body := slackPayload{
+		Attachments: []slackAttachment{{
+			Fallback: summary,
+			Color:    "danger",
+			Title:    summary,
+			Text:     "```" + event.text() + "```",
+			MrkdwnIn: []string{"text"},
+		}},
+	}
+
+	return postJson(ctx, n.client, n.url, body)
+}
@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"spyrosoft-recruitment-task/base"
+	"time"
+)
+
+type boundsPayload struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+type ratePayload struct {
+	No            string  `json:"no"`
+	EffectiveDate string  `json:"effective_date"`
+	Mid           float64 `json:"mid"`
+	Direction     string  `json:"direction"`
+}
+
+type payload struct {
+	Currency string        `json:"currency"`
+	Bounds   boundsPayload `json:"bounds"`
+	Rates    []ratePayload `json:"rates"`
+}
+
+// Webhook posts out-of-scope rates to a webhook as JSON
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhook(url string, timeout time.Duration) *Webhook {
+	return &Webhook{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Notify posts rates ordered by effective date, nothing is posted when there are none
+func (n *Webhook) Notify(ctx context.Context, event OutOfScopeEvent) error {
+	if len(event.Rates) == 0 {
+		return nil
+	}
+
+	body := payload{
+		Currency: event.Currency,
+		Bounds:   boundsPayload{Min: event.Bounds.Min, Max: event.Bounds.Max},
+	}
+	for _, rate := range event.sortedRates() {
+		body.Rates = append(body.Rates, ratePayload{
+			No:            rate.No,
+			EffectiveDate: rate.EffectiveDate.Format("2006-01-02"),
+			Mid:           rate.Mid,
+			Direction:     string(rate.Direction),
+		})
+	}
+
+	return postJson(ctx, n.client, n.url, body)
+}
+
+// postJson posts v marshalled to JSON, any other response than 2xx is an error
+func postJson(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %s", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("failed to prepare webhook request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post webhook: %s", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook rejected the payload: %w", &base.ErrBadStatus{Code: resp.StatusCode, Status: resp.Status})
+	}
+
+	return nil
+}
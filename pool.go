@@ -0,0 +1,505 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"spyrosoft-recruitment-task/api"
+	"spyrosoft-recruitment-task/base"
+	"spyrosoft-recruitment-task/export"
+	"spyrosoft-recruitment-task/logger"
+	"spyrosoft-recruitment-task/metrics"
+	"spyrosoft-recruitment-task/notify"
+	"spyrosoft-recruitment-task/report"
+	"spyrosoft-recruitment-task/storage"
+	"spyrosoft-recruitment-task/telemetry"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrPoolTimeout is returned by runPool when its workers did not finish within the interval
+var ErrPoolTimeout = errors.New("requests pool timed out")
+
+// PoolConfig holds configuration and resources shared by workers of consecutive requests pools
+type PoolConfig struct {
+	Config
+
+	// currencies fetched by every pool, each by cfg.Workers workers
+	Targets []*target
+	// source of rates of every worker
+	Fetcher    Fetcher
+	CsvWriter  *export.CsvWriter
+	Store      *storage.SqliteStore
+	RatesState *api.State
+	// nil when -report-file is not set
+	Report *report.MarkdownWriter
+	// distinct out-of-scope dates of every pool since startup
+	AllOutOfScope *api.OutOfScopeSet
+	// nil when notifications are disabled, see -notifier
+	Notifier notify.Notifier
+	// suppresses notifications of conditions already reported within -alert-cooldown
+	Cooldown *alertCooldown
+	// nil when caching is disabled
+	Cache *responseCache
+	// shared by pools to stop requests while the API keeps failing, nil when disabled
+	Breaker *circuitBreaker
+	// bounds replacing ones of Targets, reloaded while running, nil when -bands-file is not set
+	Bands *bandsFile
+
+	// rates of the previous pool of each currency keyed by table number, used by -diff
+	previousRates map[string]map[string]*base.ExchangeRate
+	// time source of pools scheduling, request timing and export timestamps
+	Clock Clock
+
+	// tracks workers of all pools, including ones left behind by a timed out pool
+	pending sync.WaitGroup
+}
+
+type fetchResult struct {
+	summary     base.ExchangeRatesSummary
+	elapsed     time.Duration
+	statusCode  int
+	contentType string
+	isJsonValid bool
+	// served from cache without performing a request
+	cached bool
+}
+
+type fetchFunc func(ctx context.Context, index int) (*fetchResult, error)
+
+// WorkerResult is the outcome of a single worker, sent to runPool once the worker finishes
+type WorkerResult struct {
+	Index       int
+	RequestId   string
+	Currency    string
+	Elapsed     time.Duration
+	StatusCode  int
+	ContentType string
+	IsJsonValid bool
+	Cached      bool
+	// -today query of a day without publication, neither a failure nor a summary
+	NoData     bool
+	Summary    base.ExchangeRatesSummary
+	OutOfScope []base.OutOfScopeRate
+	Err        error
+
+	target *target
+}
+
+// runPool runs one requests pool and waits until all of its workers finish or the pool times out.
+// Stats of every target currency are returned along with an error if the pool timed out,
+// or a *PoolError aggregating failures of workers if any of them failed.
+func runPool(ctx context.Context, cfg *PoolConfig) ([]base.PoolStats, error) {
+	if !cfg.Quiet {
+		logger.Debug(" ======== BEGIN REQUESTS POOL ======== ")
+	}
+
+	targets := cfg.Targets
+	if cfg.Bands != nil {
+		targets = cfg.Bands.apply(targets)
+	}
+
+	workers := cfg.Workers * len(targets)
+
+	currencies := make([]string, 0, len(targets))
+	for _, t := range targets {
+		currencies = append(currencies, t.Currency)
+	}
+	// fetch spans of workers are children of the pool span carried by ctx
+	ctx, span := telemetry.StartPool(ctx, currencies)
+
+	// buffered for all workers and never closed, so workers of a timed out pool neither block nor panic on sending
+	results := make(chan WorkerResult, workers)
+
+	// cancelled once the pool is done, aborting requests of workers still running after a timeout
+	workersCtx, cancelWorkers := context.WithCancel(ctx)
+	defer cancelWorkers()
+
+	// all workers are launched at once, semaphore caps how many of them perform requests simultaneously
+	concurrency := workers
+	if cfg.MaxConcurrency > 0 && cfg.MaxConcurrency < concurrency {
+		concurrency = cfg.MaxConcurrency
+	}
+	semaphore := make(chan struct{}, concurrency)
+
+	for targetIndex, t := range targets {
+		fetch := newFetchChain(cfg, t)
+
+		for i := 0; i < cfg.Workers; i++ {
+			cfg.pending.Add(1)
+			go func(index int, t *target) {
+				defer cfg.pending.Done()
+
+				select {
+				case semaphore <- struct{}{}:
+				case <-workersCtx.Done():
+					results <- WorkerResult{Index: index, Currency: t.Currency, Err: workersCtx.Err(), target: t}
+					return
+				}
+				result := apiQueryWorker(workersCtx, index, cfg, t, fetch)
+				<-semaphore
+
+				results <- result
+			}(targetIndex*cfg.Workers+i, t)
+		}
+	}
+
+	var err error
+	var failures []*WorkerError
+	summaries := map[*target][]base.ExchangeRatesSummary{}
+	latencies := map[*target][]time.Duration{}
+
+	// with -ordered-output results are held back until the pool is done, so they are logged in order of workers
+	var ordered []WorkerResult
+
+	timeout := cfg.Clock.After(cfg.Interval)
+	for received := 0; received < workers && err == nil; {
+		select {
+		case result := <-results:
+			received++
+			if cfg.OrderedOutput {
+				ordered = append(ordered, result)
+			} else {
+				reportWorkerResult(cfg, result)
+			}
+			if fetchFailed(result.Err) {
+				failures = append(failures, &WorkerError{Index: result.Index, Currency: result.Currency, Err: result.Err})
+			} else if result.Err == nil && !result.NoData {
+				summaries[result.target] = append(summaries[result.target], result.Summary)
+				if !result.Cached {
+					latencies[result.target] = append(latencies[result.target], result.Elapsed)
+				}
+			}
+		case <-timeout:
+			// workers still running are cancelled on return, their results are dropped
+			logger.Warn("Timeout, performing next requests group...")
+			err = fmt.Errorf("%w after %s", ErrPoolTimeout, cfg.Interval)
+		}
+	}
+
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].Index < ordered[j].Index
+	})
+	for _, result := range ordered {
+		reportWorkerResult(cfg, result)
+	}
+
+	// pool with some of its workers failed still reports rates of the rest, failures are returned along with them
+	if err == nil && len(failures) > 0 {
+		poolErr := &PoolError{Workers: workers, Errs: failures}
+		logger.Warn("%d/%d workers succeeded", poolErr.Succeeded(), workers)
+		err = poolErr
+	}
+
+	var allStats []base.PoolStats
+	for _, t := range targets {
+		allStats = append(allStats, reportTarget(ctx, cfg, t, summaries[t], latencies[t]))
+	}
+
+	if cfg.Report != nil {
+		writeReport(cfg, targets, summaries)
+	}
+
+	if cfg.RatesState != nil {
+		cfg.RatesState.History.Add(api.NewHistoryEntry(cfg.Clock.Now(), currencies, allStats))
+	}
+
+	if !cfg.Quiet {
+		logger.Debug(" ======== END OF REQUESTS POOL ======== ")
+	}
+
+	span.End(workers, len(failures), err)
+	return allStats, err
+}
+
+// newFetchChain returns fetch of target currency wrapped according to cache and dedupe settings
+func newFetchChain(cfg *PoolConfig, t *target) fetchFunc {
+	var fetch fetchFunc = func(ctx context.Context, index int) (*fetchResult, error) {
+		return fetchTarget(ctx, index, cfg, t)
+	}
+	if cfg.Breaker != nil {
+		fetch = breakerFetch(cfg.Breaker, fetch)
+	}
+	if cfg.Cache != nil {
+		fetch = cachedFetch(cfg.Cache, t.ApiUrl, fetch)
+	}
+	if cfg.Dedupe {
+		fetch = dedupeFetch(fetch)
+	}
+	return fetch
+}
+
+// reportTarget computes and reports stats of target currency in the pool
+func reportTarget(ctx context.Context, cfg *PoolConfig, t *target, summaries []base.ExchangeRatesSummary, latencies []time.Duration) base.PoolStats {
+	stats := base.NewPoolStats(summaries, t.Scope(), cfg.VolatilityPct)
+	stats.Latency = base.NewLatencies(latencies)
+
+	// summaries of a single currency are labelled as before, the currency is only told apart when there are more
+	if len(cfg.Targets) > 1 {
+		stats.Currency = t.Currency
+	}
+
+	// pool without any successful worker tells nothing about changes, previous rates are kept
+	if cfg.Diff && len(summaries) > 0 {
+		rates := base.IndexRates(summaries)
+		if cfg.previousRates == nil {
+			cfg.previousRates = map[string]map[string]*base.ExchangeRate{}
+		}
+		logger.PrintRateChanges(base.DiffRates(cfg.previousRates[t.Currency], rates))
+		cfg.previousRates[t.Currency] = rates
+	}
+	logger.PrintPoolSummary(stats)
+	warnIfStale(stats, cfg.MaxStaleness, cfg.Clock.Now())
+	warnIfDuplicates(stats)
+
+	if cfg.Notifier != nil && len(summaries) > 0 {
+		notifyOutOfScope(ctx, cfg, t, stats, summaries)
+	}
+
+	return stats
+}
+
+// reportWorkerResult logs request info of a successful worker or the error of a failed one
+func reportWorkerResult(cfg *PoolConfig, result WorkerResult) {
+	// body which is not JSON at all is told apart from one of the wrong schema
+	var invalidJson *base.InvalidJsonError
+	if errors.As(result.Err, &invalidJson) {
+		logger.Warn("%s Skipping invalid JSON body: %s, body starts with: %s", formatWorkerTag(result.Index, result.RequestId), invalidJson.Err, invalidJson.Snippet)
+		return
+	}
+
+	if result.NoData {
+		logger.Info("%s No data today: NBP has not published rates today", formatWorkerTag(result.Index, result.RequestId))
+		return
+	}
+
+	// NBP answered with a summary, it just had nothing to report
+	if errors.Is(result.Err, base.ErrEmptyData) {
+		logger.Warn("%s Skipping response without rates: %s", formatWorkerTag(result.Index, result.RequestId), result.Err)
+		return
+	}
+
+	if result.Err != nil {
+		//failed fetch only skips this worker, the rest of the pool keeps running
+		logger.Error("%s Fetch failed: %s", formatWorkerTag(result.Index, result.RequestId), result.Err)
+		return
+	}
+
+	// in diff mode rates are reported once per pool instead
+	if cfg.Diff {
+		return
+	}
+
+	logger.PrintReqInfo(logger.ReqInfo{
+		Index:       result.Index,
+		RequestId:   result.RequestId,
+		Elapsed:     result.Elapsed,
+		StatusCode:  result.StatusCode,
+		ContentType: result.ContentType,
+		IsJsonValid: result.IsJsonValid,
+		Scope:       result.target.Scope(),
+		OutOfScope:  result.OutOfScope,
+	})
+}
+
+// writeReport writes rates of every target fetched by the pool to the report file, failures are only logged
+func writeReport(cfg *PoolConfig, targets []*target, summaries map[*target][]base.ExchangeRatesSummary) {
+	var sections []report.Section
+	for _, t := range targets {
+		sections = append(sections, report.Section{
+			Currency: t.Currency,
+			Scope:    t.Scope(),
+			Rates:    base.DistinctRates(summaries[t]),
+		})
+	}
+
+	err := cfg.Report.Write(sections, cfg.Clock.Now())
+	if err != nil {
+		logger.Error("Failed to write report: %s", err)
+	}
+}
+
+// warnIfStale reports pools which newest rate is older than maxStaleness at now, check is disabled when it is 0
+func warnIfStale(stats base.PoolStats, maxStaleness time.Duration, now time.Time) {
+	if maxStaleness <= 0 {
+		return
+	}
+
+	age, ok := stats.Staleness(now)
+	if ok && age > maxStaleness {
+		days := int(age.Hours() / 24)
+		logger.Warn("%s Stale data: newest rate from %s is %d days old", logger.PoolTag(stats.Currency), stats.Newest.Format("2006-01-02"), days)
+	}
+}
+
+// notifyOutOfScope sends distinct out-of-scope rates of the pool to the notifier,
+// rates reported within the alert cooldown are left out, failures are only logged
+func notifyOutOfScope(ctx context.Context, cfg *PoolConfig, t *target, stats base.PoolStats, summaries []base.ExchangeRatesSummary) {
+	var rates []*base.ExchangeRate
+	for _, rate := range base.IndexRates(summaries) {
+		rates = append(rates, rate)
+	}
+
+	outOfScope := cfg.Cooldown.filter(t.Currency, t.Scope().Classify(rates))
+	if len(outOfScope) == 0 {
+		return
+	}
+
+	event := notify.OutOfScopeEvent{
+		Currency: summaries[0].Code,
+		Bounds:   t.Bounds,
+		Scope:    t.Scope(),
+		Rates:    outOfScope,
+	}
+	err := cfg.Notifier.Notify(ctx, event)
+	if err != nil {
+		logger.Error("%s Failed to send out-of-scope notification: %s", logger.PoolTag(stats.Currency), err)
+	}
+}
+
+// warnIfDuplicates reports effective dates NBP published more than once, which indicates a data anomaly
+func warnIfDuplicates(stats base.PoolStats) {
+	for _, duplicate := range stats.Duplicates {
+		mids := make([]string, 0, len(duplicate.Mids))
+		for _, mid := range duplicate.Mids {
+			mids = append(mids, fmt.Sprintf("%.4f", mid))
+		}
+		logger.Warn("%s Duplicate rates for %s with mids: %s", logger.PoolTag(stats.Currency), duplicate.Date.Format("2006-01-02"), strings.Join(mids, ", "))
+	}
+}
+
+func apiQueryWorker(ctx context.Context, index int, cfg *PoolConfig, t *target, fetch fetchFunc) WorkerResult {
+	metrics.IncFetches()
+
+	requestId := newRequestId()
+	ctx = withRequestId(ctx, requestId)
+
+	// request is aborted once timeout passes, so a hung endpoint cannot block the worker forever
+	ctx, cancel := context.WithTimeout(ctx, cfg.RequestTimeout)
+	defer cancel()
+
+	ctx, span := telemetry.StartFetch(ctx, t.Currency, index)
+
+	result := queryApi(ctx, index, cfg, t, fetch)
+	result.RequestId = requestId
+	result.Currency = t.Currency
+	result.target = t
+	if fetchFailed(result.Err) {
+		metrics.IncFetchFailures()
+		span.End(spanStatusCode(result), result.Elapsed, result.Err)
+	} else {
+		span.End(spanStatusCode(result), result.Elapsed, nil)
+	}
+
+	var invalidJson *base.InvalidJsonError
+	if errors.As(result.Err, &invalidJson) {
+		metrics.IncInvalidJson()
+	}
+
+	if result.Err == nil && cfg.LatencySla > 0 && result.Elapsed > cfg.LatencySla {
+		metrics.IncSlowResponses()
+		logger.Warn("%s Slow response: took %d ms, above -latency-sla of %s", workerTag(ctx, index), result.Elapsed.Milliseconds(), cfg.LatencySla)
+	}
+
+	return result
+}
+
+// fetchFailed tells whether err of a worker is a failure, a summary without rates is an answer of a healthy API
+// which only has nothing to check, so it is neither a failure nor a summary
+func fetchFailed(err error) bool {
+	return err != nil && !errors.Is(err, base.ErrEmptyData)
+}
+
+// spanStatusCode returns HTTP status of the result, failed results only have one when the API answered with an error status
+func spanStatusCode(result WorkerResult) int {
+	var badStatus *base.ErrBadStatus
+	if errors.As(result.Err, &badStatus) {
+		return badStatus.Code
+	}
+	return result.StatusCode
+}
+
+// queryApi fetches the summary and passes it to the configured outputs
+func queryApi(ctx context.Context, index int, cfg *PoolConfig, t *target, fetch fetchFunc) WorkerResult {
+	fetched, err := fetch(ctx, index)
+	if errors.Is(err, ErrNoDataToday) {
+		return WorkerResult{Index: index, StatusCode: http.StatusNotFound, NoData: true}
+	}
+	if err != nil {
+		return WorkerResult{Index: index, Err: err}
+	}
+
+	summary := fetched.summary
+
+	// both exports record the same fetch time
+	fetchedAt := cfg.Clock.Now()
+
+	if cfg.CsvWriter != nil {
+		err = cfg.CsvWriter.WriteRates(summary.Code, summary.Rates, fetchedAt)
+		if err != nil {
+			logger.Error("%s Failed to export rates to CSV: %s", workerTag(ctx, index), err)
+		}
+	}
+
+	if cfg.Store != nil {
+		err = cfg.Store.SaveRates(summary.Code, summary.Rates, fetchedAt)
+		if err != nil {
+			logger.Error("%s Failed to save rates to SQLite: %s", workerTag(ctx, index), err)
+		}
+	}
+
+	// exports above get all rates, checks and stats only the ones within -analysis-window
+	analyzed := summary.Within(cfg.AnalysisWindow)
+	rateOutOfScope := t.Scope().Classify(analyzed.Rates)
+
+	metrics.AddOutOfScopeRates(len(rateOutOfScope))
+	cfg.AllOutOfScope.Add(t.Currency, rateOutOfScope)
+
+	if cfg.RatesState != nil {
+		cfg.RatesState.Update(summary, rateOutOfScope)
+	}
+
+	return WorkerResult{
+		Index:       index,
+		Elapsed:     fetched.elapsed,
+		StatusCode:  fetched.statusCode,
+		ContentType: fetched.contentType,
+		IsJsonValid: fetched.isJsonValid,
+		Cached:      fetched.cached,
+		Summary:     analyzed,
+		OutOfScope:  rateOutOfScope,
+	}
+}
+
+// fetchTarget fetches rates of target currency through the fetcher of the pool,
+// sources other than the NBP API have no response details to report
+func fetchTarget(ctx context.Context, index int, cfg *PoolConfig, t *target) (*fetchResult, error) {
+	if f, ok := cfg.Fetcher.(targetFetcher); ok {
+		return f.fetchTarget(ctx, index, t)
+	}
+
+	startTime := cfg.Clock.Now()
+	summary, err := cfg.Fetcher.Fetch(ctx, t.Currency, cfg.Count)
+	if err != nil {
+		return nil, err
+	}
+	// like a decoded response body, the summary is syntactically valid once returned
+	return &fetchResult{summary: summary, elapsed: cfg.Clock.Now().Sub(startTime), isJsonValid: true}, nil
+}
+
+// dedupeFetch makes all workers of a pool share the result of a single request
+func dedupeFetch(fetch fetchFunc) fetchFunc {
+	var once sync.Once
+	var result *fetchResult
+	var err error
+
+	return func(ctx context.Context, index int) (*fetchResult, error) {
+		once.Do(func() {
+			result, err = fetch(ctx, index)
+		})
+		return result, err
+	}
+}
@@ -0,0 +1,1062 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"spyrosoft-recruitment-task/api"
+	"spyrosoft-recruitment-task/base"
+	"spyrosoft-recruitment-task/export"
+	"spyrosoft-recruitment-task/logger"
+	"spyrosoft-recruitment-task/notify"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRunPoolKeepsRunningWhenRequestsFail(t *testing.T) {
+	const workers = 10
+
+	// every third request fails before reaching NBP
+	var requests int32
+	setDefaultTransport(t, roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if atomic.AddInt32(&requests, 1)%3 == 0 {
+			return nil, errors.New("connection reset by peer")
+		}
+		return gzipResponse(testSummaryJson), nil
+	}))
+	output := captureLog(t, logger.LevelInfo)
+
+	// failed requests neither stop the other workers nor the test process
+	runWorkers(workers, testBounds)
+
+	content := output.String()
+	if failed := strings.Count(content, "Fetch failed: failed to perform GET request"); failed != 3 {
+		t.Errorf("log has %d failed fetches, want 3:\n%s", failed, content)
+	}
+	if succeeded := strings.Count(content, "HTTP Status Code: 200"); succeeded != 7 {
+		t.Errorf("log has %d successful fetches, want 7:\n%s", succeeded, content)
+	}
+}
+
+func TestWorkerReportsRatesOutOfBounds(t *testing.T) {
+	body := `{"table":"A","currency":"euro","code":"EUR","rates":[` +
+		`{"no":"001/A/NBP/2024","effectiveDate":"2024-01-02","mid":4.49},` +
+		`{"no":"002/A/NBP/2024","effectiveDate":"2024-01-03","mid":4.5},` +
+		`{"no":"003/A/NBP/2024","effectiveDate":"2024-01-04","mid":4.6},` +
+		`{"no":"004/A/NBP/2024","effectiveDate":"2024-01-05","mid":4.7},` +
+		`{"no":"005/A/NBP/2024","effectiveDate":"2024-01-08","mid":4.71}]}`
+	setDefaultTransport(t, roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return gzipResponse(body), nil
+	}))
+
+	tests := []struct {
+		bounds base.RateBounds
+		want   string
+	}{
+		// rates at the bounds are within them
+		{base.RateBounds{Min: 4.5, Max: 4.7}, "Mid Was Out Of Scope 4.50 - 4.70 PLN in: 2024-01-02; 2024-01-08\n"},
+		{base.RateBounds{Min: 4.55, Max: 4.65}, "Mid Was Out Of Scope 4.55 - 4.65 PLN in: 2024-01-02; 2024-01-03; 2024-01-05; 2024-01-08\n"},
+		{base.RateBounds{Min: 4.4, Max: 4.8}, "Mid Was Out Of Scope 4.40 - 4.80 PLN in: \n"},
+	}
+
+	for _, tt := range tests {
+		output := captureLog(t, logger.LevelInfo)
+		runWorkers(1, tt.bounds)
+
+		if !strings.Contains(output.String(), tt.want) {
+			t.Errorf("log of bounds %v =\n%s\nwant line %q", tt.bounds, output.String(), tt.want)
+		}
+	}
+}
+
+func TestEveryWorkerSendsOneRequest(t *testing.T) {
+	const workers = 7
+
+	var mu sync.Mutex
+	var urls []string
+	setDefaultTransport(t, roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		urls = append(urls, req.URL.String())
+		mu.Unlock()
+		return gzipResponse(testSummaryJson), nil
+	}))
+	captureLog(t, logger.LevelInfo)
+
+	runWorkers(workers, testBounds)
+
+	if len(urls) != workers {
+		t.Fatalf("workers sent %d requests, want %d", len(urls), workers)
+	}
+	for _, url := range urls {
+		if url != testApiUrl {
+			t.Errorf("worker requested %s, want %s", url, testApiUrl)
+		}
+	}
+}
+
+func TestWorkerReadsEveryEncoding(t *testing.T) {
+	for _, encoding := range []string{"gzip", "deflate", ""} {
+		server := newEncodedNbpServer(t, encoding, testSummaryJson)
+		output := captureLog(t, logger.LevelInfo)
+
+		cfg := newTestPoolConfig(1, server.URL)
+		reportWorkerResult(cfg, apiQueryWorker(context.Background(), 0, cfg, cfg.Targets[0], summaryFetch(cfg)))
+
+		if content := output.String(); strings.Contains(content, "Fetch failed") || !strings.Contains(content, "Is Syntax Valid JSON: true") {
+			t.Errorf("log of encoding %q =\n%s\nwant valid JSON", encoding, content)
+		}
+	}
+}
+
+func TestRunPoolWorkersShareConnectionsOfClient(t *testing.T) {
+	const workers, pools = 10, 3
+
+	var requests, connections int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		io.WriteString(w, testSummaryJson)
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+	captureLog(t, logger.LevelInfo)
+
+	cfg := newTestPoolConfig(workers, server.URL)
+	httpFetcher(cfg).Client = newHttpClient(workers, nil, nil)
+	for i := 0; i < pools; i++ {
+		if _, err := runPool(context.Background(), cfg); err != nil {
+			t.Fatalf("runPool() failed: %s", err)
+		}
+	}
+
+	// a client per request would open a connection for every one of them,
+	// a shared one keeps at most a connection per worker idle between pools
+	got, sent := atomic.LoadInt32(&connections), atomic.LoadInt32(&requests)
+	if sent != workers*pools {
+		t.Fatalf("server got %d requests, want %d", sent, workers*pools)
+	}
+	if got > sent/2 {
+		t.Errorf("%d connections opened for %d requests, want them reused across pools", got, sent)
+	}
+}
+
+func TestRunPoolReturnsFailuresOfWorkers(t *testing.T) {
+	var failing int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		io.WriteString(w, testSummaryJson)
+	}))
+	defer server.Close()
+	captureLog(t, logger.LevelInfo)
+
+	cfg := newTestPoolConfig(3, server.URL)
+	if _, err := runPool(context.Background(), cfg); err != nil {
+		t.Errorf("runPool() of successful fetches failed: %s", err)
+	}
+
+	// a single pool of -once exits non-zero when any of its workers failed
+	atomic.StoreInt32(&failing, 1)
+	_, err := runPool(context.Background(), cfg)
+	if err == nil || !strings.Contains(err.Error(), "3 of 3 workers failed") {
+		t.Errorf("runPool() error = %v, want 3 of 3 workers failed", err)
+	}
+}
+
+func TestRunPoolAggregatesErrorsOfFailedSubsetOfWorkers(t *testing.T) {
+	// every failing worker fails with an error of its own
+	failing := map[int]error{
+		2: &base.ErrBadStatus{Code: http.StatusBadGateway},
+		5: errors.New("connection reset by peer"),
+		7: context.DeadlineExceeded,
+	}
+	cfg := newTestPoolConfig(10, testApiUrl)
+	cfg.Fetcher = workerFetcherFunc(func(ctx context.Context, index int, t *target) (*fetchResult, error) {
+		if err, ok := failing[index]; ok {
+			return nil, err
+		}
+		result := okResult()
+		result.summary = testSummary(t.Currency, 4.6)
+		return result, nil
+	})
+	log := captureLog(t, logger.LevelWarn)
+
+	_, err := runPool(context.Background(), cfg)
+
+	var poolErr *PoolError
+	if !errors.As(err, &poolErr) {
+		t.Fatalf("runPool() error = %v, want *PoolError", err)
+	}
+	if poolErr.Workers != 10 || poolErr.Succeeded() != 7 {
+		t.Errorf("%d of %d workers succeeded, want 7 of 10", poolErr.Succeeded(), poolErr.Workers)
+	}
+	got := map[int]error{}
+	for _, workerErr := range poolErr.Errs {
+		got[workerErr.Index] = workerErr.Err
+		if workerErr.Currency != "eur" {
+			t.Errorf("error of worker %d is of currency %q, want eur", workerErr.Index, workerErr.Currency)
+		}
+	}
+	if len(got) != len(poolErr.Errs) || !reflect.DeepEqual(got, failing) {
+		t.Errorf("errors of workers = %v, want exactly %v", got, failing)
+	}
+	for _, target := range failing {
+		if !errors.Is(err, target) {
+			t.Errorf("errors.Is(%v) of the pool error = false, want true", target)
+		}
+	}
+	if !strings.Contains(log.String(), "7/10 workers succeeded") {
+		t.Errorf("log =\n%s\nwant summary of 7/10 workers succeeded", log.String())
+	}
+}
+
+func TestRunPoolOfNoFailedWorkersReturnsNoError(t *testing.T) {
+	cfg := newTestPoolConfig(4, testApiUrl)
+	cfg.Fetcher = fetcherFunc(func(ctx context.Context, currency string, count int) (base.ExchangeRatesSummary, error) {
+		return testSummary(currency, 4.6), nil
+	})
+	log := captureLog(t, logger.LevelWarn)
+
+	if _, err := runPool(context.Background(), cfg); err != nil {
+		t.Errorf("runPool() error = %v, want nil", err)
+	}
+	if strings.Contains(log.String(), "workers succeeded") {
+		t.Errorf("log =\n%s\nwant no summary of failures", log.String())
+	}
+}
+
+func TestRunPoolRateLimitSpacesRequests(t *testing.T) {
+	var mu sync.Mutex
+	var arrivals []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		arrivals = append(arrivals, time.Now())
+		mu.Unlock()
+		io.WriteString(w, testSummaryJson)
+	}))
+	defer server.Close()
+	captureLog(t, logger.LevelInfo)
+
+	// 20 requests per second is one every 50ms
+	cfg := newTestPoolConfig(5, server.URL)
+	httpFetcher(cfg).Limiter = newRateLimiter(20, 1)
+	if _, err := runPool(context.Background(), cfg); err != nil {
+		t.Fatalf("runPool() failed: %s", err)
+	}
+
+	if len(arrivals) != 5 {
+		t.Fatalf("server got %d requests, want 5", len(arrivals))
+	}
+	// 4 gaps of 50ms, with some slack for timer granularity
+	if spread := arrivals[4].Sub(arrivals[0]); spread < 180*time.Millisecond {
+		t.Errorf("5 requests at -rate-limit 20 arrived within %s, want at least ~200ms", spread)
+	}
+}
+
+func TestRunPoolAnalyzesOnlyRatesWithinAnalysisWindow(t *testing.T) {
+	// rates of 2024-01-02 to 2024-01-06, the out-of-scope ones are older than 2 days of the newest
+	cfg := newTestPoolConfig(1, testApiUrl)
+	cfg.Fetcher = fetcherFunc(func(ctx context.Context, currency string, count int) (base.ExchangeRatesSummary, error) {
+		return testSummary(currency, 4.4, 4.8, 4.55, 4.6, 4.65), nil
+	})
+	cfg.AnalysisWindow = 48 * time.Hour
+	captureLog(t, logger.LevelInfo)
+
+	path := filepath.Join(t.TempDir(), "rates.csv")
+	csvWriter, err := export.NewCsvWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.CsvWriter = csvWriter
+
+	allStats, err := runPool(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("runPool() failed: %s", err)
+	}
+	if err := csvWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := allStats[0]
+	if stats.Rates != 3 || stats.Min != 4.55 || stats.Max != 4.65 || math.Abs(stats.Average-4.6) > 1e-9 || stats.OutOfScope != 0 {
+		t.Errorf("pool stats = %+v, want 3 rates within 4.55-4.65 averaging 4.6 and none out of scope", stats)
+	}
+	if all := cfg.AllOutOfScope.All(); len(all) != 0 {
+		t.Errorf("out-of-scope dates = %v, want none within the window", all)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// header and every fetched rate
+	if rows := strings.Count(string(content), "\n"); rows != 6 {
+		t.Errorf("CSV has %d rows, want header and all 5 fetched rates:\n%s", rows, content)
+	}
+}
+
+func TestWarnIfStale(t *testing.T) {
+	now := time.Date(2024, 1, 12, 12, 0, 0, 0, time.UTC)
+	today := time.Date(2024, 1, 12, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		newest   time.Time
+		wantWarn string
+	}{
+		{"today", today, ""},
+		{"2 days ago", today.AddDate(0, 0, -2), ""},
+		{"10 days ago", today.AddDate(0, 0, -10), "<pool> Stale data: newest rate from 2024-01-02 is 10 days old"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := captureLog(t, logger.LevelInfo)
+
+			warnIfStale(base.PoolStats{Newest: tt.newest}, DefaultMaxStaleness, now)
+
+			got := output.String()
+			if tt.wantWarn == "" && got != "" {
+				t.Errorf("newest rate of %s is warned about:\n%s", tt.newest.Format("2006-01-02"), got)
+			}
+			if tt.wantWarn != "" && !strings.Contains(got, tt.wantWarn) {
+				t.Errorf("log =\n%s\nwant warning %q", got, tt.wantWarn)
+			}
+		})
+	}
+}
+
+func TestRunPoolWithDedupeMakesSingleRequest(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		io.WriteString(w, testSummaryJson)
+	}))
+	defer server.Close()
+	captureLog(t, logger.LevelInfo)
+
+	cfg := newTestPoolConfig(5, server.URL)
+	cfg.Dedupe = true
+
+	for pool := 1; pool <= 2; pool++ {
+		allStats, err := runPool(context.Background(), cfg)
+		if err != nil {
+			t.Fatalf("runPool() failed: %s", err)
+		}
+		// every worker still reports the shared result
+		if allStats[0].Fetches != 5 {
+			t.Errorf("pool stats of %d fetches, want 5", allStats[0].Fetches)
+		}
+		if got := atomic.LoadInt32(&requests); got != int32(pool) {
+			t.Errorf("%d requests after %d pools, want one per pool", got, pool)
+		}
+	}
+}
+
+func TestRunPoolCapsRequestsInFlight(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			seen := atomic.LoadInt32(&maxInFlight)
+			if current <= seen || atomic.CompareAndSwapInt32(&maxInFlight, seen, current) {
+				break
+			}
+		}
+
+		// long enough for the other workers to pile up if they were let through
+		time.Sleep(10 * time.Millisecond)
+		io.WriteString(w, testSummaryJson)
+	}))
+	defer server.Close()
+	captureLog(t, logger.LevelInfo)
+
+	cfg := newTestPoolConfig(10, server.URL)
+	cfg.MaxConcurrency = 2
+	allStats, err := runPool(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("runPool() failed: %s", err)
+	}
+
+	if got := atomic.LoadInt32(&maxInFlight); got != 2 {
+		t.Errorf("%d requests were in flight at once, want at most -max-concurrency of 2 and not fewer", got)
+	}
+	// all workers still run
+	if allStats[0].Fetches != 10 {
+		t.Errorf("pool stats of %d fetches, want 10", allStats[0].Fetches)
+	}
+}
+
+func TestRunPoolReceivesResultOfEveryWorker(t *testing.T) {
+	const workers = 7
+	var requests int32
+	cfg := newTestPoolConfig(workers, testApiUrl)
+	httpFetcher(cfg).Client.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if atomic.AddInt32(&requests, 1)%2 == 0 {
+			return nil, errors.New("connection reset")
+		}
+		return gzipResponse(testSummaryJson), nil
+	})
+	output := captureLog(t, logger.LevelInfo)
+
+	allStats, err := runPool(context.Background(), cfg)
+
+	// every worker reports exactly once, either its request or its failure
+	lines := strings.Split(output.String(), "\n")
+	for index := 0; index < workers; index++ {
+		tag := fmt.Sprintf("<worker-%d ", index)
+		var reports int
+		for _, line := range lines {
+			if strings.Contains(line, tag) && (strings.Contains(line, "Request Time:") || strings.Contains(line, "Fetch failed:")) {
+				reports++
+			}
+		}
+		if reports != 1 {
+			t.Errorf("worker %d reported %d results, want 1", index, reports)
+		}
+	}
+
+	var poolErr *PoolError
+	if !errors.As(err, &poolErr) || len(poolErr.Errs) != 3 {
+		t.Fatalf("runPool() error = %v, want failures of 3 workers", err)
+	}
+	if allStats[0].Fetches != 4 {
+		t.Errorf("pool stats of %d fetches, want 4 successful ones", allStats[0].Fetches)
+	}
+}
+
+func TestRunPoolInQuietModeOmitsBanners(t *testing.T) {
+	banners := []string{"BEGIN REQUESTS POOL", "END OF REQUESTS POOL"}
+
+	tests := []struct {
+		quiet       bool
+		wantBanners bool
+	}{
+		{false, true},
+		{true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("quiet=%t", tt.quiet), func(t *testing.T) {
+			// banners are debug lines, -quiet leaves them out even at debug level
+			var requests int32
+			cfg := newTestPoolConfig(2, testApiUrl)
+			cfg.Quiet = tt.quiet
+			httpFetcher(cfg).Client.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				if atomic.AddInt32(&requests, 1) == 2 {
+					return nil, errors.New("connection reset")
+				}
+				return gzipResponse(testSummaryJson), nil
+			})
+			log := captureLog(t, logger.LevelDebug)
+
+			runPool(context.Background(), cfg)
+
+			output := log.String()
+			for _, banner := range banners {
+				if strings.Contains(output, banner) != tt.wantBanners {
+					t.Errorf("log contains %q: %t, want %t:\n%s", banner, !tt.wantBanners, tt.wantBanners, output)
+				}
+			}
+			for _, kept := range []string{"Successful Requests: 1", "Fetch failed:"} {
+				if !strings.Contains(output, kept) {
+					t.Errorf("log is missing %q:\n%s", kept, output)
+				}
+			}
+		})
+	}
+}
+
+func TestWarnIfDuplicates(t *testing.T) {
+	log := captureLog(t, logger.LevelInfo)
+	date := time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)
+
+	warnIfDuplicates(base.PoolStats{})
+	warnIfDuplicates(base.PoolStats{Duplicates: []base.DuplicateDate{{Date: date, Mids: []float64{4.6, 4.65}}}})
+
+	lines := strings.Split(strings.TrimSuffix(log.String(), "\n"), "\n")
+	if len(lines) != 1 || !strings.HasSuffix(lines[0], "<pool> Duplicate rates for 2024-01-03 with mids: 4.6000, 4.6500") {
+		t.Errorf("log =\n%s\nwant a single warning of the duplicate date with its mids", log.String())
+	}
+}
+
+// webhookPayload is the part of a webhook notification the tests look at
+type webhookPayload struct {
+	Currency string `json:"currency"`
+	Rates    []struct {
+		EffectiveDate string  `json:"effective_date"`
+		Mid           float64 `json:"mid"`
+	} `json:"rates"`
+}
+
+func TestRunPoolPostsWebhookOncePerOutOfScopeDate(t *testing.T) {
+	payloads := make(chan webhookPayload, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload webhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("webhook payload is not JSON: %s", err)
+		}
+		payloads <- payload
+	}))
+	t.Cleanup(receiver.Close)
+
+	// the second pool gets 2024-01-02 again along with a new out-of-scope date 2024-01-04
+	mids := [][]float64{{4.4, 4.6}, {4.4, 4.6, 4.8}}
+	var pools int32
+	cfg := newTestPoolConfig(2, testApiUrl)
+	httpFetcher(cfg).Client.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return gzipResponse(summaryJson("eur", mids[atomic.LoadInt32(&pools)]...)), nil
+	})
+	cfg.Notifier = notify.NewWebhook(receiver.URL, notify.DefaultTimeout)
+	cfg.Cooldown = newAlertCooldown(cfg.AlertCooldown, cfg.Clock)
+
+	for pool := range mids {
+		atomic.StoreInt32(&pools, int32(pool))
+		if _, err := runPool(context.Background(), cfg); err != nil {
+			t.Fatalf("pool %d failed: %s", pool, err)
+		}
+	}
+	close(payloads)
+
+	var dates []string
+	for payload := range payloads {
+		if payload.Currency != "EUR" {
+			t.Errorf("payload of currency %q, want EUR", payload.Currency)
+		}
+		for _, rate := range payload.Rates {
+			dates = append(dates, rate.EffectiveDate)
+		}
+	}
+	if want := []string{"2024-01-02", "2024-01-04"}; !reflect.DeepEqual(dates, want) {
+		t.Errorf("posted dates = %v, want each out-of-scope date once: %v", dates, want)
+	}
+}
+
+func TestRunPoolLogsFailedWebhook(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(receiver.Close)
+
+	cfg := newTestPoolConfig(1, testApiUrl)
+	httpFetcher(cfg).Client.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return gzipResponse(summaryJson("eur", 4.4)), nil
+	})
+	cfg.Notifier = notify.NewWebhook(receiver.URL, notify.DefaultTimeout)
+	cfg.Cooldown = newAlertCooldown(cfg.AlertCooldown, cfg.Clock)
+	log := captureLog(t, logger.LevelInfo)
+
+	// failed delivery is not a failure of the pool
+	if _, err := runPool(context.Background(), cfg); err != nil {
+		t.Fatalf("runPool() failed: %s", err)
+	}
+	if !strings.Contains(log.String(), "<pool> Failed to send out-of-scope notification: webhook rejected the payload: unexpected HTTP status 500 Internal Server Error") {
+		t.Errorf("log =\n%s\nwant error of the failed notification", log.String())
+	}
+}
+
+// recordingNotifier records events it is notified of, failing each with err
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []notify.OutOfScopeEvent
+	err    error
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, event notify.OutOfScopeEvent) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, event)
+	return n.err
+}
+
+func TestRunPoolNotifiesOutOfScopeEvent(t *testing.T) {
+	cfg := newTestPoolConfig(2, testApiUrl)
+	cfg.Targets[0].Bounds = base.RateBounds{Min: 4.5, Max: 4.7}
+	cfg.Fetcher = fetcherFunc(func(ctx context.Context, currency string, count int) (base.ExchangeRatesSummary, error) {
+		return testSummary(currency, 4.4, 4.6, 4.8), nil
+	})
+	notifier := &recordingNotifier{}
+	cfg.Notifier = notifier
+	cfg.Cooldown = newAlertCooldown(cfg.AlertCooldown, cfg.Clock)
+
+	if _, err := runPool(context.Background(), cfg); err != nil {
+		t.Fatalf("runPool() failed: %s", err)
+	}
+
+	if len(notifier.events) != 1 {
+		t.Fatalf("notified %d events, want one of the pool", len(notifier.events))
+	}
+	event := notifier.events[0]
+	bounds := base.RateBounds{Min: 4.5, Max: 4.7}
+	if event.Currency != "EUR" || event.Bounds != bounds || event.Scope != bounds {
+		t.Errorf("event of %s bounds %v scope %v, want EUR of %v", event.Currency, event.Bounds, event.Scope, bounds)
+	}
+	var rates []string
+	for _, rate := range event.Rates {
+		rates = append(rates, fmt.Sprintf("%s %s %.1f %s", rate.No, rate.EffectiveDate.Format(DateLayout), rate.Mid, rate.Direction))
+	}
+	// notifiers order rates by date themselves
+	sort.Strings(rates)
+	want := []string{"001/A/NBP/2024 2024-01-02 4.4 below", "003/A/NBP/2024 2024-01-04 4.8 above"}
+	if !reflect.DeepEqual(rates, want) {
+		t.Errorf("event rates = %v, want %v", rates, want)
+	}
+}
+
+func TestRunPoolDoesNotNotifyRatesInScope(t *testing.T) {
+	cfg := newTestPoolConfig(1, testApiUrl)
+	cfg.Targets[0].Bounds = base.RateBounds{Min: 4.5, Max: 4.7}
+	cfg.Fetcher = fetcherFunc(func(ctx context.Context, currency string, count int) (base.ExchangeRatesSummary, error) {
+		return testSummary(currency, 4.55, 4.6), nil
+	})
+	notifier := &recordingNotifier{}
+	cfg.Notifier = notifier
+	cfg.Cooldown = newAlertCooldown(cfg.AlertCooldown, cfg.Clock)
+
+	if _, err := runPool(context.Background(), cfg); err != nil {
+		t.Fatalf("runPool() failed: %s", err)
+	}
+	if len(notifier.events) != 0 {
+		t.Errorf("notified %v, want nothing of rates in scope", notifier.events)
+	}
+}
+
+// runTimedOutPool runs pool of cfg and times it out once it waits for its interval on clock,
+// and once running is done unless it is nil
+func runTimedOutPool(t *testing.T, cfg *PoolConfig, clock *fakeClock, running *sync.WaitGroup) error {
+	t.Helper()
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := runPool(context.Background(), cfg)
+		errs <- err
+	}()
+	clock.BlockUntil(1)
+	if running != nil {
+		running.Wait()
+	}
+	clock.Advance(cfg.Interval)
+	return <-errs
+}
+
+func TestRunPoolCancelsWorkersStillRunningAfterTimeout(t *testing.T) {
+	release := make(chan struct{})
+	cancelled := make(chan bool, 1)
+	// worker which has not started before the timeout gives up without fetching
+	var running sync.WaitGroup
+	running.Add(2)
+	var requests int32
+	cfg := newTestPoolConfig(2, testApiUrl)
+	cfg.Interval = time.Minute
+	httpFetcher(cfg).Client.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		running.Done()
+		if atomic.AddInt32(&requests, 1) == 1 {
+			// aborts its request once cancelled, but reports it only after the pool is done
+			<-req.Context().Done()
+			cancelled <- true
+			<-release
+			return nil, req.Context().Err()
+		}
+		// ignores cancellation and reports a result long after the timeout
+		<-release
+		return gzipResponse(testSummaryJson), nil
+	})
+	clock := newFakeClock(time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC))
+	cfg.Clock = clock
+	captureLog(t, logger.LevelInfo)
+
+	err := runTimedOutPool(t, cfg, clock, &running)
+	if !errors.Is(err, ErrPoolTimeout) {
+		t.Fatalf("runPool() error = %v, want %v", err, ErrPoolTimeout)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("worker still running after the timeout was not cancelled")
+	}
+
+	// late results of both workers are sent after the pool returned, neither blocking nor panicking
+	close(release)
+	done := make(chan struct{})
+	go func() {
+		cfg.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("workers of the timed out pool did not finish")
+	}
+}
+
+func TestRunPoolLeaksNoGoroutinesOnTimeout(t *testing.T) {
+	// requests are stuck until cancelled, so every pool times out
+	cfg := newTestPoolConfig(5, testApiUrl)
+	cfg.Interval = time.Minute
+	httpFetcher(cfg).Client.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+	clock := newFakeClock(time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC))
+	cfg.Clock = clock
+	captureLog(t, logger.LevelInfo)
+
+	runTimedOutPool(t, cfg, clock, nil)
+	cfg.pending.Wait()
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 50; i++ {
+		if err := runTimedOutPool(t, cfg, clock, nil); !errors.Is(err, ErrPoolTimeout) {
+			t.Fatalf("pool %d error = %v, want %v", i, err, ErrPoolTimeout)
+		}
+	}
+	cfg.pending.Wait()
+
+	// goroutines of the last pool may take a moment to exit
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("%d goroutines after 50 timed out pools, %d before them", after, before)
+	}
+}
+
+func TestRunPoolChecksEachCurrencyAgainstItsBand(t *testing.T) {
+	mids := map[string][]float64{
+		"eur": {4.4, 4.6, 4.8},
+		"usd": {3.9, 4.0},
+	}
+	targets, err := buildTargets(loadTestConfig(t, "-currencies", "eur,usd", "-bands", "eur=4.5:4.7,usd=3.8:4.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := newTestPoolConfig(1, testApiUrl)
+	cfg.Targets = targets
+	httpFetcher(cfg).Client.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		for currency, currencyMids := range mids {
+			if strings.Contains(req.URL.Path, "/"+currency+"/") {
+				return gzipResponse(summaryJson(currency, currencyMids...)), nil
+			}
+		}
+		return nil, fmt.Errorf("request of unexpected currency: %s", req.URL)
+	})
+	captureLog(t, logger.LevelInfo)
+
+	allStats, err := runPool(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("runPool() failed: %s", err)
+	}
+
+	got := map[string]int{}
+	for _, stats := range allStats {
+		got[stats.Currency] = stats.OutOfScope
+	}
+	if want := map[string]int{"eur": 2, "usd": 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("out-of-scope dates per currency = %v, want %v", got, want)
+	}
+}
+
+func TestRunPoolAggregatesFakeFetcherResults(t *testing.T) {
+	// workers of a currency fetch overlapping windows, as they would from the API, the second one of a newer rate
+	mids := map[string][][]float64{
+		"eur": {{4.4, 4.6}, {4.4, 4.6, 4.8}},
+		"usd": {{3.9, 4.0}, {3.9, 4.0}},
+	}
+	var mu sync.Mutex
+	calls := map[string]int{}
+	targets, err := buildTargets(loadTestConfig(t, "-currencies", "eur,usd", "-rate-min", "4.0", "-rate-max", "4.7"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := newTestPoolConfig(2, testApiUrl)
+	cfg.Targets = targets
+	cfg.Fetcher = fetcherFunc(func(ctx context.Context, currency string, count int) (base.ExchangeRatesSummary, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if count != DefaultCount {
+			t.Errorf("Fetch() of %d rates, want %d", count, DefaultCount)
+		}
+		window := mids[currency][calls[currency]]
+		calls[currency]++
+		return testSummary(currency, window...), nil
+	})
+	captureLog(t, logger.LevelInfo)
+
+	allStats, err := runPool(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("runPool() failed: %s", err)
+	}
+	if len(allStats) != 2 {
+		t.Fatalf("runPool() returned stats of %d currencies, want 2", len(allStats))
+	}
+
+	eur, usd := allStats[0], allStats[1]
+	if eur.Currency != "eur" || eur.Fetches != 2 || eur.Rates != 5 || eur.Min != 4.4 || eur.Max != 4.8 {
+		t.Errorf("EUR stats = %+v, want 2 fetches of 5 rates within 4.4-4.8", eur)
+	}
+	if math.Abs(eur.Average-4.56) > 1e-9 {
+		t.Errorf("EUR average = %v, want 4.56", eur.Average)
+	}
+	// 4.8 of the third day is fetched by one worker only
+	if eur.OutOfScope != 1 {
+		t.Errorf("EUR out of scope = %d, want 1", eur.OutOfScope)
+	}
+
+	if usd.Currency != "usd" || usd.Fetches != 2 || usd.Rates != 4 || usd.Min != 3.9 || usd.Max != 4.0 {
+		t.Errorf("USD stats = %+v, want 2 fetches of 4 rates within 3.9-4.0", usd)
+	}
+	// 3.9 of the first day is fetched by both workers, it is counted once
+	if usd.OutOfScope != 1 {
+		t.Errorf("USD out of scope = %d, want 1", usd.OutOfScope)
+	}
+}
+
+func TestRunPoolCollectsDistinctOutOfScopeDatesOfAllPools(t *testing.T) {
+	// workers of both pools fetch overlapping days, 2024-01-02 below and 2024-01-03 or 2024-01-04 above the band
+	mids := [][][]float64{
+		{{4.4, 4.6}, {4.4, 4.8}},
+		{{4.4, 4.8}, {4.4, 4.6, 4.8}},
+	}
+	var pool, calls int32
+	cfg := newTestPoolConfig(8, testApiUrl)
+	cfg.Fetcher = fetcherFunc(func(ctx context.Context, currency string, count int) (base.ExchangeRatesSummary, error) {
+		call := atomic.AddInt32(&calls, 1)
+		return testSummary(currency, mids[atomic.LoadInt32(&pool)][call%2]...), nil
+	})
+	captureLog(t, logger.LevelError)
+
+	for i := range mids {
+		atomic.StoreInt32(&pool, int32(i))
+		if _, err := runPool(context.Background(), cfg); err != nil {
+			t.Fatalf("pool %d failed: %s", i, err)
+		}
+	}
+
+	want := []api.OutOfScopeDate{{Currency: "eur", Date: "2024-01-02"}, {Currency: "eur", Date: "2024-01-03"}, {Currency: "eur", Date: "2024-01-04"}}
+	if got := cfg.AllOutOfScope.All(); !reflect.DeepEqual(got, want) {
+		t.Errorf("out-of-scope dates since startup = %v, want %v", got, want)
+	}
+}
+
+// counterValue returns current value of Prometheus counter of name
+func counterValue(t *testing.T, name string) float64 {
+	t.Helper()
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %s", err)
+	}
+	for _, family := range families {
+		if family.GetName() == name {
+			return family.GetMetric()[0].GetCounter().GetValue()
+		}
+	}
+	t.Fatalf("counter %s is not registered", name)
+	return 0
+}
+
+func TestRunPoolSkipsInvalidJsonBody(t *testing.T) {
+	server := newEncodedNbpServer(t, "", `{"table":"A","currency":euro}`)
+	cfg := newTestPoolConfig(2, server.URL)
+	invalid := counterValue(t, "nbp_invalid_json_total")
+	log := captureLog(t, logger.LevelWarn)
+
+	// body is neither unmarshalled nor fatal, the pool goes on to its summary
+	allStats, err := runPool(context.Background(), cfg)
+
+	if err == nil {
+		t.Error("runPool() error = nil, want failure of the workers")
+	}
+	if allStats[0].Fetches != 0 {
+		t.Errorf("pool stats of %d fetches, want none of the invalid bodies", allStats[0].Fetches)
+	}
+	if got := counterValue(t, "nbp_invalid_json_total"); got != invalid+2 {
+		t.Errorf("invalid JSON counter went from %v to %v, want 2 more", invalid, got)
+	}
+	if got := strings.Count(log.String(), `Skipping invalid JSON body: invalid character 'e' looking for beginning of value, body starts with: {"table":"A","currency":euro}`); got != 2 {
+		t.Errorf("log has %d warnings of the invalid body, want 2:\n%s", got, log.String())
+	}
+}
+
+func TestRunPoolDoesNotCountEmptyDataAsFailure(t *testing.T) {
+	var calls int32
+	cfg := newTestPoolConfig(2, testApiUrl)
+	cfg.Fetcher = fetcherFunc(func(ctx context.Context, currency string, count int) (base.ExchangeRatesSummary, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return base.ExchangeRatesSummary{}, fmt.Errorf("failed to parse response: %w", base.ErrEmptyData)
+		}
+		return testSummary(currency, 4.6), nil
+	})
+	// a single failure would open the breaker of threshold 1
+	cfg.Breaker = newCircuitBreaker(1, DefaultTripCooldown, cfg.Clock)
+	failures := counterValue(t, "nbp_fetch_failures_total")
+	captureLog(t, logger.LevelInfo)
+
+	allStats, err := runPool(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("runPool() with a summary without rates failed: %s", err)
+	}
+	if allStats[0].Fetches != 1 {
+		t.Errorf("pool stats of %d fetches, want 1 as the summary without rates tells nothing", allStats[0].Fetches)
+	}
+	if got := counterValue(t, "nbp_fetch_failures_total"); got != failures {
+		t.Errorf("fetch failures counter went from %v to %v, want it unchanged", failures, got)
+	}
+	if err := cfg.Breaker.allow(); err != nil {
+		t.Errorf("breaker is open after a summary without rates: %s", err)
+	}
+}
+
+// slowResponsePattern matches slow response warning, capturing index of the worker and its latency
+var slowResponsePattern = regexp.MustCompile(`<worker-(\d+) [0-9a-f]+> Slow response: took (\d+) ms, above -latency-sla of 100ms`)
+
+func TestRunPoolWarnsAboutResponsesAboveLatencySla(t *testing.T) {
+	tests := []struct {
+		name     string
+		delay    time.Duration
+		wantWarn bool
+	}{
+		{"slow", 150 * time.Millisecond, true},
+		{"fast", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay := tt.delay
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(delay)
+				io.WriteString(w, summaryJson("eur", 4.6))
+			}))
+			t.Cleanup(server.Close)
+			cfg := newTestPoolConfig(1, server.URL)
+			cfg.LatencySla = 100 * time.Millisecond
+			slow := counterValue(t, "nbp_slow_responses_total")
+			log := captureLog(t, logger.LevelWarn)
+
+			if _, err := runPool(context.Background(), cfg); err != nil {
+				t.Fatalf("runPool() failed: %s", err)
+			}
+
+			warnings := slowResponsePattern.FindAllStringSubmatch(log.String(), -1)
+			if tt.wantWarn != (len(warnings) == 1) {
+				t.Fatalf("log =\n%s\nwant slow response warning %t", log.String(), tt.wantWarn)
+			}
+			if tt.wantWarn {
+				if elapsed, _ := strconv.Atoi(warnings[0][2]); warnings[0][1] != "0" || elapsed < 150 {
+					t.Errorf("warning of worker %s of %s ms, want worker 0 of at least 150 ms", warnings[0][1], warnings[0][2])
+				}
+			}
+			want := slow
+			if tt.wantWarn {
+				want++
+			}
+			if got := counterValue(t, "nbp_slow_responses_total"); got != want {
+				t.Errorf("slow responses counter went from %v to %v, want %v", slow, got, want)
+			}
+		})
+	}
+}
+
+func TestOrderedOutputLogsWorkersInIndexOrder(t *testing.T) {
+	const workers = 6
+
+	// workers finish in reverse order of their indexes, each one waits for the next one
+	done := make([]chan struct{}, workers)
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	cfg := newTestPoolConfig(workers, testApiUrl)
+	cfg.Fetcher = workerFetcherFunc(func(ctx context.Context, index int, t *target) (*fetchResult, error) {
+		defer close(done[index])
+		if index+1 < workers {
+			<-done[index+1]
+		}
+		if index == 2 {
+			return nil, errors.New("connection reset by peer")
+		}
+		result := okResult()
+		result.summary = testSummary(t.Currency, 4.6)
+		return result, nil
+	})
+	cfg.OrderedOutput = true
+	log := captureLog(t, logger.LevelInfo)
+
+	if _, err := runPool(context.Background(), cfg); err == nil {
+		t.Fatal("runPool() succeeded, want error of worker 2")
+	}
+
+	// consecutive lines of the same worker are taken once
+	var indexes []int
+	for _, line := range strings.Split(log.String(), "\n") {
+		match := workerTagPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		index, err := strconv.Atoi(match[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(indexes) == 0 || indexes[len(indexes)-1] != index {
+			indexes = append(indexes, index)
+		}
+	}
+
+	if want := []int{0, 1, 2, 3, 4, 5}; !reflect.DeepEqual(indexes, want) {
+		t.Errorf("workers logged in order %v, want %v:\n%s", indexes, want, log.String())
+	}
+}
+
+func TestRunPoolLogsNoDataTodayAtInfoLevel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "404 NotFound - Not Found - Brak danych", http.StatusNotFound)
+	}))
+	t.Cleanup(server.Close)
+	cfg := newTestPoolConfig(2, server.URL)
+	httpFetcher(cfg).Today = true
+
+	log := captureLog(t, logger.LevelInfo)
+	allStats, err := runPool(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("runPool() error = %v, want no data today to be no failure", err)
+	}
+	if allStats[0].Fetches != 0 {
+		t.Errorf("pool stats of %d fetches, want none of a day without publication", allStats[0].Fetches)
+	}
+	if got := strings.Count(log.String(), "No data today"); got != 2 {
+		t.Errorf("log has %d lines of no data today, want one per worker:\n%s", got, log.String())
+	}
+
+	warnings := captureLog(t, logger.LevelWarn)
+	if _, err := runPool(context.Background(), cfg); err != nil {
+		t.Fatalf("runPool() failed: %s", err)
+	}
+	if content := warnings.String(); strings.Contains(content, "No data today") {
+		t.Errorf("no data today is logged above info level:\n%s", content)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// WorkerError is a failure of a single worker of a requests pool
+type WorkerError struct {
+	Index    int
+	Currency string
+	Err      error
+}
+
+func (e *WorkerError) Error() string {
+	return fmt.Sprintf("worker-%d %s: %s", e.Index, e.Currency, e.Err)
+}
+
+func (e *WorkerError) Unwrap() error {
+	return e.Err
+}
+
+// PoolError aggregates failures of workers of a pool, so a pool with only some of its workers failed
+// is told apart from a successful one without dropping what went wrong.
+// errors.Is and errors.As match it when they match any of its worker errors.
+type PoolError struct {
+	// number of workers of the pool
+	Workers int
+	// failures ordered as workers reported them
+	Errs []*WorkerError
+}
+
+// Succeeded returns the number of workers of the pool which did not fail
+func (e *PoolError) Succeeded() int {
+	return e.Workers - len(e.Errs)
+}
+
+func (e *PoolError) Error() string {
+	messages := make([]string, 0, len(e.Errs))
+	for _, err := range e.Errs {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Sprintf("%d of %d workers failed: %s", len(e.Errs), e.Workers, strings.Join(messages, "; "))
+}
+
+func (e *PoolError) Is(target error) bool {
+	for _, err := range e.Errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *PoolError) As(target interface{}) bool {
+	for _, err := range e.Errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"spyrosoft-recruitment-task/base"
+	"testing"
+)
+
+func TestPoolError(t *testing.T) {
+	err := &PoolError{Workers: 4, Errs: []*WorkerError{
+		{Index: 3, Currency: "usd", Err: &base.ErrBadStatus{Code: http.StatusNotFound}},
+		{Index: 0, Currency: "eur", Err: context.DeadlineExceeded},
+	}}
+
+	if err.Succeeded() != 2 {
+		t.Errorf("Succeeded() = %d, want 2", err.Succeeded())
+	}
+	want := "2 of 4 workers failed: worker-3 usd: " + err.Errs[0].Err.Error() + "; worker-0 eur: context deadline exceeded"
+	if got := err.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		t.Error("errors.Is() does not match just the errors of workers")
+	}
+	var badStatus *base.ErrBadStatus
+	if !errors.As(err, &badStatus) || badStatus.Code != http.StatusNotFound {
+		t.Errorf("errors.As() of *base.ErrBadStatus = %v, want status 404 of worker 3", badStatus)
+	}
+	var workerErr *WorkerError
+	if !errors.As(err, &workerErr) || workerErr.Index != 3 {
+		t.Errorf("errors.As() of *WorkerError = %v, want the first failed worker", workerErr)
+	}
+}
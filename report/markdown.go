@@ -0,0 +1,112 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"spyrosoft-recruitment-task/base"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FormatMarkdown is the only report format, a GitHub-flavored markdown table per currency
+const FormatMarkdown = "markdown"
+
+// Section holds rates of a currency fetched by a pool
+type Section struct {
+	Currency string
+	// bounds or baseline telling whether a rate is in band
+	Scope base.Scope
+	// distinct rates ordered by effective date
+	Rates []*base.ExchangeRate
+}
+
+// WriteMarkdown writes a table of date, mid and whether the rate is in scope for every section
+func WriteMarkdown(w io.Writer, sections []Section) error {
+	var b bytes.Buffer
+	for _, section := range sections {
+		fmt.Fprintf(&b, "### %s (%s)\n\n", strings.ToUpper(section.Currency), section.Scope)
+
+		if len(section.Rates) == 0 {
+			b.WriteString("No rates fetched.\n\n")
+			continue
+		}
+
+		outOfScope := map[string]bool{}
+		for _, rate := range section.Scope.Classify(section.Rates) {
+			outOfScope[rate.No] = true
+		}
+
+		b.WriteString("| Date | Mid | In Band? |\n")
+		b.WriteString("| --- | ---: | :---: |\n")
+		for _, rate := range section.Rates {
+			date := ""
+			if rate.EffectiveDate != nil {
+				date = rate.EffectiveDate.Format("2006-01-02")
+			}
+
+			inBand := "yes"
+			if outOfScope[rate.No] {
+				inBand = "no"
+			}
+			fmt.Fprintf(&b, "| %s | %.4f | %s |\n", date, rate.Mid, inBand)
+		}
+		b.WriteString("\n")
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// MarkdownWriter writes report of every pool to a file, replacing the previous one or appending to it
+type MarkdownWriter struct {
+	path string
+	// reports of consecutive pools are appended under a heading with their time
+	append bool
+
+	mu sync.Mutex
+}
+
+func NewMarkdownWriter(path string, append bool) *MarkdownWriter {
+	return &MarkdownWriter{path: path, append: append}
+}
+
+// Write writes report of a pool finished at generatedAt
+func (mw *MarkdownWriter) Write(sections []Section, generatedAt time.Time) error {
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
+
+	var b bytes.Buffer
+	if mw.append {
+		fmt.Fprintf(&b, "## Pool at %s\n\n", generatedAt.Format(time.RFC3339))
+	} else {
+		fmt.Fprintf(&b, "# NBP Rates Report\n\nGenerated at %s.\n\n", generatedAt.Format(time.RFC3339))
+	}
+
+	err := WriteMarkdown(&b, sections)
+	if err != nil {
+		return err
+	}
+
+	if !mw.append {
+		err = os.WriteFile(mw.path, b.Bytes(), 0666)
+		if err != nil {
+			return fmt.Errorf("failed to write report file: %s", err)
+		}
+		return nil
+	}
+
+	file, err := os.OpenFile(mw.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return fmt.Errorf("failed to open report file: %s", err)
+	}
+	defer file.Close()
+
+	_, err = file.Write(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to append to report file: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package report
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"spyrosoft-recruitment-task/base"
+	"spyrosoft-recruitment-task/marshal"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newRate(no string, day int, mid float64) *base.ExchangeRate {
+	return &base.ExchangeRate{No: no, EffectiveDate: &marshal.CustomTime{Time: time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC)}, Mid: mid}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	sections := []Section{
+		{Currency: "eur", Scope: base.RateBounds{Min: 4.5, Max: 4.7}, Rates: []*base.ExchangeRate{
+			newRate("001/A/NBP/2024", 2, 4.4),
+			newRate("002/A/NBP/2024", 3, 4.6),
+			newRate("003/A/NBP/2024", 4, 4.8),
+		}},
+		{Currency: "usd", Scope: base.RateBounds{Min: 3.9, Max: 4.2}},
+	}
+
+	var buffer bytes.Buffer
+	err := WriteMarkdown(&buffer, sections)
+	if err != nil {
+		t.Fatalf("WriteMarkdown() failed: %s", err)
+	}
+
+	want := "### EUR (4.50 - 4.70 PLN)\n\n" +
+		"| Date | Mid | In Band? |\n" +
+		"| --- | ---: | :---: |\n" +
+		"| 2024-01-02 | 4.4000 | no |\n" +
+		"| 2024-01-03 | 4.6000 | yes |\n" +
+		"| 2024-01-04 | 4.8000 | no |\n\n" +
+		"### USD (3.90 - 4.20 PLN)\n\n" +
+		"No rates fetched.\n\n"
+	if got := buffer.String(); got != want {
+		t.Errorf("WriteMarkdown() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMarkdownWriterReplacesOrAppends(t *testing.T) {
+	sections := []Section{{Currency: "eur", Scope: base.RateBounds{Min: 4.5, Max: 4.7}, Rates: []*base.ExchangeRate{newRate("001/A/NBP/2024", 2, 4.6)}}}
+	first := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+
+	tests := []struct {
+		append bool
+		want   []string
+	}{
+		{false, []string{"# NBP Rates Report\n\nGenerated at 2024-01-02T13:00:00Z.\n\n"}},
+		{true, []string{"## Pool at 2024-01-02T12:00:00Z\n\n", "## Pool at 2024-01-02T13:00:00Z\n\n"}},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "report.md")
+		writer := NewMarkdownWriter(path, tt.append)
+		for _, generatedAt := range []time.Time{first, second} {
+			if err := writer.Write(sections, generatedAt); err != nil {
+				t.Fatalf("Write() failed: %s", err)
+			}
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Count(string(content), "| 2024-01-02 | 4.6000 | yes |\n"); got != len(tt.want) {
+			t.Errorf("report of append %t has %d tables, want %d:\n%s", tt.append, got, len(tt.want), content)
+		}
+		for _, heading := range tt.want {
+			if !strings.Contains(string(content), heading) {
+				t.Errorf("report of append %t is missing %q:\n%s", tt.append, heading, content)
+			}
+		}
+	}
+}
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"spyrosoft-recruitment-task/base"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	DateLayout = "2006-01-02"
+
+	// NBP refuses date range queries longer than that
+	MaxRangeDays = 367
+
+	bodySnippetLength = 200
+)
+
+// buildApiUrl returns URL of the last cfg.Count rates, or of rates between cfg.From and cfg.To when both are set
+func buildApiUrl(cfg Config) (string, error) {
+	baseUrl := strings.TrimSuffix(cfg.ApiBaseUrl, "/")
+	table := strings.ToLower(cfg.Table)
+	currency := strings.ToLower(strings.TrimSpace(cfg.Currency))
+
+	if cfg.Today {
+		return fmt.Sprintf("%s/%s/%s/today/", baseUrl, table, currency), nil
+	}
+
+	if cfg.From == "" && cfg.To == "" {
+		return fmt.Sprintf("%s/%s/%s/last/%d/", baseUrl, table, currency, cfg.Count), nil
+	}
+
+	from, to, err := parseDateRange(cfg.From, cfg.To)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s/%s/%s/%s/%s/", baseUrl, table, currency, from.Format(DateLayout), to.Format(DateLayout)), nil
+}
+
+func parseDateRange(fromValue string, toValue string) (time.Time, time.Time, error) {
+	if fromValue == "" || toValue == "" {
+		return time.Time{}, time.Time{}, errors.New("both -from and -to dates are required for a date range query")
+	}
+
+	from, err := time.Parse(DateLayout, fromValue)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("-from date %q is not in YYYY-MM-DD format", fromValue)
+	}
+
+	to, err := time.Parse(DateLayout, toValue)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("-to date %q is not in YYYY-MM-DD format", toValue)
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("-from date %s is after -to date %s", fromValue, toValue)
+	}
+
+	// both ends of the range are included
+	days := int(to.Sub(from).Hours()/24) + 1
+	if days > MaxRangeDays {
+		return time.Time{}, time.Time{}, fmt.Errorf("date range of %d days exceeds NBP limit of %d days", days, MaxRangeDays)
+	}
+
+	return from, to, nil
+}
+
+// parseProxyUrl parses -proxy value, nil is returned when it is empty
+func parseProxyUrl(value string) (*url.URL, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	proxyUrl, err := url.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("-proxy %q is not a valid URL: %s", value, err)
+	}
+
+	switch proxyUrl.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("-proxy %q has unsupported scheme, expected http, https or socks5", value)
+	}
+
+	if proxyUrl.Host == "" {
+		return nil, fmt.Errorf("-proxy %q has no host", value)
+	}
+
+	return proxyUrl, nil
+}
+
+// newTlsConfig returns TLS config trusting CAs of caFile in addition to system ones,
+// nil is returned when defaults are kept
+func newTlsConfig(caFile string, insecureSkipVerify bool) (*tls.Config, error) {
+	if caFile == "" && !insecureSkipVerify {
+		return nil, nil
+	}
+
+	config := &tls.Config{InsecureSkipVerify: insecureSkipVerify}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read -ca-file: %s", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("-ca-file %s contains no PEM certificates", caFile)
+		}
+		config.RootCAs = pool
+	}
+
+	return config, nil
+}
+
+// newHttpClient returns client keeping connections of all workers alive,
+// requests go through proxyUrl when it is set, otherwise through HTTP_PROXY/HTTPS_PROXY if any,
+// nil tlsConfig keeps default verification against system CAs
+func newHttpClient(workers int, proxyUrl *url.URL, tlsConfig *tls.Config) *http.Client {
+	proxy := http.ProxyFromEnvironment
+	if proxyUrl != nil {
+		proxy = http.ProxyURL(proxyUrl)
+	}
+
+	transport := &http.Transport{
+		Proxy:               proxy,
+		TLSClientConfig:     tlsConfig,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: workers,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	return &http.Client{Transport: transport}
+}
+
+func newRateLimiter(requestsPerSecond float64, burst int) *rate.Limiter {
+	if requestsPerSecond == 0 {
+		return rate.NewLimiter(rate.Inf, burst)
+	}
+
+	return rate.NewLimiter(rate.Limit(requestsPerSecond), burst)
+}
+
+const (
+	ResponseFormatJson = "json"
+	ResponseFormatXml  = "xml"
+)
+
+// requestOptions are the configurable parts of an API request
+type requestOptions struct {
+	// replaces host taken from the URL when not empty
+	Host      string
+	UserAgent string
+	// ResponseFormatJson or ResponseFormatXml
+	Format string
+}
+
+// newRequestOptions returns request options configured by cfg
+func newRequestOptions(cfg Config) requestOptions {
+	return requestOptions{
+		Host:      cfg.Host,
+		UserAgent: cfg.UserAgent,
+		Format:    cfg.ResponseFormat,
+	}
+}
+
+// prepareHttpRequest builds GET request of apiUrl with headers set according to opts,
+// it is called once per target at startup, so a bad URL or header fails before any pool runs
+func prepareHttpRequest(ctx context.Context, apiUrl string, opts requestOptions) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", apiUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare HTTP GET request: %s", err)
+	}
+
+	// url.Parse accepts relative and scheme-less URLs, which client would reject on every request
+	if (req.URL.Scheme != "http" && req.URL.Scheme != "https") || req.URL.Host == "" {
+		return nil, fmt.Errorf("API URL %q is not an absolute http or https URL", apiUrl)
+	}
+	if strings.ContainsAny(req.URL.Path, " \t") {
+		return nil, fmt.Errorf("API URL %q contains whitespace, check -currency and -table", apiUrl)
+	}
+
+	if !isValidHeaderValue(opts.Host) {
+		return nil, fmt.Errorf("-host %q contains control characters", opts.Host)
+	}
+	if !isValidHeaderValue(opts.UserAgent) {
+		return nil, fmt.Errorf("-user-agent %q contains control characters", opts.UserAgent)
+	}
+
+	// client ignores Host set in header map, only req.Host overrides the URL host
+	if opts.Host != "" {
+		req.Host = opts.Host
+	}
+
+	addHeaders(req, opts)
+
+	return req, nil
+}
+
+// isValidHeaderValue tells whether value can be sent in a header, client fails requests with CR, LF or other control characters
+func isValidHeaderValue(value string) bool {
+	for _, r := range value {
+		if r < ' ' && r != '\t' || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+func addHeaders(req *http.Request, opts requestOptions) {
+	req.Header.Set("User-Agent", opts.UserAgent)
+
+	// NBP picks response format from Accept, default one is not guaranteed
+	if opts.Format == ResponseFormatXml {
+		req.Header.Set("Accept", "application/xml")
+	} else {
+		req.Header.Set("Accept", "application/json")
+	}
+	req.Header.Set("Accept-Language", "pl-PL,pl;q=0.9,en-US;q=0.8,en;q=0.7")
+
+	//gzip encoding results in a much smaller response body
+	req.Header.Set("Accept-Encoding", "deflate, gzip")
+}
+
+// ErrNoDataToday is returned for 404 Not Found of a -today query, which NBP answers on days without publication,
+// e.g. weekends and holidays, so it is not a failure of the API
+var ErrNoDataToday = errors.New("no rates published today")
+
+// ErrResponseTooLarge is returned by body reader once more than -max-body-bytes are read
+var ErrResponseTooLarge = errors.New("response too large")
+
+// newBodyReader returns reader of response body decompressed according to Content-Encoding,
+// body is decompressed while being read, without buffering it whole in memory.
+// Both the compressed and the decompressed stream are limited to maxBytes.
+func newBodyReader(response *http.Response, maxBytes int64) (io.ReadCloser, error) {
+	response.Body = &limitedBody{Reader: &limitedReader{r: response.Body, remaining: maxBytes}, Closer: response.Body}
+
+	reader, err := newDecompressingReader(response)
+	if err != nil {
+		return nil, err
+	}
+	return &limitedBody{Reader: &limitedReader{r: reader, remaining: maxBytes}, Closer: reader}, nil
+}
+
+func newDecompressingReader(response *http.Response) (io.ReadCloser, error) {
+	encoding := strings.ToLower(strings.TrimSpace(response.Header.Get("Content-Encoding")))
+
+	switch encoding {
+	case "gzip":
+		gzipReader, err := newGzipReader(response.Body)
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: empty gzip body", base.ErrDecompress)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: gzip header: %s", base.ErrDecompress, err)
+		}
+		return gzipReader, nil
+	case "deflate":
+		// "deflate" should be zlib wrapped, but some servers send raw deflate stream
+		bufferedBody := bufio.NewReader(response.Body)
+		header, err := bufferedBody.Peek(2)
+		if err == nil && isZlibHeader(header) {
+			zlibReader, err := zlib.NewReader(bufferedBody)
+			if err != nil {
+				return nil, fmt.Errorf("%w: zlib header: %s", base.ErrDecompress, err)
+			}
+			return zlibReader, nil
+		}
+		return flate.NewReader(bufferedBody), nil
+	default:
+		// server ignored Accept-Encoding, body is not compressed
+		return io.NopCloser(response.Body), nil
+	}
+}
+
+// gzipReaders keeps gzip readers of fully read bodies, so frequent pools do not allocate a new one per response
+var gzipReaders sync.Pool
+
+// newGzipReader returns reader of gzip stream r, reusing a pooled gzip.Reader when there is one
+func newGzipReader(r io.Reader) (io.ReadCloser, error) {
+	reader, ok := gzipReaders.Get().(*gzip.Reader)
+	if !ok {
+		reader, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return &pooledGzipReader{reader: reader}, nil
+	}
+
+	// reader failing to reset is left for the garbage collector, its state is unknown
+	err := reader.Reset(r)
+	if err != nil {
+		return nil, err
+	}
+	return &pooledGzipReader{reader: reader}, nil
+}
+
+// pooledGzipReader puts its gzip.Reader back to gzipReaders once closed,
+// reader of a body which was not read to the end is dropped instead
+type pooledGzipReader struct {
+	reader *gzip.Reader
+	// stream was read up to io.EOF, checksum included
+	done bool
+}
+
+func (r *pooledGzipReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if err == io.EOF {
+		r.done = true
+	}
+	return n, err
+}
+
+func (r *pooledGzipReader) Close() error {
+	if r.reader == nil {
+		return nil
+	}
+
+	err := r.reader.Close()
+	if r.done && err == nil {
+		gzipReaders.Put(r.reader)
+	}
+	r.reader = nil
+	return err
+}
+
+// limitedBody reads through a limitedReader and closes the stream it limits
+type limitedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// limitedReader is io.LimitReader which fails with ErrResponseTooLarge instead of ending the stream at the limit
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// stream exactly as long as the limit is still accepted
+		var probe [1]byte
+		n, err := l.r.Read(probe[:])
+		if n > 0 {
+			return 0, ErrResponseTooLarge
+		}
+		return 0, err
+	}
+
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// isZlibHeader checks compression method and checksum of zlib stream header (RFC 1950)
+func isZlibHeader(header []byte) bool {
+	cmf, flg := header[0], header[1]
+	return cmf&0x0f == 8 && (uint16(cmf)<<8|uint16(flg))%31 == 0
+}
+
+// readBodySnippet returns the beginning of response body for error messages
+func readBodySnippet(response *http.Response) string {
+	snippet, err := io.ReadAll(io.LimitReader(response.Body, bodySnippetLength))
+	if err != nil {
+		return fmt.Sprintf("<failed to read body: %s>", err)
+	}
+
+	return strings.TrimSpace(string(snippet))
+}
@@ -0,0 +1,570 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"spyrosoft-recruitment-task/base"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBuildApiUrlNormalizesCurrency(t *testing.T) {
+	for _, currency := range []string{"usd", "USD", "Usd", " usd "} {
+		got, err := buildApiUrl(loadTestConfig(t, "-currency", currency, "-count", "10"))
+		if want := "http://api.nbp.pl/api/exchangerates/rates/a/usd/last/10/"; err != nil || got != want {
+			t.Errorf("buildApiUrl() of currency %q = %s, %v, want %s", currency, got, err, want)
+		}
+	}
+}
+
+func TestBuildApiUrlOfCount(t *testing.T) {
+	got, err := buildApiUrl(loadTestConfig(t, "-count", "255"))
+	if want := "http://api.nbp.pl/api/exchangerates/rates/a/eur/last/255/"; err != nil || got != want {
+		t.Errorf("buildApiUrl() = %s, %v, want %s", got, err, want)
+	}
+}
+
+func TestBuildApiUrlOfTable(t *testing.T) {
+	got, err := buildApiUrl(loadTestConfig(t, "-table", "C", "-currency", "usd", "-count", "10"))
+	if want := "http://api.nbp.pl/api/exchangerates/rates/c/usd/last/10/"; err != nil || got != want {
+		t.Errorf("buildApiUrl() of table C = %s, %v, want %s", got, err, want)
+	}
+}
+
+func TestIsTableACurrency(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"GBP", true},
+		{" usd ", true},
+		{"xyz", false},
+		{"", false},
+		{"euro", false},
+	}
+
+	for _, tt := range tests {
+		if got := base.IsTableACurrency(tt.code); got != tt.want {
+			t.Errorf("IsTableACurrency(%q) = %t, want %t", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestIsTableCurrency(t *testing.T) {
+	tests := []struct {
+		table string
+		code  string
+		want  bool
+	}{
+		{base.TableA, "thb", true},
+		{base.TableB, "afn", true},
+		{base.TableB, "euro", false},
+		{base.TableC, "USD", true},
+		{base.TableC, "thb", false},
+		{"d", "usd", false},
+	}
+
+	for _, tt := range tests {
+		if got := base.IsTableCurrency(tt.table, tt.code); got != tt.want {
+			t.Errorf("IsTableCurrency(%q, %q) = %t, want %t", tt.table, tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestDecompressResponseOfEveryEncoding(t *testing.T) {
+	var want base.ExchangeRatesSummary
+	if err := json.Unmarshal([]byte(testSummaryJson), &want); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, encoding := range []string{"gzip", "deflate", "raw-deflate", ""} {
+		server := newEncodedNbpServer(t, encoding, testSummaryJson)
+
+		req, err := prepareHttpRequest(context.Background(), server.URL, requestOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader, err := newBodyReader(resp, DefaultMaxBodyBytes)
+		if err != nil {
+			t.Fatalf("newBodyReader() of encoding %q failed: %s", encoding, err)
+		}
+		var got base.ExchangeRatesSummary
+		err = json.NewDecoder(reader).Decode(&got)
+		reader.Close()
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("body of encoding %q is not JSON: %s", encoding, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("summary of encoding %q = %+v, want %+v", encoding, got, want)
+		}
+	}
+}
+
+func TestBuildApiUrlOfToday(t *testing.T) {
+	got, err := buildApiUrl(loadTestConfig(t, "-today", "-currency", "USD", "-table", "c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "http://api.nbp.pl/api/exchangerates/rates/c/usd/today/"; got != want {
+		t.Errorf("buildApiUrl() = %s, want %s", got, want)
+	}
+}
+
+func TestBuildApiUrlOfDateRange(t *testing.T) {
+	got, err := buildApiUrl(loadTestConfig(t, "-from", "2024-07-01", "-to", "2024-07-05"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "http://api.nbp.pl/api/exchangerates/rates/a/eur/2024-07-01/2024-07-05/"; got != want {
+		t.Errorf("buildApiUrl() = %s, want %s", got, want)
+	}
+
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"-from", "2024-07-01"}, "both -from and -to dates are required for a date range query"},
+		{[]string{"-to", "2024-07-05"}, "both -from and -to dates are required for a date range query"},
+		{[]string{"-from", "01.07.2024", "-to", "2024-07-05"}, `-from date "01.07.2024" is not in YYYY-MM-DD format`},
+		{[]string{"-from", "2024-07-01", "-to", "2024-7-5"}, `-to date "2024-7-5" is not in YYYY-MM-DD format`},
+		{[]string{"-from", "2024-07-05", "-to", "2024-07-01"}, "-from date 2024-07-05 is after -to date 2024-07-01"},
+		{[]string{"-from", "2024-01-01", "-to", "2025-01-02"}, "date range of 368 days exceeds NBP limit of 367 days"},
+	}
+
+	for _, tt := range tests {
+		_, err := buildApiUrl(loadTestConfig(t, tt.args...))
+		if err == nil || err.Error() != tt.wantErr {
+			t.Errorf("buildApiUrl() of %q = %v, want %s", tt.args, err, tt.wantErr)
+		}
+	}
+
+	// both ends are included, so a leap year and a day is the longest range
+	if _, err := buildApiUrl(loadTestConfig(t, "-from", "2024-01-01", "-to", "2025-01-01")); err != nil {
+		t.Errorf("buildApiUrl() of 367 days failed: %s", err)
+	}
+}
+
+// encodedResponse returns 200 OK response of body compressed according to encoding, as compressBody does
+func encodedResponse(encoding string, body string) *http.Response {
+	header := http.Header{}
+	if encoding != "" {
+		header.Set("Content-Encoding", strings.TrimPrefix(encoding, "raw-"))
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     header,
+		Body:       io.NopCloser(bytes.NewReader(compressBody(encoding, body))),
+	}
+}
+
+// largeSummaryJson is response of the most rates a single request can ask for
+func largeSummaryJson() string {
+	mids := make([]float64, MaxCount)
+	for i := range mids {
+		mids[i] = 4.3 + float64(i%40)/100
+	}
+	return summaryJson("eur", mids...)
+}
+
+// decodeBuffered decodes response like it was before streaming, reading the whole body first
+func decodeBuffered(resp *http.Response) (base.ExchangeRatesSummary, error) {
+	gzipReader, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return base.ExchangeRatesSummary{}, err
+	}
+	defer gzipReader.Close()
+
+	content, err := io.ReadAll(gzipReader)
+	if err != nil {
+		return base.ExchangeRatesSummary{}, err
+	}
+	var summary base.ExchangeRatesSummary
+	err = json.Unmarshal(content, &summary)
+	return summary, err
+}
+
+// decodeStreamed decodes response like HTTPFetcher does, straight from the decompressing reader
+func decodeStreamed(resp *http.Response) (base.ExchangeRatesSummary, error) {
+	reader, err := newBodyReader(resp, DefaultMaxBodyBytes)
+	if err != nil {
+		return base.ExchangeRatesSummary{}, err
+	}
+	defer reader.Close()
+
+	return decodeSummary(reader, ResponseFormatJson, base.TableA, "")
+}
+
+func TestStreamedDecodeMatchesBufferedDecode(t *testing.T) {
+	body := largeSummaryJson()
+
+	buffered, err := decodeBuffered(encodedResponse("gzip", body))
+	if err != nil {
+		t.Fatalf("buffered decode failed: %s", err)
+	}
+	streamed, err := decodeStreamed(encodedResponse("gzip", body))
+	if err != nil {
+		t.Fatalf("streamed decode failed: %s", err)
+	}
+
+	if !reflect.DeepEqual(streamed, buffered) {
+		t.Errorf("streamed summary differs from buffered one:\n%+v\n%+v", streamed, buffered)
+	}
+}
+
+func BenchmarkDecodeGzippedBody(b *testing.B) {
+	// compressed once, so only decoding is measured
+	compressed := compressBody("gzip", largeSummaryJson())
+	header := http.Header{"Content-Encoding": {"gzip"}}
+
+	decoders := []struct {
+		name   string
+		decode func(*http.Response) (base.ExchangeRatesSummary, error)
+	}{
+		{"buffered", decodeBuffered},
+		{"streamed", decodeStreamed},
+	}
+	for _, decoder := range decoders {
+		b.Run(decoder.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				resp := &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(bytes.NewReader(compressed))}
+				_, err := decoder.decode(resp)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestBodyReaderClosesGzipReader(t *testing.T) {
+	reader, err := newBodyReader(encodedResponse("gzip", summaryJson("eur", 4.6)), DefaultMaxBodyBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(reader); err != nil {
+		t.Fatal(err)
+	}
+	if err := reader.Close(); err != nil {
+		t.Fatalf("Close() failed: %s", err)
+	}
+
+	gzipReader, ok := reader.(*limitedBody).Closer.(*pooledGzipReader)
+	if !ok {
+		t.Fatalf("body is read through %T, want gzip reader", reader.(*limitedBody).Closer)
+	}
+	if gzipReader.reader != nil {
+		t.Errorf("gzip reader is kept after Close(), want it closed and released")
+	}
+}
+
+func TestConcurrentDecompressionsThroughPooledGzipReaders(t *testing.T) {
+	const decompressions = 200
+
+	var wg sync.WaitGroup
+	for i := 0; i < decompressions; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			// every body differs, so a reader reset onto a wrong stream would be noticed
+			body := summaryJson("eur", 4+float64(i)/1000, 4.6)
+			reader, err := newBodyReader(encodedResponse("gzip", body), DefaultMaxBodyBytes)
+			if err != nil {
+				t.Errorf("decompression %d failed: %s", i, err)
+				return
+			}
+			defer reader.Close()
+
+			// some bodies are left unfinished, so their readers are dropped instead of pooled
+			if i%10 == 0 {
+				io.CopyN(io.Discard, reader, 10)
+				return
+			}
+			got, err := io.ReadAll(reader)
+			if err != nil {
+				t.Errorf("decompression %d failed: %s", i, err)
+				return
+			}
+			if string(got) != body {
+				t.Errorf("decompression %d = %s, want %s", i, got, body)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestPooledGzipReaderOfInvalidStream(t *testing.T) {
+	// alternating valid and invalid bodies makes the invalid ones reset readers of the pool
+	for i := 0; i < 5; i++ {
+		reader, err := newBodyReader(encodedResponse("gzip", summaryJson("eur", 4.6)), DefaultMaxBodyBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, reader)
+		reader.Close()
+
+		response := encodedResponse("", "not gzip")
+		response.Header.Set("Content-Encoding", "gzip")
+		_, err = newBodyReader(response, DefaultMaxBodyBytes)
+		if !errors.Is(err, base.ErrDecompress) {
+			t.Fatalf("newBodyReader() of invalid gzip stream error = %v, want %v", err, base.ErrDecompress)
+		}
+	}
+}
+
+func BenchmarkGzipReader(b *testing.B) {
+	compressed := compressBody("gzip", summaryJson("eur", 4.55, 4.6))
+
+	readers := []struct {
+		name      string
+		newReader func(io.Reader) (io.ReadCloser, error)
+	}{
+		{"new", func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) }},
+		{"pooled", newGzipReader},
+	}
+	for _, reader := range readers {
+		b.Run(reader.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				r, err := reader.newReader(bytes.NewReader(compressed))
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := io.Copy(io.Discard, r); err != nil {
+					b.Fatal(err)
+				}
+				r.Close()
+			}
+		})
+	}
+}
+
+func TestParseProxyUrl(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"http://proxy.local:3128", "http://proxy.local:3128", false},
+		{"socks5://127.0.0.1:1080", "socks5://127.0.0.1:1080", false},
+		{"ftp://proxy.local", "", true},
+		{"http://", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseProxyUrl(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseProxyUrl(%q) error = %v, want error %t", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != nil && got.String() != tt.want || got == nil && tt.want != "" {
+			t.Errorf("parseProxyUrl(%q) = %v, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+// proxyRequests runs a pool of a worker fetching from NBP at unresolvable host through proxyUrl
+func proxyRequests(t *testing.T, proxyUrl *url.URL) error {
+	t.Helper()
+
+	apiUrl, err := buildApiUrl(Config{ApiBaseUrl: "http://nbp.invalid/api/", Table: base.TableA, Currency: DefaultCurrency, Count: DefaultCount})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := newTestPoolConfig(1, apiUrl)
+	httpFetcher(cfg).Client = newHttpClient(cfg.Workers, proxyUrl, nil)
+
+	_, err = runPool(context.Background(), cfg)
+	return err
+}
+
+func TestHttpClientRequestsThroughHttpProxy(t *testing.T) {
+	requested := make(chan string, 1)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested <- r.URL.String()
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, summaryJson("eur", 4.6))
+	}))
+	t.Cleanup(proxy.Close)
+	proxyUrl, _ := url.Parse(proxy.URL)
+
+	if err := proxyRequests(t, proxyUrl); err != nil {
+		t.Fatalf("pool through proxy failed: %s", err)
+	}
+	if got := <-requested; !strings.HasPrefix(got, "http://nbp.invalid/api/a/eur/") {
+		t.Errorf("proxy was requested %q, want absolute URL of NBP API", got)
+	}
+}
+
+// serveSocks5 answers a single no-auth SOCKS5 CONNECT by connecting it to target, whatever address was requested
+func serveSocks5(t *testing.T, listener net.Listener, target string, requested chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// greeting of version and auth methods, answered with no auth
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+	io.ReadFull(conn, make([]byte, header[1]))
+	conn.Write([]byte{5, 0})
+
+	// CONNECT request of version, command, reserved, address type, address and port
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return
+	}
+	var host string
+	switch request[3] {
+	case 1:
+		ip := make([]byte, 4)
+		io.ReadFull(conn, ip)
+		host = net.IP(ip).String()
+	case 3:
+		length := make([]byte, 1)
+		io.ReadFull(conn, length)
+		name := make([]byte, length[0])
+		io.ReadFull(conn, name)
+		host = string(name)
+	default:
+		t.Errorf("unexpected SOCKS5 address type %d", request[3])
+		return
+	}
+	port := make([]byte, 2)
+	io.ReadFull(conn, port)
+	requested <- net.JoinHostPort(host, fmt.Sprint(int(port[0])<<8|int(port[1])))
+
+	upstream, err := net.Dial("tcp", target)
+	if err != nil {
+		t.Errorf("failed to connect to target: %s", err)
+		return
+	}
+	defer upstream.Close()
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+
+	go io.Copy(upstream, conn)
+	io.Copy(conn, upstream)
+}
+
+func TestHttpClientRequestsThroughSocks5Proxy(t *testing.T) {
+	server := newEncodedNbpServer(t, "", summaryJson("eur", 4.6))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	requested := make(chan string, 1)
+	go serveSocks5(t, listener, server.Listener.Addr().String(), requested)
+
+	if err := proxyRequests(t, &url.URL{Scheme: "socks5", Host: listener.Addr().String()}); err != nil {
+		t.Fatalf("pool through proxy failed: %s", err)
+	}
+	if got := <-requested; got != "nbp.invalid:80" {
+		t.Errorf("proxy was asked to connect to %q, want nbp.invalid:80", got)
+	}
+}
+
+func TestHttpClientTrustsTlsServerOnlyWhenConfigured(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, summaryJson("eur", 4.6))
+	}))
+	t.Cleanup(server.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name               string
+		caFile             string
+		insecureSkipVerify bool
+		wantErr            bool
+	}{
+		{"default", "", false, true},
+		{"trusted CA", caFile, false, false},
+		{"skipped verification", "", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig, err := newTlsConfig(tt.caFile, tt.insecureSkipVerify)
+			if err != nil {
+				t.Fatalf("newTlsConfig() failed: %s", err)
+			}
+			client := newHttpClient(1, nil, tlsConfig)
+
+			resp, err := client.Get(server.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GET of TLS server error = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewTlsConfigRejectsCaFileWithoutCertificates(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	err := os.WriteFile(caFile, []byte("not a certificate"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := newTlsConfig(caFile, false); err == nil || !strings.Contains(err.Error(), "contains no PEM certificates") {
+		t.Errorf("newTlsConfig() error = %v, want file without PEM certificates rejected", err)
+	}
+	if _, err := newTlsConfig(filepath.Join(t.TempDir(), "missing.pem"), false); err == nil {
+		t.Errorf("newTlsConfig() of missing file succeeded, want error")
+	}
+}
+
+func TestPrepareHttpRequestRejectsInvalidConfiguration(t *testing.T) {
+	tests := []struct {
+		name    string
+		apiUrl  string
+		opts    requestOptions
+		wantErr string
+	}{
+		{"relative URL", "api.nbp.pl/api/exchangerates/rates/a/eur/", requestOptions{}, "is not an absolute http or https URL"},
+		{"unsupported scheme", "ftp://api.nbp.pl/a/eur/", requestOptions{}, "is not an absolute http or https URL"},
+		{"whitespace in path", "http://api.nbp.pl/a/e ur/", requestOptions{}, "contains whitespace"},
+		{"control character in host", "http://api.nbp.pl/a/eur/", requestOptions{Host: "api.nbp.pl\r\nX-Injected: 1"}, "-host"},
+		{"control character in user agent", "http://api.nbp.pl/a/eur/", requestOptions{UserAgent: "rates\x00"}, "-user-agent"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := prepareHttpRequest(context.Background(), tt.apiUrl, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("prepareHttpRequest() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+type requestIdKey struct{}
+
+// newRequestId returns short random hex id correlating all log lines of a single worker
+func newRequestId() string {
+	id := make([]byte, 4)
+	_, err := rand.Read(id)
+	if err != nil {
+		return "00000000"
+	}
+	return hex.EncodeToString(id)
+}
+
+func withRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, id)
+}
+
+// requestIdFrom returns request id of the worker running ctx, empty when there is none
+func requestIdFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey{}).(string)
+	return id
+}
+
+// workerTag prefixes log lines of a worker with its index and request id
+func workerTag(ctx context.Context, index int) string {
+	return formatWorkerTag(index, requestIdFrom(ctx))
+}
+
+func formatWorkerTag(index int, requestId string) string {
+	return fmt.Sprintf("<worker-%d %s>", index, requestId)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"spyrosoft-recruitment-task/logger"
+	"strings"
+	"testing"
+)
+
+// workerTagPattern matches worker tag of a log line, capturing index and request id
+var workerTagPattern = regexp.MustCompile(`<worker-(\d+) ([0-9a-f]*)>`)
+
+func TestWorkerLinesShareRequestId(t *testing.T) {
+	const workers = 4
+	server := newEncodedNbpServer(t, "", summaryJson("eur", 4.6))
+	// verbose headers are logged by the fetch, request info once the pool receives the result
+	cfg := newTestPoolConfig(workers, server.URL)
+	cfg.Verbose = true
+	log := captureLog(t, logger.LevelDebug)
+
+	if _, err := runPool(context.Background(), cfg); err != nil {
+		t.Fatalf("runPool() failed: %s", err)
+	}
+
+	ids := map[string]string{}
+	lines := map[string]int{}
+	for _, line := range strings.Split(log.String(), "\n") {
+		match := workerTagPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		index, id := match[1], match[2]
+		if id == "" {
+			t.Errorf("line of worker %s has no request id: %q", index, line)
+			continue
+		}
+		if previous, ok := ids[index]; ok && previous != id {
+			t.Errorf("worker %s logged request ids %s and %s, want a single one", index, previous, id)
+		}
+		ids[index] = id
+		lines[index]++
+	}
+
+	if len(ids) != workers {
+		t.Fatalf("log has lines of %d workers, want %d", len(ids), workers)
+	}
+	seen := map[string]bool{}
+	for index, id := range ids {
+		if seen[id] {
+			t.Errorf("request id %s of worker %s is shared with another worker", id, index)
+		}
+		seen[id] = true
+		// request and response headers along with request info
+		if lines[index] < 3 {
+			t.Errorf("worker %s logged %d lines, want its headers and request info", index, lines[index])
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	DefaultMaxRetries = 3
+	// more retries would only keep a worker waiting on the capped backoff of an endpoint that is down
+	MaxRetries = 10
+
+	// longest Retry-After of a 429 response which is waited for, longer ones are cut to it
+	DefaultMaxRetryAfter = 10 * time.Second
+
+	retryBaseDelay = 100 * time.Millisecond
+	// backoff stops doubling here, so a large attempt never overflows the delay
+	retryMaxDelay = 10 * time.Second
+)
+
+// doWithRetry performs the request, retrying network errors and 5xx responses with exponential backoff,
+// 429 responses are retried after their Retry-After, up to maxRetryAfter.
+// Any other response is returned as is, including other 4xx ones.
+// Every attempt waits for the rate limiter, so retries of a failing API stay within the limit too.
+// Waits between attempts are measured by clock.
+func doWithRetry(ctx context.Context, client *http.Client, limiter *rate.Limiter, clock Clock, req *http.Request, maxRetries int, maxRetryAfter time.Duration) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		err := limiter.Wait(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("aborted while waiting for rate limiter: %w", err)
+		}
+
+		resp, err := client.Do(req)
+		if err == nil && resp.StatusCode < http.StatusInternalServerError && resp.StatusCode != http.StatusTooManyRequests {
+			return resp, nil
+		}
+
+		if attempt >= maxRetries {
+			if err != nil {
+				return nil, fmt.Errorf("giving up after %d attempts: %w", attempt+1, err)
+			}
+			return resp, nil
+		}
+
+		delay := retryBackoff(attempt)
+		if resp != nil {
+			if wait, ok := retryAfter(resp, clock.Now()); ok && resp.StatusCode == http.StatusTooManyRequests {
+				delay = wait
+				if delay > maxRetryAfter {
+					delay = maxRetryAfter
+				}
+			}
+
+			// drop the failed response, connection is released once body is closed
+			resp.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("retry aborted: %w", ctx.Err())
+		case <-clock.After(delay):
+		}
+	}
+}
+
+// retryBackoff returns 100ms, 200ms, 400ms, ... up to retryMaxDelay, plus up to 50% of random jitter
+func retryBackoff(attempt int) time.Duration {
+	delay := retryBaseDelay
+	for i := 0; i < attempt && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay / 2)))
+	return delay + jitter
+}
+
+// retryAfter returns delay requested by Retry-After header of resp, given either in seconds or as HTTP date,
+// false is returned when the header is missing or invalid
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	// date already passed means retrying right away
+	if wait := date.Sub(now); wait > 0 {
+		return wait, true
+	}
+	return 0, true
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoWithRetryRecoversFromFlakyServer(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxRetries int
+		wantStatus int
+		wantCalls  int
+	}{
+		{"succeeds on third attempt", 3, http.StatusOK, 3},
+		{"gives up with last failed response", 1, http.StatusInternalServerError, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				calls++
+				// fails twice, then succeeds
+				if calls <= 2 {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				w.Write([]byte(testSummaryJson))
+			}))
+			defer server.Close()
+
+			req, err := http.NewRequest("GET", server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := doWithRetry(context.Background(), &http.Client{}, newRateLimiter(0, 1), realClock{}, req, tt.maxRetries, DefaultMaxRetryAfter)
+			if err != nil {
+				t.Fatalf("doWithRetry() failed: %s", err)
+			}
+			resp.Body.Close()
+
+			mu.Lock()
+			defer mu.Unlock()
+			if resp.StatusCode != tt.wantStatus || calls != tt.wantCalls {
+				t.Errorf("got status %d after %d attempts, want %d after %d", resp.StatusCode, calls, tt.wantStatus, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDoWithRetryDoesNotRetryClientErrors(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequest("GET", server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := doWithRetry(context.Background(), &http.Client{}, newRateLimiter(0, 1), realClock{}, req, DefaultMaxRetries, DefaultMaxRetryAfter)
+	if err != nil {
+		t.Fatalf("doWithRetry() failed: %s", err)
+	}
+	resp.Body.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if resp.StatusCode != http.StatusNotFound || calls != 1 {
+		t.Errorf("got status %d after %d attempts, want 404 after 1", resp.StatusCode, calls)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{2, 400 * time.Millisecond},
+		{7, retryMaxDelay},
+		// would overflow the shifted base delay
+		{40, retryMaxDelay},
+		{1000, retryMaxDelay},
+	}
+
+	for _, tt := range tests {
+		// jitter adds up to half of the delay
+		if got := retryBackoff(tt.attempt); got < tt.want || got > tt.want*3/2 {
+			t.Errorf("retryBackoff(%d) = %s, want between %s and %s", tt.attempt, got, tt.want, tt.want*3/2)
+		}
+	}
+}
+
+func TestDoWithRetryWaitsForRateLimiterOnEveryAttempt(t *testing.T) {
+	var mu sync.Mutex
+	var arrivals []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		arrivals = append(arrivals, time.Now())
+
+		if len(arrivals) < 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(testSummaryJson))
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequest("GET", server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 4 requests per second is one every 250ms, longer than backoff of the first retry
+	resp, err := doWithRetry(context.Background(), &http.Client{}, newRateLimiter(4, 1), realClock{}, req, DefaultMaxRetries, DefaultMaxRetryAfter)
+	if err != nil {
+		t.Fatalf("doWithRetry() failed: %s", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || len(arrivals) != 2 {
+		t.Fatalf("got status %d after %d attempts, want 200 after 2", resp.StatusCode, len(arrivals))
+	}
+	if gap := arrivals[1].Sub(arrivals[0]); gap < 230*time.Millisecond {
+		t.Errorf("retry came %s after the first attempt, want ~250ms of -rate-limit 4", gap)
+	}
+}
+
+func TestDoWithRetryWaitsBackoffOnClock(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, testSummaryJson)
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequest("GET", server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := newFakeClock(time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC))
+
+	done := make(chan *http.Response)
+	go func() {
+		resp, err := doWithRetry(context.Background(), &http.Client{}, newRateLimiter(0, 1), clock, req, DefaultMaxRetries, DefaultMaxRetryAfter)
+		if err != nil {
+			t.Errorf("doWithRetry() failed: %s", err)
+		}
+		done <- resp
+	}()
+
+	// first backoff is 100ms plus up to 50% of jitter
+	clock.BlockUntil(1)
+	wait := clock.Deadlines()[0]
+	if wait < retryBaseDelay || wait >= retryBaseDelay*3/2 {
+		t.Errorf("retry waits for %s, want between %s and %s", wait, retryBaseDelay, retryBaseDelay*3/2)
+	}
+
+	clock.Advance(wait)
+	resp := <-done
+	if resp == nil {
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || atomic.LoadInt32(&attempts) != 2 {
+		t.Errorf("got status %d after %d attempts, want 200 after 2", resp.StatusCode, attempts)
+	}
+}
+
+func TestDoWithRetryWaitsRetryAfterOnClock(t *testing.T) {
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		retryAfter string
+		want       time.Duration
+	}{
+		{"seconds", "5", 5 * time.Second},
+		{"HTTP date", now.Add(7 * time.Second).Format(http.TimeFormat), 7 * time.Second},
+		{"capped by maxRetryAfter", "60", 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&attempts, 1) == 1 {
+					w.Header().Set("Retry-After", tt.retryAfter)
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				io.WriteString(w, testSummaryJson)
+			}))
+			defer server.Close()
+
+			req, err := http.NewRequest("GET", server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			clock := newFakeClock(now)
+
+			done := make(chan *http.Response)
+			go func() {
+				resp, err := doWithRetry(context.Background(), &http.Client{}, newRateLimiter(0, 1), clock, req, DefaultMaxRetries, 10*time.Second)
+				if err != nil {
+					t.Errorf("doWithRetry() failed: %s", err)
+				}
+				done <- resp
+			}()
+
+			clock.BlockUntil(1)
+			if wait := clock.Deadlines()[0]; wait != tt.want {
+				t.Errorf("retry waits for %s, want %s", wait, tt.want)
+			}
+
+			clock.Advance(tt.want)
+			resp := <-done
+			if resp == nil {
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK || atomic.LoadInt32(&attempts) != 2 {
+				t.Errorf("got status %d after %d attempts, want 200 after 2", resp.StatusCode, attempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import "time"
+
+// adaptiveInterval backs off polling while NBP has not published new rates,
+// NBP publishes once per business day, so polling at the base interval is mostly wasted
+type adaptiveInterval struct {
+	base    time.Duration
+	max     time.Duration
+	current time.Duration
+	// newest effective date seen in the previous pool
+	lastNewest time.Time
+}
+
+// newAdaptiveInterval returns scheduler starting at base interval, max not greater than base disables back off
+func newAdaptiveInterval(base, max time.Duration) *adaptiveInterval {
+	return &adaptiveInterval{base: base, max: max, current: base}
+}
+
+// next returns interval before the next pool given newest effective date fetched by the current one,
+// interval is doubled while the date does not change and reset to base as soon as it does
+func (a *adaptiveInterval) next(newest time.Time) time.Duration {
+	if a.max <= a.base {
+		return a.base
+	}
+
+	// pool without any rates tells nothing about freshness, keep current interval
+	if newest.IsZero() {
+		return a.current
+	}
+
+	if newest.Equal(a.lastNewest) {
+		a.current *= 2
+		if a.current > a.max {
+			a.current = a.max
+		}
+	} else {
+		a.current = a.base
+		a.lastNewest = newest
+	}
+
+	return a.current
+}
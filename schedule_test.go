@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveIntervalBacksOffWhileDataIsUnchanged(t *testing.T) {
+	monday := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	tuesday := monday.AddDate(0, 0, 1)
+	scheduler := newAdaptiveInterval(10*time.Second, time.Minute)
+
+	steps := []struct {
+		newest time.Time
+		want   time.Duration
+	}{
+		{monday, 10 * time.Second},
+		{monday, 20 * time.Second},
+		{monday, 40 * time.Second},
+		// capped at -max-interval
+		{monday, time.Minute},
+		{monday, time.Minute},
+		// pool without rates keeps the current interval
+		{time.Time{}, time.Minute},
+		// new publication resets to the base interval
+		{tuesday, 10 * time.Second},
+		{tuesday, 20 * time.Second},
+	}
+
+	for i, step := range steps {
+		if got := scheduler.next(step.newest); got != step.want {
+			t.Errorf("step %d: next(%s) = %s, want %s", i, step.newest.Format("2006-01-02"), got, step.want)
+		}
+	}
+}
+
+func TestAdaptiveIntervalWithoutMaxIntervalKeepsBase(t *testing.T) {
+	monday := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	scheduler := newAdaptiveInterval(10*time.Second, 0)
+
+	for i := 0; i < 3; i++ {
+		if got := scheduler.next(monday); got != 10*time.Second {
+			t.Errorf("next() = %s without -max-interval, want base 10s", got)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"spyrosoft-recruitment-task/base"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// rates are keyed by currency along with table number, as NBP numbers a table once for all of its currencies
+const createRatesTable = `CREATE TABLE IF NOT EXISTS rates (
+	currency TEXT NOT NULL,
+	no TEXT NOT NULL,
+	effective_date TEXT,
+	mid REAL,
+	fetched_at TIMESTAMP,
+	PRIMARY KEY (currency, no)
+)`
+
+// migrateRatesTable moves rows of the rates table keyed by table number alone into the current one,
+// currency was not recorded then, so it is left empty
+var migrateRatesTable = []string{
+	`ALTER TABLE rates RENAME TO rates_without_currency`,
+	createRatesTable,
+	`INSERT INTO rates (currency, no, effective_date, mid, fetched_at)
+	SELECT '', no, effective_date, mid, fetched_at FROM rates_without_currency`,
+	`DROP TABLE rates_without_currency`,
+}
+
+const upsertRate = `INSERT INTO rates (currency, no, effective_date, mid, fetched_at) VALUES (?, ?, ?, ?, ?)
+ON CONFLICT(currency, no) DO UPDATE SET effective_date = excluded.effective_date, mid = excluded.mid, fetched_at = excluded.fetched_at`
+
+// SqliteStore upserts fetched rates into a SQLite database, writes from concurrent workers are serialized
+type SqliteStore struct {
+	mu     sync.Mutex
+	db     *sql.DB
+	upsert *sql.Stmt
+}
+
+func NewSqliteStore(path string) (*SqliteStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %s", err)
+	}
+
+	// single connection, so in-memory databases are shared and SQLite never sees concurrent writers
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(createRatesTable)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create rates table: %s", err)
+	}
+
+	err = migrate(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	upsert, err := db.Prepare(upsertRate)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to prepare upsert statement: %s", err)
+	}
+
+	return &SqliteStore{db: db, upsert: upsert}, nil
+}
+
+// migrate upgrades rates table created before currency was recorded, current table is left as is
+func migrate(db *sql.DB) error {
+	var hasCurrency bool
+	err := db.QueryRow(`SELECT COUNT(*) > 0 FROM pragma_table_info('rates') WHERE name = 'currency'`).Scan(&hasCurrency)
+	if err != nil {
+		return fmt.Errorf("failed to inspect rates table: %s", err)
+	}
+	if hasCurrency {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin migration: %s", err)
+	}
+	for _, statement := range migrateRatesTable {
+		_, err = tx.Exec(statement)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to add currency to rates table: %s", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit migration: %s", err)
+	}
+	return nil
+}
+
+// SaveRates upserts rates of currency, a rate fetched again replaces the previous row of its currency and table number
+func (s *SqliteStore) SaveRates(currency string, rates []*base.ExchangeRate, fetchedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %s", err)
+	}
+
+	stmt := tx.Stmt(s.upsert)
+	for _, rate := range rates {
+		effectiveDate := ""
+		if rate.EffectiveDate != nil {
+			effectiveDate = rate.EffectiveDate.Format("2006-01-02")
+		}
+
+		_, err = stmt.Exec(currency, rate.No, effectiveDate, rate.Mid, fetchedAt.UTC())
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to upsert rate %s: %s", rate.No, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit rates: %s", err)
+	}
+
+	return nil
+}
+
+func (s *SqliteStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.upsert.Close()
+	return s.db.Close()
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"database/sql"
+	"path/filepath"
+	"spyrosoft-recruitment-task/base"
+	"spyrosoft-recruitment-task/marshal"
+	"testing"
+	"time"
+)
+
+func newRate(no string, date string, mid float64) *base.ExchangeRate {
+	effectiveDate, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		panic(err)
+	}
+	return &base.ExchangeRate{No: no, EffectiveDate: &marshal.CustomTime{Time: effectiveDate}, Mid: mid}
+}
+
+type row struct {
+	currency      string
+	no            string
+	effectiveDate string
+	mid           float64
+}
+
+func queryRows(t *testing.T, db *sql.DB) []row {
+	t.Helper()
+
+	rows, err := db.Query(`SELECT currency, no, effective_date, mid FROM rates ORDER BY currency, no`)
+	if err != nil {
+		t.Fatalf("failed to query rates: %s", err)
+	}
+	defer rows.Close()
+
+	var all []row
+	for rows.Next() {
+		var r row
+		err = rows.Scan(&r.currency, &r.no, &r.effectiveDate, &r.mid)
+		if err != nil {
+			t.Fatalf("failed to scan rate: %s", err)
+		}
+		all = append(all, r)
+	}
+	return all
+}
+
+func TestSqliteStoreUpsertsRates(t *testing.T) {
+	store, err := NewSqliteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSqliteStore() failed: %s", err)
+	}
+	defer store.Close()
+
+	fetchedAt := time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC)
+	rates := []*base.ExchangeRate{newRate("001/A/NBP/2024", "2024-01-02", 4.4), newRate("002/A/NBP/2024", "2024-01-03", 4.6)}
+
+	// every worker of a pool saves the same rates
+	for i := 0; i < 2; i++ {
+		err = store.SaveRates("EUR", rates, fetchedAt)
+		if err != nil {
+			t.Fatalf("SaveRates() failed: %s", err)
+		}
+	}
+
+	// corrected rate replaces the saved one
+	err = store.SaveRates("EUR", []*base.ExchangeRate{newRate("002/A/NBP/2024", "2024-01-03", 4.65)}, fetchedAt.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("SaveRates() failed: %s", err)
+	}
+
+	want := []row{
+		{"EUR", "001/A/NBP/2024", "2024-01-02", 4.4},
+		{"EUR", "002/A/NBP/2024", "2024-01-03", 4.65},
+	}
+	assertRows(t, queryRows(t, store.db), want)
+}
+
+func TestSqliteStoreKeepsCurrenciesOfSameTableNumber(t *testing.T) {
+	store, err := NewSqliteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSqliteStore() failed: %s", err)
+	}
+	defer store.Close()
+
+	fetchedAt := time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC)
+	err = store.SaveRates("EUR", []*base.ExchangeRate{newRate("001/A/NBP/2024", "2024-01-02", 4.4)}, fetchedAt)
+	if err != nil {
+		t.Fatalf("SaveRates() failed: %s", err)
+	}
+	err = store.SaveRates("USD", []*base.ExchangeRate{newRate("001/A/NBP/2024", "2024-01-02", 3.9)}, fetchedAt)
+	if err != nil {
+		t.Fatalf("SaveRates() failed: %s", err)
+	}
+
+	want := []row{
+		{"EUR", "001/A/NBP/2024", "2024-01-02", 4.4},
+		{"USD", "001/A/NBP/2024", "2024-01-02", 3.9},
+	}
+	assertRows(t, queryRows(t, store.db), want)
+}
+
+func TestNewSqliteStoreMigratesTableWithoutCurrency(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.db")
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`CREATE TABLE rates (no TEXT PRIMARY KEY, effective_date TEXT, mid REAL, fetched_at TIMESTAMP);
+	INSERT INTO rates VALUES ('001/A/NBP/2024', '2024-01-02', 4.4, '2024-01-03 12:00:00')`)
+	if err != nil {
+		t.Fatalf("failed to create table of the old schema: %s", err)
+	}
+	db.Close()
+
+	store, err := NewSqliteStore(path)
+	if err != nil {
+		t.Fatalf("NewSqliteStore() of the old schema failed: %s", err)
+	}
+	defer store.Close()
+
+	err = store.SaveRates("USD", []*base.ExchangeRate{newRate("001/A/NBP/2024", "2024-01-02", 3.9)}, time.Now())
+	if err != nil {
+		t.Fatalf("SaveRates() after migration failed: %s", err)
+	}
+
+	want := []row{
+		{"", "001/A/NBP/2024", "2024-01-02", 4.4},
+		{"USD", "001/A/NBP/2024", "2024-01-02", 3.9},
+	}
+	assertRows(t, queryRows(t, store.db), want)
+}
+
+func assertRows(t *testing.T, got []row, want []row) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d rows %+v, want %d rows %+v", len(got), got, len(want), want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("row %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"spyrosoft-recruitment-task/base"
+	"strconv"
+	"strings"
+)
+
+// target is a currency fetched by every requests pool, along with its own rate bounds,
+// it is not modified once built, -bands-file reloads replace it with a copy
+type target struct {
+	Currency string
+	ApiUrl   string
+	Bounds   base.RateBounds
+	// replaces Bounds in deciding which rates are out of scope when its window is set
+	Baseline base.Baseline
+	// replaces both Bounds and Baseline when set
+	Rule *base.Rule
+
+	// API request prepared at startup, every fetch sends a clone of it
+	request *http.Request
+}
+
+// Scope returns what rates of the target are checked against, as chosen by -mode
+func (t *target) Scope() base.Scope {
+	if t.Rule != nil {
+		return t.Rule
+	}
+	if t.Baseline.Window > 0 {
+		return t.Baseline
+	}
+	return t.Bounds
+}
+
+// buildTargets returns the -currencies list, or -currency alone when the list is empty,
+// currencies without an entry in -bands use -rate-min and -rate-max.
+// API request of every target is prepared here, so a target which could never be fetched fails startup.
+func buildTargets(cfg Config) ([]*target, error) {
+	currencies := parseCurrencies(cfg.Currencies)
+	if len(currencies) == 0 {
+		currencies = []string{cfg.Currency}
+	}
+
+	bands, err := parseBands(cfg.Bands)
+	if err != nil {
+		return nil, err
+	}
+
+	// rule is not modified once parsed, so all targets share it
+	var rule *base.Rule
+	if cfg.Mode == ModeRule {
+		rule, err = base.ParseRule(cfg.Rule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -rule: %s", err)
+		}
+	}
+
+	var targets []*target
+	for _, currency := range currencies {
+		currencyCfg := cfg
+		currencyCfg.Currency = currency
+
+		apiUrl, err := buildApiUrl(currencyCfg)
+		if err != nil {
+			return nil, err
+		}
+
+		bounds, ok := bands[strings.ToLower(currency)]
+		if !ok {
+			bounds = cfg.Bounds
+		}
+
+		request, err := prepareHttpRequest(context.Background(), apiUrl, newRequestOptions(cfg))
+		if err != nil {
+			return nil, fmt.Errorf("currency %s: %s", currency, err)
+		}
+
+		t := &target{Currency: strings.ToLower(currency), ApiUrl: apiUrl, Bounds: bounds, request: request}
+		if cfg.Mode == ModeBaseline {
+			t.Baseline = base.Baseline{Window: cfg.BaselineWindow, DeviationPct: cfg.DeviationPct}
+		}
+		t.Rule = rule
+		targets = append(targets, t)
+	}
+
+	return targets, nil
+}
+
+// parseCurrencies splits comma separated currency codes, empty entries are skipped
+func parseCurrencies(value string) []string {
+	var currencies []string
+	for _, currency := range strings.Split(value, ",") {
+		currency = strings.ToLower(strings.TrimSpace(currency))
+		if currency != "" {
+			currencies = append(currencies, currency)
+		}
+	}
+	return currencies
+}
+
+// parseBands parses rate bounds of currencies given as "eur=4.5:4.7,usd=3.9:4.2"
+func parseBands(value string) (map[string]base.RateBounds, error) {
+	bands := map[string]base.RateBounds{}
+	for _, band := range strings.Split(value, ",") {
+		band = strings.TrimSpace(band)
+		if band == "" {
+			continue
+		}
+
+		currency, bounds, ok := strings.Cut(band, "=")
+		if !ok {
+			return nil, fmt.Errorf("-bands entry %q: expected <currency>=<min>:<max>", band)
+		}
+
+		minValue, maxValue, ok := strings.Cut(bounds, ":")
+		if !ok {
+			return nil, fmt.Errorf("-bands entry %q: expected <currency>=<min>:<max>", band)
+		}
+
+		min, err := strconv.ParseFloat(strings.TrimSpace(minValue), 64)
+		if err != nil {
+			return nil, fmt.Errorf("-bands entry %q: invalid min: %s", band, err)
+		}
+
+		max, err := strconv.ParseFloat(strings.TrimSpace(maxValue), 64)
+		if err != nil {
+			return nil, fmt.Errorf("-bands entry %q: invalid max: %s", band, err)
+		}
+
+		if min > max {
+			return nil, fmt.Errorf("-bands entry %q: min must not be greater than max", band)
+		}
+
+		bands[strings.ToLower(strings.TrimSpace(currency))] = base.RateBounds{Min: min, Max: max}
+	}
+	return bands, nil
+}
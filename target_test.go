@@ -0,0 +1,79 @@
+package main
+
+import (
+	"reflect"
+	"spyrosoft-recruitment-task/base"
+	"testing"
+)
+
+func TestParseBands(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    map[string]base.RateBounds
+		wantErr bool
+	}{
+		{"", map[string]base.RateBounds{}, false},
+		{"eur=4.5:4.7", map[string]base.RateBounds{"eur": {Min: 4.5, Max: 4.7}}, false},
+		{" EUR = 4.5 : 4.7 , usd=3.9:4.2,", map[string]base.RateBounds{"eur": {Min: 4.5, Max: 4.7}, "usd": {Min: 3.9, Max: 4.2}}, false},
+		{"eur", nil, true},
+		{"eur=4.5", nil, true},
+		{"eur=low:4.7", nil, true},
+		{"eur=4.5:high", nil, true},
+		{"eur=4.7:4.5", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseBands(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseBands(%q) error = %v, want error %t", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseBands(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestBuildTargetsAppliesBandOfEachCurrency(t *testing.T) {
+	cfg := loadTestConfig(t, "-currencies", "EUR, usd,gbp", "-bands", "eur=4.5:4.7,usd=3.9:4.2", "-rate-min", "5.0", "-rate-max", "5.2")
+
+	targets, err := buildTargets(cfg)
+	if err != nil {
+		t.Fatalf("buildTargets() failed: %s", err)
+	}
+
+	// currency without a band keeps -rate-min and -rate-max
+	want := map[string]base.RateBounds{
+		"eur": {Min: 4.5, Max: 4.7},
+		"usd": {Min: 3.9, Max: 4.2},
+		"gbp": {Min: 5.0, Max: 5.2},
+	}
+	if len(targets) != len(want) {
+		t.Fatalf("buildTargets() = %d targets, want %d", len(targets), len(want))
+	}
+	for _, target := range targets {
+		if target.Bounds != want[target.Currency] {
+			t.Errorf("bounds of %s = %v, want %v", target.Currency, target.Bounds, want[target.Currency])
+		}
+	}
+}
+
+func TestBuildTargetsScopeOfMode(t *testing.T) {
+	tests := []struct {
+		args []string
+		want base.Scope
+	}{
+		{[]string{"-rate-min", "4.5", "-rate-max", "4.7"}, base.RateBounds{Min: 4.5, Max: 4.7}},
+		{[]string{"-mode", "baseline", "-baseline-window", "4", "-deviation-pct", "3"}, base.Baseline{Window: 4, DeviationPct: 3}},
+	}
+
+	for _, tt := range tests {
+		targets, err := buildTargets(loadTestConfig(t, tt.args...))
+		if err != nil {
+			t.Fatalf("buildTargets(%q) failed: %s", tt.args, err)
+		}
+		if got := targets[0].Scope(); got != tt.want {
+			t.Errorf("scope of %q = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/instrument"
+	"go.opentelemetry.io/otel/metric/instrument/syncfloat64"
+	"go.opentelemetry.io/otel/metric/instrument/syncint64"
+	"go.opentelemetry.io/otel/metric/unit"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName is service.name resource attribute of exported spans and metrics
+const ServiceName = "nbp-api-query-worker"
+
+const instrumentationName = "spyrosoft-recruitment-task"
+
+// Telemetry records spans of requests pools and fetches along with fetch metrics,
+// it is carried by context from runPool down to workers
+type Telemetry struct {
+	tracer   trace.Tracer
+	fetches  syncint64.Counter
+	failures syncint64.Counter
+	latency  syncfloat64.Histogram
+
+	shutdown []func(context.Context) error
+}
+
+// disabled records nothing, it is used when context carries no Telemetry
+var disabled = mustNewTelemetry(trace.NewNoopTracerProvider(), metric.NewNoopMeterProvider())
+
+// Start returns Telemetry exporting over OTLP/HTTP to endpoint, e.g. http://localhost:4318,
+// plain http endpoint is sent to without TLS. It must be shut down to flush what is still buffered.
+func Start(ctx context.Context, endpoint string, version string) (*Telemetry, error) {
+	endpointUrl, err := url.Parse(endpoint)
+	if err != nil || endpointUrl.Host == "" {
+		return nil, fmt.Errorf("OpenTelemetry endpoint %q is not a valid absolute URL", endpoint)
+	}
+
+	traceOpts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpointUrl.Host)}
+	metricOpts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(endpointUrl.Host)}
+	if endpointUrl.Scheme == "http" {
+		traceOpts = append(traceOpts, otlptracehttp.WithInsecure())
+		metricOpts = append(metricOpts, otlpmetrichttp.WithInsecure())
+	}
+
+	res := resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceNameKey.String(ServiceName),
+		semconv.ServiceVersionKey.String(version),
+	)
+
+	traceExporter, err := otlptracehttp.New(ctx, traceOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %s", err)
+	}
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(traceExporter), sdktrace.WithResource(res))
+
+	metricExporter, err := otlpmetrichttp.New(ctx, metricOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP metric exporter: %s", err)
+	}
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)), sdkmetric.WithResource(res))
+
+	t, err := newTelemetry(tracerProvider, meterProvider)
+	if err != nil {
+		return nil, err
+	}
+	t.shutdown = []func(context.Context) error{tracerProvider.Shutdown, meterProvider.Shutdown}
+	return t, nil
+}
+
+func newTelemetry(tracerProvider trace.TracerProvider, meterProvider metric.MeterProvider) (*Telemetry, error) {
+	meter := meterProvider.Meter(instrumentationName)
+
+	fetches, err := meter.SyncInt64().Counter("nbp.fetches", instrument.WithDescription("Number of performed NBP API fetches."))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fetches counter: %s", err)
+	}
+	failures, err := meter.SyncInt64().Counter("nbp.fetch.failures", instrument.WithDescription("Number of failed NBP API fetches."))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fetch failures counter: %s", err)
+	}
+	latency, err := meter.SyncFloat64().Histogram("nbp.fetch.duration", instrument.WithDescription("Latency of NBP API fetches."), instrument.WithUnit(unit.Milliseconds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fetch duration histogram: %s", err)
+	}
+
+	return &Telemetry{
+		tracer:   tracerProvider.Tracer(instrumentationName),
+		fetches:  fetches,
+		failures: failures,
+		latency:  latency,
+	}, nil
+}
+
+func mustNewTelemetry(tracerProvider trace.TracerProvider, meterProvider metric.MeterProvider) *Telemetry {
+	t, err := newTelemetry(tracerProvider, meterProvider)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Shutdown flushes buffered spans and metrics and stops exporting them
+func (t *Telemetry) Shutdown(ctx context.Context) error {
+	for _, shutdown := range t.shutdown {
+		err := shutdown(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type telemetryKey struct{}
+
+// WithTelemetry returns ctx carrying t, pools and fetches started under it are recorded by t
+func WithTelemetry(ctx context.Context, t *Telemetry) context.Context {
+	return context.WithValue(ctx, telemetryKey{}, t)
+}
+
+func fromContext(ctx context.Context) *Telemetry {
+	if t, ok := ctx.Value(telemetryKey{}).(*Telemetry); ok {
+		return t
+	}
+	return disabled
+}
+
+// Pool is span of a requests pool
+type Pool struct {
+	span trace.Span
+}
+
+// StartPool starts span of a pool fetching given currencies, spans of its fetches are children of it
+func StartPool(ctx context.Context, currencies []string) (context.Context, *Pool) {
+	ctx, span := fromContext(ctx).tracer.Start(ctx, "requests pool", trace.WithAttributes(attribute.StringSlice("nbp.currencies", currencies)))
+	return ctx, &Pool{span: span}
+}
+
+// End ends span of the pool, which failed when err is not nil
+func (p *Pool) End(workers int, failures int, err error) {
+	p.span.SetAttributes(attribute.Int("nbp.workers", workers), attribute.Int("nbp.failures", failures))
+	if err != nil {
+		p.span.SetStatus(codes.Error, err.Error())
+	}
+	p.span.End()
+}
+
+// Fetch is span of a single worker's fetch, recorded in fetch metrics once ended
+type Fetch struct {
+	ctx       context.Context
+	telemetry *Telemetry
+	span      trace.Span
+	currency  string
+}
+
+// StartFetch starts span of a fetch of currency by worker of given index
+func StartFetch(ctx context.Context, currency string, index int) (context.Context, *Fetch) {
+	t := fromContext(ctx)
+	ctx, span := t.tracer.Start(ctx, "fetch", trace.WithAttributes(
+		attribute.String("nbp.currency", currency),
+		attribute.Int("nbp.worker", index),
+	))
+	return ctx, &Fetch{ctx: ctx, telemetry: t, span: span, currency: currency}
+}
+
+// End ends span of the fetch with its HTTP status and latency, both left out when the request was not performed
+func (f *Fetch) End(statusCode int, elapsed time.Duration, err error) {
+	currency := attribute.String("nbp.currency", f.currency)
+	f.telemetry.fetches.Add(f.ctx, 1, currency)
+
+	if statusCode != 0 {
+		f.span.SetAttributes(semconv.HTTPStatusCodeKey.Int(statusCode))
+	}
+	if elapsed > 0 {
+		latency := float64(elapsed) / float64(time.Millisecond)
+		f.span.SetAttributes(attribute.Float64("nbp.latency_ms", latency))
+		f.telemetry.latency.Record(f.ctx, latency, currency)
+	}
+	if err != nil {
+		f.telemetry.failures.Add(f.ctx, 1, currency)
+		f.span.RecordError(err)
+		f.span.SetStatus(codes.Error, err.Error())
+	}
+	f.span.End()
+}
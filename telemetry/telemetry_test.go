@@ -0,0 +1,166 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newTestTelemetry returns Telemetry recording spans to an in-memory exporter and metrics to a manual reader
+func newTestTelemetry(t *testing.T) (*Telemetry, *tracetest.InMemoryExporter, sdkmetric.Reader) {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	reader := sdkmetric.NewManualReader()
+	telemetry, err := newTelemetry(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)), sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	if err != nil {
+		t.Fatalf("newTelemetry() failed: %s", err)
+	}
+	return telemetry, exporter, reader
+}
+
+// attributesOf returns attributes of span by key
+func attributesOf(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attributes := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes {
+		attributes[kv.Key] = kv.Value
+	}
+	return attributes
+}
+
+func TestPoolAndFetchSpans(t *testing.T) {
+	telemetry, exporter, _ := newTestTelemetry(t)
+	ctx := WithTelemetry(context.Background(), telemetry)
+
+	poolCtx, pool := StartPool(ctx, []string{"eur", "usd"})
+	_, fetch := StartFetch(poolCtx, "eur", 0)
+	fetch.End(http.StatusOK, 12*time.Millisecond, nil)
+	_, fetch = StartFetch(poolCtx, "usd", 1)
+	fetch.End(0, 0, errors.New("connection refused"))
+	pool.End(2, 1, errors.New("1 of 2 workers failed"))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("recorded %d spans, want pool and 2 fetches: %+v", len(spans), spans)
+	}
+	ok, failed, poolSpan := spans[0], spans[1], spans[2]
+
+	if poolSpan.Name != "requests pool" || poolSpan.Status.Code != codes.Error {
+		t.Errorf("pool span %q of status %v, want failed requests pool", poolSpan.Name, poolSpan.Status)
+	}
+	attributes := attributesOf(poolSpan)
+	if got := attributes["nbp.currencies"].AsStringSlice(); len(got) != 2 || got[0] != "eur" || got[1] != "usd" {
+		t.Errorf("pool span currencies = %v, want [eur usd]", got)
+	}
+	if attributes["nbp.workers"].AsInt64() != 2 || attributes["nbp.failures"].AsInt64() != 1 {
+		t.Errorf("pool span attributes = %v, want 2 workers of which 1 failed", attributes)
+	}
+
+	for _, fetch := range []tracetest.SpanStub{ok, failed} {
+		if fetch.Name != "fetch" || fetch.Parent.SpanID() != poolSpan.SpanContext.SpanID() {
+			t.Errorf("span %q is not a fetch child of the pool span", fetch.Name)
+		}
+	}
+
+	attributes = attributesOf(ok)
+	if attributes["nbp.currency"].AsString() != "eur" || attributes["nbp.worker"].AsInt64() != 0 ||
+		attributes["http.status_code"].AsInt64() != http.StatusOK || attributes["nbp.latency_ms"].AsFloat64() != 12 {
+		t.Errorf("fetch span attributes = %v, want eur of worker 0 answered with 200 in 12 ms", attributes)
+	}
+	if ok.Status.Code == codes.Error {
+		t.Errorf("successful fetch span has status %v", ok.Status)
+	}
+
+	attributes = attributesOf(failed)
+	if attributes["nbp.currency"].AsString() != "usd" || attributes["nbp.worker"].AsInt64() != 1 {
+		t.Errorf("fetch span attributes = %v, want usd of worker 1", attributes)
+	}
+	// request which was not performed has neither status nor latency
+	if _, ok := attributes["http.status_code"]; ok {
+		t.Errorf("failed fetch span has status code %v", attributes["http.status_code"])
+	}
+	if _, ok := attributes["nbp.latency_ms"]; ok {
+		t.Errorf("failed fetch span has latency %v", attributes["nbp.latency_ms"])
+	}
+	if failed.Status.Code != codes.Error || failed.Status.Description != "connection refused" || len(failed.Events) != 1 {
+		t.Errorf("failed fetch span status %v with %d events, want error recorded", failed.Status, len(failed.Events))
+	}
+}
+
+func TestFetchMetrics(t *testing.T) {
+	telemetry, _, reader := newTestTelemetry(t)
+	ctx := WithTelemetry(context.Background(), telemetry)
+
+	for _, elapsed := range []time.Duration{10 * time.Millisecond, 30 * time.Millisecond} {
+		_, fetch := StartFetch(ctx, "eur", 0)
+		fetch.End(http.StatusOK, elapsed, nil)
+	}
+	_, fetch := StartFetch(ctx, "eur", 1)
+	fetch.End(http.StatusInternalServerError, 5*time.Millisecond, errors.New("bad status 500"))
+
+	collected, err := reader.Collect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics := map[string]metricdata.Aggregation{}
+	for _, scope := range collected.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+
+	counters := []struct {
+		name string
+		want int64
+	}{
+		{"nbp.fetches", 3},
+		{"nbp.fetch.failures", 1},
+	}
+	for _, counter := range counters {
+		sum, ok := metrics[counter.name].(metricdata.Sum[int64])
+		if !ok || len(sum.DataPoints) != 1 {
+			t.Errorf("%s = %+v, want a single counter of currency", counter.name, metrics[counter.name])
+			continue
+		}
+		point := sum.DataPoints[0]
+		if currency, _ := point.Attributes.Value("nbp.currency"); point.Value != counter.want || currency.AsString() != "eur" {
+			t.Errorf("%s = %d of %v, want %d of eur", counter.name, point.Value, point.Attributes, counter.want)
+		}
+	}
+
+	histogram, ok := metrics["nbp.fetch.duration"].(metricdata.Histogram)
+	if !ok || len(histogram.DataPoints) != 1 {
+		t.Fatalf("nbp.fetch.duration = %+v, want a single histogram of currency", metrics["nbp.fetch.duration"])
+	}
+	if point := histogram.DataPoints[0]; point.Count != 3 || point.Sum != 45 {
+		t.Errorf("nbp.fetch.duration of %d fetches summing to %v ms, want 3 summing to 45 ms", point.Count, point.Sum)
+	}
+}
+
+func TestContextWithoutTelemetryRecordsNothing(t *testing.T) {
+	ctx, pool := StartPool(context.Background(), []string{"eur"})
+	_, fetch := StartFetch(ctx, "eur", 0)
+	fetch.End(http.StatusOK, time.Millisecond, nil)
+	pool.End(1, 0, nil)
+
+	if fetch.span.SpanContext().IsValid() || pool.span.IsRecording() {
+		t.Error("spans without telemetry in context are recorded, want no-op spans")
+	}
+}
+
+func TestStartRejectsInvalidEndpoint(t *testing.T) {
+	for _, endpoint := range []string{"", "localhost:4318", "://localhost"} {
+		if _, err := Start(context.Background(), endpoint, "test"); err == nil {
+			t.Errorf("Start() of endpoint %q succeeded, want error", endpoint)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"spyrosoft-recruitment-task/logger"
+	"strings"
+	"sync"
+	"time"
+)
+
+// requestTrace records how long phases of an API request took, set by httptrace callbacks.
+// With retries only the last attempt is kept, as every attempt starts with GetConn.
+type requestTrace struct {
+	// callbacks of concurrent dials, e.g. of IPv4 and IPv6 addresses, may overlap
+	mu sync.Mutex
+
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+
+	dns       time.Duration
+	connect   time.Duration
+	tls       time.Duration
+	firstByte time.Duration
+	// idle connection was reused, so there was no DNS lookup, connect nor TLS handshake
+	reused bool
+}
+
+// withClientTrace returns ctx which makes requests record their phases into trace
+func (t *requestTrace) withClientTrace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.start = time.Now()
+			t.dnsStart, t.connectStart, t.tlsStart = time.Time{}, time.Time{}, time.Time{}
+			t.dns, t.connect, t.tls, t.firstByte = 0, 0, 0, 0
+			t.reused = false
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.reused = info.Reused
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dns = time.Since(t.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(_ string, _ string, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if err == nil {
+				t.connect = time.Since(t.connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tls = time.Since(t.tlsStart)
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.firstByte = time.Since(t.start)
+		},
+	})
+}
+
+// log logs recorded phases at debug level, phases which did not happen, e.g. TLS of plain HTTP
+// or first byte of a failed request, are left out
+func (t *requestTrace) log(ctx context.Context, index int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var phases []string
+	if t.reused {
+		phases = append(phases, "reused connection")
+	}
+	if t.dns > 0 {
+		phases = append(phases, "DNS "+formatPhase(t.dns))
+	}
+	if t.connect > 0 {
+		phases = append(phases, "connect "+formatPhase(t.connect))
+	}
+	if t.tls > 0 {
+		phases = append(phases, "TLS "+formatPhase(t.tls))
+	}
+	if t.firstByte > 0 {
+		phases = append(phases, "first byte "+formatPhase(t.firstByte))
+	}
+	if len(phases) == 0 {
+		return
+	}
+
+	logger.Debug("%s Trace: %s", workerTag(ctx, index), strings.Join(phases, ", "))
+}
+
+// formatPhase rounds d to microseconds, phases of local connections take less than a millisecond
+func formatPhase(d time.Duration) string {
+	return d.Round(time.Microsecond).String()
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"spyrosoft-recruitment-task/logger"
+	"strings"
+	"testing"
+)
+
+// localhostUrl returns URL of server by host name, so requests to it look the name up
+func localhostUrl(server *httptest.Server) string {
+	return strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+}
+
+// tracedGet performs GET of url by client, recording its phases into trace
+func tracedGet(t *testing.T, client *http.Client, url string, trace *requestTrace) {
+	t.Helper()
+
+	request, err := http.NewRequestWithContext(trace.withClientTrace(context.Background()), http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatalf("GET %s failed: %s", url, err)
+	}
+	io.Copy(io.Discard, response.Body)
+	response.Body.Close()
+}
+
+func TestRequestTraceRecordsPhases(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, summaryJson("eur", 4.6))
+	}))
+	t.Cleanup(server.Close)
+	client := server.Client()
+	// certificate of the test server is issued to example.com, not to localhost
+	client.Transport.(*http.Transport).TLSClientConfig.ServerName = "example.com"
+
+	trace := &requestTrace{}
+	tracedGet(t, client, localhostUrl(server), trace)
+
+	if trace.reused {
+		t.Error("first request reused a connection, want a new one")
+	}
+	phases := []struct {
+		name     string
+		recorded bool
+	}{
+		{"DNS", trace.dns > 0},
+		{"connect", trace.connect > 0},
+		{"TLS", trace.tls > 0},
+		{"first byte", trace.firstByte > 0},
+	}
+	for _, phase := range phases {
+		if !phase.recorded {
+			t.Errorf("%s duration is not recorded: %+v", phase.name, trace)
+		}
+	}
+
+	// the second request goes over the idle connection of the first one
+	trace = &requestTrace{}
+	tracedGet(t, client, localhostUrl(server), trace)
+
+	if !trace.reused || trace.dns != 0 || trace.connect != 0 || trace.tls != 0 || trace.firstByte <= 0 {
+		t.Errorf("trace of request over idle connection = %+v, want only first byte of reused connection", trace)
+	}
+}
+
+func TestHttpFetcherLogsTraceAtDebugLevel(t *testing.T) {
+	server := newEncodedNbpServer(t, "", summaryJson("eur", 4.55, 4.6))
+
+	tests := []struct {
+		name  string
+		trace bool
+		level logger.Level
+		want  []string
+	}{
+		{"traced", true, logger.LevelDebug, []string{"Trace: DNS ", ", connect ", ", first byte "}},
+		{"traced above debug level", true, logger.LevelInfo, nil},
+		{"not traced", false, logger.LevelDebug, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestPoolConfig(1, localhostUrl(server))
+			httpFetcher(cfg).Trace = tt.trace
+			log := captureLog(t, tt.level)
+
+			_, err := summaryFetch(cfg)(context.Background(), 0)
+			if err != nil {
+				t.Fatalf("fetchTarget() failed: %s", err)
+			}
+
+			content := log.String()
+			for _, want := range tt.want {
+				if !strings.Contains(content, want) {
+					t.Errorf("log has no %q:\n%s", want, content)
+				}
+			}
+			if tt.want == nil && strings.Contains(content, "Trace:") {
+				t.Errorf("log has trace, want none:\n%s", content)
+			}
+		})
+	}
+}